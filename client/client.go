@@ -0,0 +1,303 @@
+// Package client is the Go client for the cookie generator HTTP API. It has
+// no dependency on the server packages; the wire types are in types.go.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds each HTTP attempt; solves can take tens of seconds
+	DefaultTimeout = 90 * time.Second
+
+	// DefaultMaxRetries is how many times a retryable error is retried
+	DefaultMaxRetries = 2
+
+	// DefaultRetryWait is used when the server sends no Retry-After
+	DefaultRetryWait = time.Second
+
+	// maxRetryWait caps Retry-After so a bad header cannot stall the caller
+	maxRetryWait = time.Minute
+)
+
+// Client calls the HTTP API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+	apiKey     string
+	adminToken string
+	maxRetries int
+	retryWait  time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying http.Client. The client is not
+// modified; its own Timeout still applies on top of WithTimeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout sets the per-attempt timeout, applied through the request
+// context. 0 disables it.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithAPIKey sends X-API-Key; the server bills provider calls to the
+// tenant that owns the key
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithAdminToken authenticates /admin routes
+func WithAdminToken(token string) Option {
+	return func(c *Client) { c.adminToken = token }
+}
+
+// WithRetry sets how many times retryable errors are retried and the wait
+// used when the server sends no Retry-After. maxRetries 0 disables retries.
+func WithRetry(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+	}
+}
+
+// New creates a client for the server at baseURL (e.g. http://localhost:9999)
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+		timeout:    DefaultTimeout,
+		maxRetries: DefaultMaxRetries,
+		retryWait:  DefaultRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GenerateSbsd calls POST /sbsd
+func (c *Client) GenerateSbsd(ctx context.Context, req *SbsdRequest) (*SuccessResponse, error) {
+	var out SuccessResponse
+	if err := c.do(ctx, http.MethodPost, "/sbsd", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StatsFilter filters GET /stats/providers and the cache admin routes
+type StatsFilter struct {
+	Domain   string
+	Provider string
+	Mode     string
+}
+
+func (f StatsFilter) query() url.Values {
+	return values("domain", f.Domain, "provider", f.Provider, "mode", f.Mode)
+}
+
+// ProviderStats calls GET /stats/providers
+func (c *Client) ProviderStats(ctx context.Context, f StatsFilter) (*ProviderStatsResponse, error) {
+	var out ProviderStatsResponse
+	if err := c.do(ctx, http.MethodGet, "/stats/providers", f.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Profiles calls GET /profiles
+func (c *Client) Profiles(ctx context.Context) (*ProfilesResponse, error) {
+	var out ProfilesResponse
+	if err := c.do(ctx, http.MethodGet, "/profiles", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UsageFilter filters GET /usage. Days use the YYYY-MM-DD format.
+type UsageFilter struct {
+	From     string
+	To       string
+	Tenant   string
+	Provider string
+	Domain   string
+}
+
+// Usage calls GET /usage
+func (c *Client) Usage(ctx context.Context, f UsageFilter) (*UsageReport, error) {
+	q := values("from", f.From, "to", f.To, "tenant", f.Tenant, "provider", f.Provider, "domain", f.Domain)
+	var out UsageReport
+	if err := c.do(ctx, http.MethodGet, "/usage", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCache calls GET /admin/cache
+func (c *Client) ListCache(ctx context.Context, f StatsFilter) (*CacheEntriesResponse, error) {
+	var out CacheEntriesResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/cache", f.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCacheEntry calls GET /admin/cache/{domain}/{provider}/{mode}
+func (c *Client) GetCacheEntry(ctx context.Context, domain, provider, mode string) (*ProviderCacheEntry, error) {
+	path := "/admin/cache/" + url.PathEscape(domain) + "/" + url.PathEscape(provider) + "/" + url.PathEscape(mode)
+	var out ProviderCacheEntry
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InvalidateCache calls DELETE /admin/cache. An empty filter requires all=true.
+func (c *Client) InvalidateCache(ctx context.Context, f StatsFilter, all bool) (*CacheInvalidateResponse, error) {
+	q := f.query()
+	if all {
+		q.Set("all", "true")
+	}
+	var out CacheInvalidateResponse
+	if err := c.do(ctx, http.MethodDelete, "/admin/cache", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OpenAPI calls GET /openapi.json and returns the raw document
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var out json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// do sends the request, retrying while the server reports a retryable error
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		payload = b
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		wait, err := c.attempt(ctx, method, u, payload, out)
+		if err == nil {
+			return nil
+		}
+		apiErr, ok := err.(*Error)
+		if !ok || !apiErr.Retryable || attempt >= c.maxRetries {
+			return err
+		}
+		if wait <= 0 {
+			wait = c.retryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one request. On an API error it also returns the Retry-After wait.
+func (c *Client) attempt(ctx context.Context, method, u string, payload []byte, out interface{}) (time.Duration, error) {
+	var rd io.Reader
+	if payload != nil {
+		rd = bytes.NewReader(payload)
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+	if c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return 0, fmt.Errorf("decode response: %w", err)
+		}
+		return 0, nil
+	}
+	return retryAfter(resp.Header.Get("Retry-After")), decodeError(resp.StatusCode, raw)
+}
+
+// withTimeout bounds ctx with the per-attempt timeout
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// retryAfter parses Retry-After as seconds or an HTTP date
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	}
+	if d > maxRetryWait {
+		d = maxRetryWait
+	}
+	return d
+}
+
+func values(kv ...string) url.Values {
+	q := url.Values{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			q.Set(kv[i], kv[i+1])
+		}
+	}
+	return q
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Error is a non-2xx response from the server. Step and StepNumber identify
+// where the solve flow failed (see internal/errors for the list).
+type Error struct {
+	StatusCode     int
+	Step           string
+	StepNumber     int
+	Description    string
+	Provider       string
+	Domain         string
+	RawError       string
+	Retryable      bool
+	BlockReason    string       // Why the site blocked the homepage (akamai_access_denied, rate_limited...)
+	Fields         []FieldError // Field-level details of request_validation errors
+	Detail         *ErrorDetail
+	PartialCookies *Cookies
+	Timeline       []TimelineEntry // Steps run before the failure
+}
+
+func (e *Error) Error() string {
+	if e.Step == "" {
+		return fmt.Sprintf("http %d: %s", e.StatusCode, e.RawError)
+	}
+	return fmt.Sprintf("http %d: [%s] %s: %s", e.StatusCode, e.Step, e.Description, e.RawError)
+}
+
+// decodeError builds an Error from an ErrorResponse body; bodies that are not
+// ErrorResponse JSON keep the raw text
+func decodeError(status int, body []byte) *Error {
+	e := &Error{StatusCode: status}
+	var resp ErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error == nil {
+		e.RawError = string(body)
+		if e.RawError == "" {
+			e.RawError = http.StatusText(status)
+		}
+		return e
+	}
+	d := resp.Error
+	e.Step = d.Step
+	e.StepNumber = d.StepNumber
+	e.Description = d.Description
+	e.Provider = d.Provider
+	e.Domain = d.Domain
+	e.RawError = d.RawError
+	e.Retryable = d.Retryable
+	e.BlockReason = d.BlockReason
+	e.Fields = d.Fields
+	e.Detail = d
+	e.PartialCookies = resp.PartialCookies
+	e.Timeline = resp.Timeline
+	return e
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxEventSize bounds one Server-Sent Event; the result carries every cookie
+const maxEventSize = 16 << 20
+
+// GenerateSbsdStream calls POST /sbsd with Accept: text/event-stream and
+// passes each progress event to onProgress (may be nil) as the solve runs.
+// It returns the final result; failures are *Error as in GenerateSbsd.
+// Streams are not retried; the WithTimeout limit covers the whole stream.
+func (c *Client) GenerateSbsdStream(ctx context.Context, req *SbsdRequest, onProgress func(ProgressEvent)) (*SuccessResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/sbsd", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set(APIKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Validation errors are answered before the stream starts, as plain JSON
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, decodeError(resp.StatusCode, raw)
+		}
+		var out SuccessResponse
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &out, nil
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64<<10), maxEventSize)
+	var event string
+	var data []byte
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// Comment, sent as heartbeat
+		case line != "":
+			switch field, value := sseField(line); field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value...)
+				data = append(data, '\n')
+			}
+		default:
+			data = bytes.TrimSuffix(data, []byte("\n"))
+			switch event {
+			case EventProgress:
+				var ev ProgressEvent
+				if err := json.Unmarshal(data, &ev); err == nil && onProgress != nil {
+					onProgress(ev)
+				}
+			case EventResult:
+				var out SuccessResponse
+				if err := json.Unmarshal(data, &out); err != nil {
+					return nil, fmt.Errorf("decode result event: %w", err)
+				}
+				return &out, nil
+			case EventError:
+				var er ErrorResponse
+				status := http.StatusInternalServerError
+				if json.Unmarshal(data, &er) == nil && er.Error != nil && er.Error.HTTPStatus != 0 {
+					status = er.Error.HTTPStatus
+				}
+				return nil, decodeError(status, data)
+			}
+			event, data = "", nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read event stream: %w", err)
+	}
+	return nil, fmt.Errorf("event stream ended without a result")
+}
+
+// sseField splits an event-stream line into field and value. A single space
+// after the colon is dropped; a line without colon is a field with no value.
+func sseField(line string) (string, string) {
+	field, value, found := strings.Cut(line, ":")
+	if !found {
+		return line, ""
+	}
+	return field, strings.TrimPrefix(value, " ")
+}
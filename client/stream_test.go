@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func streamServer(t *testing.T, body string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL)
+}
+
+func TestGenerateSbsdStreamParsing(t *testing.T) {
+	// Heartbeat comments, data without the optional space and a result split
+	// over several data lines
+	c := streamServer(t, ": ping\n\n"+
+		"event: progress\ndata:{\"type\":\"step_started\",\"step\":\"homepage\",\"attempt\":1}\n\n"+
+		": ping\n\n"+
+		"event:progress\ndata: {\"type\":\"step_finished\",\ndata:  \"step\":\"homepage\",\ndata: \"status\":200}\n\n"+
+		"event: result\ndata: {\"success\":true,\ndata: \"session\":{\"provider\":\"hyper\",\"profile\":\"chrome_144\"}}\n\n")
+
+	var events []ProgressEvent
+	resp, err := c.GenerateSbsdStream(context.Background(), &SbsdRequest{URL: "www.example.com"}, func(ev ProgressEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.Session == nil || resp.Session.Provider != "hyper" {
+		t.Errorf("result = %+v", resp)
+	}
+	if len(events) != 2 || events[0].Step != "homepage" || events[1].Type != "step_finished" || events[1].Status != 200 {
+		t.Errorf("progress events = %+v", events)
+	}
+}
+
+func TestGenerateSbsdStreamJoinsDataLines(t *testing.T) {
+	// Data lines are joined with "\n" and keep their text after the first space
+	c := streamServer(t, "event: error\ndata: upstream failed\ndata:   at step 2\n\n")
+	_, err := c.GenerateSbsdStream(context.Background(), &SbsdRequest{URL: "www.example.com"}, nil)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("err = %v; want *Error", err)
+	}
+	if want := "upstream failed\n  at step 2"; e.RawError != want {
+		t.Errorf("RawError = %q; want %q", e.RawError, want)
+	}
+}
+
+func TestSSEField(t *testing.T) {
+	for _, c := range []struct {
+		line, field, value string
+	}{
+		{"data: x", "data", "x"},
+		{"data:x", "data", "x"},
+		{"data:  x ", "data", " x "},
+		{"data", "data", ""},
+		{"event: result", "event", "result"},
+		{"data: a:b", "data", "a:b"},
+	} {
+		if f, v := sseField(c.line); f != c.field || v != c.value {
+			t.Errorf("sseField(%q) = %q, %q; want %q, %q", c.line, f, v, c.field, c.value)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package client
+
+import "time"
+
+// Wire types of the HTTP API. They mirror the JSON sent by the server and are
+// declared here so the client does not import server packages; types_test.go
+// checks that the JSON fields match.
+
+// APIKeyHeader carries the tenant API key (see WithAPIKey)
+const APIKeyHeader = "X-API-Key"
+
+// Event names sent on text/event-stream responses
+const (
+	EventProgress = "progress" // ProgressEvent
+	EventResult   = "result"   // SuccessResponse, last event on success
+	EventError    = "error"    // ErrorResponse, last event on failure
+)
+
+// SbsdRequest is the body of POST /sbsd
+type SbsdRequest struct {
+	URL            string   `json:"url"`
+	AkamaiURL      string   `json:"akamaiUrl,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
+	RandomUA       string   `json:"randomUserAgent,omitempty"`
+	UserAgent      string   `json:"userAgent,omitempty"`
+	SecChUa        string   `json:"secChUa,omitempty"`
+	Language       string   `json:"language,omitempty"`
+	AkamaiProvider string   `json:"akamaiProvider,omitempty"`
+	ProviderChain  []string `json:"providerChain,omitempty"`
+	GenerateReport bool     `json:"generateReport,omitempty"`
+
+	// Retry overrides the server retry policy for this request
+	Retry *RetrySpec `json:"retry,omitempty"`
+}
+
+// RetrySpec overrides the retry policy of one request. Durations use Go
+// syntax ("500ms", "2s").
+type RetrySpec struct {
+	MaxAttempts    int      `json:"maxAttempts,omitempty"`
+	InitialBackoff string   `json:"initialBackoff,omitempty"`
+	MaxBackoff     string   `json:"maxBackoff,omitempty"`
+	Multiplier     float64  `json:"multiplier,omitempty"`
+	Jitter         float64  `json:"jitter,omitempty"`
+	Phases         []string `json:"phases,omitempty"`
+	Statuses       []int    `json:"statuses,omitempty"`
+}
+
+// SuccessResponse is returned by POST /sbsd when the cookies were generated
+type SuccessResponse struct {
+	Success   bool            `json:"success"`
+	Cookies   *Cookies        `json:"cookies"`
+	Telemetry *Telemetry      `json:"telemetry"`
+	Session   *Session        `json:"session"`
+	Timeline  []TimelineEntry `json:"timeline,omitempty"`
+}
+
+// Cookies holds the generated cookies
+type Cookies struct {
+	FullString string       `json:"full_string"`
+	Items      []CookieItem `json:"items"`
+}
+
+// CookieItem is a single cookie
+type CookieItem struct {
+	Name   string        `json:"name"`
+	Value  string        `json:"value"`
+	Domain string        `json:"domain"`
+	Parsed *ParsedCookie `json:"parsed,omitempty"` // Decoded fields of Akamai cookies
+}
+
+// ParsedCookie is the decoded view of an Akamai cookie
+type ParsedCookie struct {
+	State  string            `json:"state"` // valid, pending or malformed
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Telemetry holds the solve telemetry
+type Telemetry struct {
+	AbckToken         string `json:"abck_token,omitempty"`
+	AbckState         string `json:"abck_state,omitempty"`
+	BmSzEncoded       string `json:"bm_sz_encoded,omitempty"`
+	BmSEncoded        string `json:"bm_s_encoded,omitempty"`
+	SensorDataEncoded string `json:"sensor_data_encoded,omitempty"`
+}
+
+// Session describes how the solve ran
+type Session struct {
+	Provider string   `json:"provider"`
+	Profile  string   `json:"profile"`
+	Attempts int      `json:"attempts,omitempty"`
+	Retries  *Retries `json:"retries,omitempty"`
+}
+
+// Retries counts the extra attempts made by the retry policy
+type Retries struct {
+	TLSAPI   int `json:"tls_api"`
+	Provider int `json:"provider"`
+	SbsdPost int `json:"sbsd_post"`
+}
+
+// TimelineEntry is one timed step of the solve
+type TimelineEntry struct {
+	Step       string    `json:"step"`
+	Provider   string    `json:"provider,omitempty"`
+	Attempt    int       `json:"attempt"`
+	Status     int       `json:"status,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Detail     string    `json:"detail,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Success        bool            `json:"success"`
+	Error          *ErrorDetail    `json:"error"`
+	PartialCookies *Cookies        `json:"partial_cookies,omitempty"`
+	Timeline       []TimelineEntry `json:"timeline,omitempty"`
+	Debug          *Debug          `json:"debug,omitempty"`
+}
+
+// ErrorDetail describes a failure
+type ErrorDetail struct {
+	Step        string        `json:"step"`
+	StepNumber  int           `json:"step_number"`
+	Description string        `json:"description"`
+	Provider    string        `json:"provider,omitempty"`
+	Domain      string        `json:"domain,omitempty"`
+	HTTPStatus  int           `json:"http_status,omitempty"`
+	RawError    string        `json:"raw_error"`
+	Retryable   bool          `json:"retryable"`
+	BlockReason string        `json:"block_reason,omitempty"`
+	Context     *ErrorContext `json:"context,omitempty"`
+	Fields      []FieldError  `json:"fields,omitempty"`
+}
+
+// ErrorContext adds details to an ErrorDetail
+type ErrorContext struct {
+	Attempt          int               `json:"attempt,omitempty"`
+	MaxAttempts      int               `json:"max_attempts,omitempty"`
+	ElapsedMs        int64             `json:"elapsed_ms,omitempty"`
+	ScriptCandidates []ScriptCandidate `json:"script_candidates,omitempty"`
+	SiteStatus       int               `json:"site_status,omitempty"`
+	ReferenceID      string            `json:"reference_id,omitempty"`
+	SuggestedAction  string            `json:"suggested_action,omitempty"` // retry, retry_later, switch_proxy, switch_geo or give_up
+	RetryAfter       string            `json:"retry_after,omitempty"`
+}
+
+// ScriptCandidate is a script considered by script discovery
+type ScriptCandidate struct {
+	URL      string   `json:"url"`
+	Source   string   `json:"source"`
+	Score    int      `json:"score"`
+	Eligible bool     `json:"eligible"`
+	Reasons  []string `json:"reasons"`
+}
+
+// FieldError describes an invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Debug holds debug information
+type Debug struct {
+	ReportPath string `json:"report_path,omitempty"`
+}
+
+// ProgressEvent is sent while a streamed solve runs
+type ProgressEvent struct {
+	Type       string    `json:"type"`
+	Step       string    `json:"step,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Attempt    int       `json:"attempt,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Accepted   *bool     `json:"accepted,omitempty"`
+	Cookies    []string  `json:"cookies,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// ProfilesResponse is returned by GET /profiles
+type ProfilesResponse struct {
+	Profiles  []string   `json:"profiles"`
+	Default   string     `json:"default"`
+	TLSAPI    []string   `json:"tls_api,omitempty"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ProviderStatsResponse is returned by GET /stats/providers
+type ProviderStatsResponse struct {
+	Providers   []ProviderStat `json:"providers"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// ProviderStat aggregates the solves of one (domain, provider, mode)
+type ProviderStat struct {
+	Domain        string    `json:"domain"`
+	Provider      string    `json:"provider"`
+	Mode          string    `json:"mode"`
+	Solves        int64     `json:"solves"`
+	Successes     int64     `json:"successes"`
+	SuccessRate   float64   `json:"success_rate"`
+	MeanAttempts  float64   `json:"mean_attempts"`
+	MeanLatencyMs float64   `json:"mean_latency_ms"`
+	MeanCalls     float64   `json:"mean_calls"` // Provider API calls per solve (EWMA)
+	TotalCalls    int64     `json:"total_calls"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// UsageReport is returned by GET /usage
+type UsageReport struct {
+	From       string           `json:"from,omitempty"`
+	To         string           `json:"to,omitempty"`
+	Records    []UsageRecord    `json:"records"`
+	ByProvider map[string]int64 `json:"by_provider"`
+	ByTenant   map[string]int64 `json:"by_tenant"`
+	ByDomain   map[string]int64 `json:"by_domain"`
+	Total      int64            `json:"total"`
+}
+
+// UsageRecord counts provider calls of one day, tenant, provider and domain
+type UsageRecord struct {
+	Day      string `json:"day"`
+	Tenant   string `json:"tenant"`
+	Provider string `json:"provider"`
+	Domain   string `json:"domain"`
+	Calls    int64  `json:"calls"`
+}
+
+// CacheEntriesResponse is returned by GET /admin/cache
+type CacheEntriesResponse struct {
+	Entries     []ProviderCacheEntry `json:"entries"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// ProviderCacheEntry is one entry of the provider cache
+type ProviderCacheEntry struct {
+	Domain         string    `json:"domain"`
+	Provider       string    `json:"provider"`
+	Mode           string    `json:"mode"`
+	ScriptURL      string    `json:"script_url"`
+	ScriptHash     string    `json:"script_hash,omitempty"`
+	ScriptHeadHash string    `json:"script_head_hash,omitempty"`
+	Dynamic        string    `json:"dynamic"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CacheInvalidateResponse is returned by DELETE /admin/cache
+type CacheInvalidateResponse struct {
+	Removed int `json:"removed"`
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gerador_cookies/internal/handler"
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// TestWireTypesMatchServer checks that every client type has the same JSON
+// fields as the server type it mirrors
+func TestWireTypesMatchServer(t *testing.T) {
+	pairs := []struct{ client, server interface{} }{
+		{SbsdRequest{}, handler.SbsdRequest{}},
+		{SuccessResponse{}, response.SuccessResponse{}},
+		{ErrorResponse{}, response.ErrorResponse{}},
+		{ProgressEvent{}, scraper.ProgressEvent{}},
+		{ProfilesResponse{}, handler.ProfilesResponse{}},
+		{ProviderStatsResponse{}, handler.ProviderStatsResponse{}},
+		{UsageReport{}, scraper.UsageReport{}},
+		{CacheEntriesResponse{}, handler.CacheEntriesResponse{}},
+		{ProviderCacheEntry{}, handler.CacheEntry{}},
+		{CacheInvalidateResponse{}, handler.CacheInvalidateResponse{}},
+	}
+	for _, p := range pairs {
+		compareJSONFields(t, reflect.TypeOf(p.client), reflect.TypeOf(p.server))
+	}
+	if APIKeyHeader != handler.APIKeyHeader {
+		t.Errorf("APIKeyHeader = %q; server uses %q", APIKeyHeader, handler.APIKeyHeader)
+	}
+	if EventProgress != response.EventProgress || EventResult != response.EventResult || EventError != response.EventError {
+		t.Errorf("event names differ from the server")
+	}
+}
+
+func compareJSONFields(t *testing.T, c, s reflect.Type) {
+	t.Helper()
+	cf, sf := jsonFields(c), jsonFields(s)
+	var cn, sn []string
+	for n := range cf {
+		cn = append(cn, n)
+	}
+	for n := range sf {
+		sn = append(sn, n)
+	}
+	sort.Strings(cn)
+	sort.Strings(sn)
+	if !reflect.DeepEqual(cn, sn) {
+		t.Errorf("%s fields %v; server %s has %v", c.Name(), cn, s, sn)
+		return
+	}
+	for name, ct := range cf {
+		st := sf[name]
+		ct, st = deref(ct), deref(st)
+		if ct.Kind() == reflect.Struct && ct != reflect.TypeOf(time.Time{}) {
+			compareJSONFields(t, ct, st)
+		} else if ct.Kind() != st.Kind() {
+			t.Errorf("%s.%s is %s; server has %s", c.Name(), name, ct.Kind(), st.Kind())
+		}
+	}
+}
+
+// jsonFields maps JSON names (with their options) to field types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		out[name+","+opts] = f.Type
+	}
+	return out
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t
+}
+
+func TestWithTimeoutDoesNotModifyHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	hc := &http.Client{}
+	for _, opts := range [][]Option{
+		{WithHTTPClient(hc), WithTimeout(20 * time.Millisecond)},
+		{WithTimeout(20 * time.Millisecond), WithHTTPClient(hc)},
+	} {
+		c := New(srv.URL, append(opts, WithRetry(0, 0))...)
+		start := time.Now()
+		_, err := c.Profiles(context.Background())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Profiles error = %v; want deadline exceeded", err)
+		}
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Fatalf("timeout ignored: request took %s", d)
+		}
+	}
+	if hc.Timeout != 0 {
+		t.Fatalf("WithTimeout changed the caller's http.Client (Timeout = %s)", hc.Timeout)
+	}
+}
@@ -0,0 +1,119 @@
+// cachectl inspeciona e invalida o cache de providers usando o mesmo backend
+// do servidor (file, sqlite ou redis).
+//
+//	cachectl [-backend file|sqlite|redis] [-path P] [-redis-url URL] <comando>
+//
+// Comandos:
+//
+//	list [-domain D] [-provider P] [-mode M]
+//	get <domain> <provider> <mode>
+//	invalidate [-domain D] [-provider P] [-mode M] [-all]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"gerador_cookies/scraper"
+	"gerador_cookies/scraper/cachestore"
+)
+
+func main() {
+	backend := flag.String("backend", envOr("CACHE_BACKEND", cachestore.BackendFile), "cache backend: file, sqlite or redis")
+	path := flag.String("path", "", "JSON file (file) or database (sqlite); empty uses the default location")
+	redisURL := flag.String("redis-url", envOr("CACHE_REDIS_URL", "redis://localhost:6379/0"), "redis URL")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *path == "" && *backend == cachestore.BackendSQLite {
+		*path = os.Getenv("CACHE_SQLITE_PATH")
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	store, err := cachestore.Open(*backend, *path, *redisURL)
+	if err != nil {
+		fatalf("open cache: %v", err)
+	}
+	defer store.Close()
+	pc := scraper.NewProviderCache(store)
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		filter := filterFlags(fs)
+		fs.Parse(args[1:])
+		entries, err := pc.List(*filter)
+		if err != nil {
+			fatalf("list: %v", err)
+		}
+		printJSON(entries)
+	case "get":
+		if len(args) != 4 {
+			fatalf("usage: cachectl get <domain> <provider> <mode>")
+		}
+		entry, found, err := pc.Entry(args[1], args[2], args[3])
+		if err != nil {
+			fatalf("get: %v", err)
+		}
+		if !found {
+			fatalf("entry not found: %s|%s|%s", args[1], args[2], args[3])
+		}
+		printJSON(entry)
+	case "invalidate":
+		fs := flag.NewFlagSet("invalidate", flag.ExitOnError)
+		filter := filterFlags(fs)
+		all := fs.Bool("all", false, "remove every entry")
+		fs.Parse(args[1:])
+		if filter.IsEmpty() && !*all {
+			fatalf("invalidate: pass -domain, -provider, -mode or -all")
+		}
+		removed, err := pc.Invalidate(*filter)
+		if err != nil {
+			fatalf("invalidate: %v", err)
+		}
+		printJSON(map[string]int{"removed": removed})
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func filterFlags(fs *flag.FlagSet) *scraper.CacheFilter {
+	f := &scraper.CacheFilter{}
+	fs.StringVar(&f.Domain, "domain", "", "filter by domain")
+	fs.StringVar(&f.Provider, "provider", "", "filter by provider")
+	fs.StringVar(&f.Mode, "mode", "", "filter by mode (sensor or sbsd)")
+	return f
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: cachectl [flags] list|get|invalidate [args]\n\n")
+	flag.PrintDefaults()
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fatalf("encode: %v", err)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "cachectl: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,219 @@
+// cookiegen resolve SBSD, ABCK ou ambos direto pelo pacote scraper, sem o
+// servidor HTTP. Usa a mesma configuração do servidor (.env, CONFIG_FILE).
+//
+//	cookiegen -domain www.example.com [-mode sbsd|abck|both] [-format json|header|netscape]
+//
+// Código de saída: 0 em sucesso, o step_number do erro (1-12, ver
+// internal/errors) em falha e 64 para flags ou configuração inválidas.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gerador_cookies/internal/config"
+	"gerador_cookies/scraper"
+	"gerador_cookies/scraper/cachestore"
+)
+
+const (
+	exitUsage  = 64 // Flags ou configuração inválidas (EX_USAGE)
+	exitOutput = 74 // Falha ao escrever o resultado no stdout (EX_IOERR)
+)
+
+func main() {
+	domain := flag.String("domain", "", "target domain (e.g. www.example.com)")
+	mode := flag.String("mode", string(scraper.SolveSBSD), "solve mode: sbsd, abck or both")
+	provider := flag.String("provider", "", "preferred provider: "+strings.Join(scraper.SupportedProviders, ", "))
+	chain := flag.String("provider-chain", "", "comma-separated fallback providers (overrides the configured chain)")
+	profile := flag.String("profile", "chrome_144", "browser profile supported by the TLS-API (chrome_*, firefox_* or safari_*)")
+	proxy := flag.String("proxy", "", "proxy URL (http, https, socks5)")
+	scriptPath := flag.String("script", "", "anti-bot script path; skips discovery on the homepage")
+	language := flag.String("language", "", "Accept-Language")
+	userAgent := flag.String("user-agent", "", "User-Agent override")
+	report := flag.Bool("report", false, "write a request report and print its path to stderr")
+	format := flag.String("format", formatJSON, "output format: json, header or netscape")
+	quiet := flag.Bool("quiet", false, "discard scraper logs")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file")
+	envFile := flag.String("env-file", "", "env file to load (default: .env if present)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	host := normalizeDomain(*domain)
+	switch {
+	case host == "":
+		usagef("-domain is required")
+	case !validMode(*mode):
+		usagef("invalid -mode %q", *mode)
+	case *scriptPath != "" && *mode == string(scraper.SolveBoth):
+		usagef("-script cannot be combined with -mode both")
+	case *format != formatJSON && *format != formatHeader && *format != formatNetscape:
+		usagef("invalid -format %q", *format)
+	case scraper.ProfileFamily(*profile) == "":
+		usagef("invalid -profile %q", *profile)
+	case *provider != "" && !scraper.IsSupportedProvider(*provider):
+		usagef("invalid -provider %q", *provider)
+	}
+
+	cfg, err := config.LoadFrom(config.Options{File: *configFile, EnvFile: *envFile, Providers: scraper.SupportedProviders})
+	if err != nil {
+		usagef("load config: %v", err)
+	}
+	// os.Exit não roda defers: cleanup é chamado antes de sair
+	cleanup := setup(cfg, *profile)
+
+	var providerChain []string
+	if *chain != "" {
+		providerChain = scraper.BuildProviderChain("", strings.Split(*chain, ","))
+	} else {
+		providerChain = scraper.BuildProviderChain(*provider, cfg.ProviderChain)
+	}
+
+	// Ctrl-C cancela o solve mas ainda passa pela saída e pelo cleanup abaixo
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	res, solveErr := solve(ctx, cfg, solveInput{
+		Mode:          scraper.SolveMode(*mode),
+		Domain:        host,
+		ScriptPath:    *scriptPath,
+		Proxy:         *proxy,
+		Profile:       *profile,
+		Provider:      *provider,
+		ProviderChain: providerChain,
+		Language:      *language,
+		UserAgent:     *userAgent,
+		Report:        *report,
+	})
+	stop()
+
+	if res.ReportPath != "" {
+		fmt.Fprintf(os.Stderr, "report: %s\n", res.ReportPath)
+	}
+	code := 0
+	if err := write(os.Stdout, *format, host, *profile, res, solveErr); err != nil {
+		fmt.Fprintf(os.Stderr, "cookiegen: %v\n", err)
+		code = exitOutput
+	} else if solveErr != nil {
+		if *format != formatJSON {
+			fmt.Fprintf(os.Stderr, "cookiegen: %v\n", solveErr)
+		}
+		code = solveErr.StepNumber()
+	}
+	cleanup()
+	os.Exit(code)
+}
+
+func validMode(mode string) bool {
+	switch scraper.SolveMode(mode) {
+	case scraper.SolveSBSD, scraper.SolveABCK, scraper.SolveBoth:
+		return true
+	}
+	return false
+}
+
+// setup aplica perfis de site, réplicas da TLS-API, backend do cache e
+// contabilização como o servidor faz, para que a CLI compartilhe cache e
+// orçamentos com ele. As estatísticas de providers são lidas para o roteamento
+// mas não gravadas: o arquivo é um snapshot inteiro e a CLI apagaria o que o
+// servidor registrou enquanto ela rodava. Retorna a limpeza que grava o uso e
+// fecha o backend do cache.
+func setup(cfg *config.Config, profile string) func() {
+	profiles, err := scraper.LoadSiteProfiles(cfg.SiteProfilesPath)
+	if err != nil {
+		usagef("load site profiles: %v", err)
+	}
+	scraper.SetDefaultSiteProfiles(profiles)
+	pool := scraper.NewTLSAPIPool(cfg.TLSAPIURLs(), cfg.TLSAPIEjectDuration)
+	scraper.SetDefaultTLSAPIPool(pool)
+
+	// Perfil fora da lista da TLS-API é erro de uso; sem resposta da TLS-API, segue sem checar
+	tlsProfiles := scraper.NewTLSAPIProfiles(scraper.NewTLSAPIClientWithPool(pool, cfg.TLSAPIToken, 0))
+	if err := tlsProfiles.Refresh(); err == nil {
+		scraper.SetDefaultTLSAPIProfiles(tlsProfiles)
+		if err := tlsProfiles.Check(profile); err != nil {
+			usagef("invalid -profile: %v", err)
+		}
+	}
+
+	scraper.SetProviderCacheEnabled(cfg.CacheEnabled)
+	if cfg.CacheClearOnStart {
+		if err := scraper.ClearProviderCacheFile(); err != nil {
+			fmt.Fprintf(os.Stderr, "cookiegen: clear provider cache: %v\n", err)
+		}
+	}
+	closeStore := func() {}
+	if cfg.CacheEnabled && cfg.CacheBackend != "" && cfg.CacheBackend != cachestore.BackendFile {
+		store, err := cachestore.Open(cfg.CacheBackend, cfg.CacheSQLitePath, cfg.CacheRedisURL)
+		if err != nil {
+			usagef("open provider cache: %v", err)
+		}
+		scraper.SetDefaultProviderCacheStore(store)
+		closeStore = func() { store.Close() }
+	}
+	scraper.SetDefaultProviderCacheTTL(cfg.CacheTTL)
+	scraper.SetDefaultRetryPolicy(scraper.RetryPolicy{
+		MaxAttempts:    cfg.RetryMaxAttempts,
+		InitialBackoff: cfg.RetryInitialBackoff,
+		MaxBackoff:     cfg.RetryMaxBackoff,
+	})
+
+	stats, err := scraper.LoadProviderStatsDefault()
+	if err != nil {
+		closeStore()
+		usagef("load provider stats: %v", err)
+	}
+	stats.Detach()
+	stats.SetRouting(cfg.AdaptiveRouting, cfg.ExplorationRate)
+	scraper.SetDefaultProviderStats(stats)
+
+	budgets, err := scraper.LoadUsageBudgets(cfg.UsageBudgetsPath)
+	if err != nil {
+		closeStore()
+		usagef("load usage budgets: %v", err)
+	}
+	usage, err := scraper.LoadUsageTrackerDefault(budgets)
+	if err != nil {
+		closeStore()
+		usagef("load provider usage: %v", err)
+	}
+	scraper.SetDefaultUsageTracker(usage)
+	return func() {
+		if err := usage.Flush(); err != nil {
+			log.Printf("failed to save provider usage: %v", err)
+		}
+		closeStore()
+	}
+}
+
+// normalizeDomain aceita tanto "www.example.com" quanto uma URL completa
+func normalizeDomain(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.ToLower(s)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: cookiegen -domain D [flags]\n\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nexit codes: 0 success, 1-12 failed step (step_number), %d invalid flags or config, %d failed to write the output\n", exitUsage, exitOutput)
+}
+
+func usagef(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "cookiegen: "+format+"\n", args...)
+	os.Exit(exitUsage)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gerador_cookies/internal/errors"
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+const (
+	formatJSON     = "json"
+	formatHeader   = "header"
+	formatNetscape = "netscape"
+)
+
+// write imprime o resultado no formato pedido. Em json a falha sai no mesmo
+// ErrorResponse da API; nos outros formatos nada vai para stdout em falha.
+func write(w io.Writer, format, domain, profile string, res *scraper.SolveResult, solveErr *errors.SolverError) error {
+	switch format {
+	case formatHeader:
+		if solveErr != nil {
+			return nil
+		}
+		_, err := fmt.Fprintln(w, response.CookieHeader(res.Cookies))
+		return err
+	case formatNetscape:
+		if solveErr != nil {
+			return nil
+		}
+		return writeNetscape(w, domain, res.Cookies)
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if solveErr != nil {
+			resp := solveErr.ToErrorResponse()
+			errors.WithPartialCookies(resp, response.NewCookies(res.Cookies))
+			errors.WithTimeline(resp, response.NewTimeline(res.Timeline))
+			errors.WithDebug(resp, res.ReportPath)
+			return enc.Encode(resp)
+		}
+		return enc.Encode(&response.SuccessResponse{
+			Success:   true,
+			Cookies:   response.NewCookies(res.Cookies),
+			Telemetry: response.NewTelemetry(res.Cookies, res.SensorData),
+			Session:   response.NewSession(res, profile),
+			Timeline:  response.NewTimeline(res.Timeline),
+		})
+	}
+}
+
+// writeNetscape grava no formato cookies.txt aceito por curl -b e wget --load-cookies
+func writeNetscape(w io.Writer, domain string, cookies []*http.Cookie) error {
+	if _, err := fmt.Fprintln(w, "# Netscape HTTP Cookie File"); err != nil {
+		return err
+	}
+	for _, c := range cookies {
+		host := c.Domain
+		if host == "" {
+			host = domain
+		}
+		subdomains := "FALSE"
+		if strings.HasPrefix(host, ".") {
+			subdomains = "TRUE"
+		}
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+		secure := "FALSE"
+		if c.Secure {
+			secure = "TRUE"
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		if c.HttpOnly {
+			host = "#HttpOnly_" + host
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", host, subdomains, path, secure, expires, c.Name, c.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+
+	"gerador_cookies/internal/config"
+	"gerador_cookies/internal/errors"
+	"gerador_cookies/scraper"
+)
+
+type solveInput struct {
+	Mode          scraper.SolveMode
+	Domain        string
+	ScriptPath    string
+	Proxy         string
+	Profile       string
+	Provider      string
+	ProviderChain []string
+	Language      string
+	UserAgent     string
+	Report        bool
+}
+
+// solve roda scraper.Solve e converte a falha no erro do step da API, cujo
+// step_number vira o código de saída
+func solve(ctx context.Context, cfg *config.Config, in solveInput) (*scraper.SolveResult, *errors.SolverError) {
+	res, err := scraper.Solve(ctx, scraper.SolveRequest{
+		Mode:       in.Mode,
+		Proxy:      in.Proxy,
+		ScriptPath: in.ScriptPath,
+		Config: scraper.Config{
+			Domain:         in.Domain,
+			Language:       in.Language,
+			AkamaiProvider: in.Provider,
+			SbSdProvider:   in.Provider,
+			ProviderChain:  in.ProviderChain,
+			UserAgent:      in.UserAgent,
+			ProfileType:    in.Profile,
+			TLSAPIBrowser:  in.Profile,
+			GenerateReport: in.Report,
+			JeviAPIKey:     cfg.JeviAPIKey,
+			N4SAPIKey:      cfg.N4SAPIKey,
+			RoolinkAPIKey:  cfg.RoolinkAPIKey,
+		},
+	})
+	if err == nil {
+		return res, nil
+	}
+	if se, ok := errors.FromSolveError(err, in.Domain).(*errors.SolverError); ok {
+		return res, se
+	}
+	return res, errors.NewScraperInitError(err, in.Domain)
+}
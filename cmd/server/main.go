@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,24 +13,132 @@ import (
 	"gerador_cookies/internal/config"
 	"gerador_cookies/internal/handler"
 	"gerador_cookies/internal/service"
+	"gerador_cookies/scraper"
+	"gerador_cookies/scraper/cachestore"
 )
 
 func main() {
-	cfg, err := config.Load()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON config file (env vars take precedence)")
+	envFile := flag.String("env-file", "", "env file to load (default: .env if present)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.LoadFrom(config.Options{File: *configFile, EnvFile: *envFile, Providers: scraper.SupportedProviders})
+	if *printConfig && cfg != nil {
+		cfg.WriteEffective(os.Stdout)
+	}
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
+	if *printConfig {
+		return
+	}
+
+	// Carregar perfis de site (recarregados automaticamente quando o arquivo muda)
+	profiles, err := scraper.LoadSiteProfiles(cfg.SiteProfilesPath)
+	if err != nil {
+		log.Fatalf("failed to load site profiles: %v", err)
+	}
+	scraper.SetDefaultSiteProfiles(profiles)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go profiles.Watch(ctx, cfg.SiteProfilesReloadInterval)
+
+	// Réplicas da TLS-API compartilhadas por todos os scrapers, com health check periódico
+	tlsPool := scraper.NewTLSAPIPool(cfg.TLSAPIURLs(), cfg.TLSAPIEjectDuration)
+	if err := tlsPool.CheckHealth(); err != nil {
+		log.Printf("TLS-API health check: %v", err)
+	}
+	scraper.SetDefaultTLSAPIPool(tlsPool)
+	go tlsPool.Watch(ctx, cfg.TLSAPIHealthInterval)
+
+	// Perfis de navegador suportados pela TLS-API; requests com outros perfis são rejeitadas
+	tlsProfiles := scraper.NewTLSAPIProfiles(scraper.NewTLSAPIClientWithPool(tlsPool, cfg.TLSAPIToken, 0))
+	if err := tlsProfiles.Refresh(); err != nil {
+		log.Printf("failed to load TLS-API profiles (accepting all until the next refresh): %v", err)
+	}
+	scraper.SetDefaultTLSAPIProfiles(tlsProfiles)
+	go tlsProfiles.Watch(ctx, cfg.TLSAPIProfilesRefresh)
+
+	// Backend do cache de providers (file é o padrão e dispensa configuração)
+	scraper.SetProviderCacheEnabled(cfg.CacheEnabled)
+	if cfg.CacheClearOnStart {
+		if err := scraper.ClearProviderCacheFile(); err != nil {
+			log.Printf("failed to clear provider cache: %v", err)
+		}
+	}
+	cacheStore, err := openCacheStore(cfg)
+	if err != nil {
+		log.Fatalf("failed to open provider cache: %v", err)
+	}
+	if cacheStore != nil {
+		scraper.SetDefaultProviderCacheStore(cacheStore)
+		defer cacheStore.Close()
+	}
+
+	// TTL padrão do cache; perfis de site podem sobrescrever com cacheTtl
+	scraper.SetDefaultProviderCacheTTL(cfg.CacheTTL)
+
+	// Política de retry padrão; perfis de site e requests podem sobrescrever
+	scraper.SetDefaultRetryPolicy(scraper.RetryPolicy{
+		MaxAttempts:    cfg.RetryMaxAttempts,
+		InitialBackoff: cfg.RetryInitialBackoff,
+		MaxBackoff:     cfg.RetryMaxBackoff,
+	})
+
+	// Circuit breaker compartilhado entre os providers
+	scraper.SetDefaultProviderBreaker(scraper.NewProviderBreaker(cfg.ProviderBreakerFailures, cfg.ProviderBreakerCooldown))
+
+	// Estatísticas por (domínio, provider, modo), persistidas ao lado do cache
+	stats, err := scraper.LoadProviderStatsDefault()
+	if err != nil {
+		log.Fatalf("failed to load provider stats: %v", err)
+	}
+	stats.SetRouting(cfg.AdaptiveRouting, cfg.ExplorationRate)
+	scraper.SetDefaultProviderStats(stats)
+	defer stats.Flush()
+
+	// Contabilização de chamadas aos providers e orçamentos diários/mensais
+	budgets, err := scraper.LoadUsageBudgets(cfg.UsageBudgetsPath)
+	if err != nil {
+		log.Fatalf("failed to load usage budgets: %v", err)
+	}
+	usage, err := scraper.LoadUsageTrackerDefault(budgets)
+	if err != nil {
+		log.Fatalf("failed to load provider usage: %v", err)
+	}
+	scraper.SetDefaultUsageTracker(usage)
+	defer usage.Flush()
 
 	// Criar service
 	solverService := service.NewSolverService(cfg)
 
 	// Criar handlers
 	sbsdHandler := handler.NewSbsdHandler(cfg, solverService)
+	statsHandler := handler.NewStatsHandler(stats)
+	usageHandler := handler.NewUsageHandler(usage)
+	cacheHandler := handler.NewCacheHandler(scraper.DefaultProviderCache())
+	profilesHandler := handler.NewProfilesHandler(tlsProfiles)
+	openapiHandler := handler.NewOpenAPIHandler()
+	admin := handler.RequireAdminToken(cfg.AdminToken)
+	apiKey := handler.RequireAPIKey(cfg.TenantAPIKeys)
 
 	mux := http.NewServeMux()
 
 	// Registrar rotas
-	mux.HandleFunc("POST /sbsd", sbsdHandler.Handle)
+	mux.HandleFunc("POST /sbsd", apiKey(sbsdHandler.Handle))
+	mux.HandleFunc("GET /profiles", profilesHandler.Handle)
+	if cfg.AdminToken != "" {
+		mux.HandleFunc("GET /stats/providers", admin(statsHandler.Providers))
+		mux.HandleFunc("GET /usage", admin(usageHandler.Handle))
+		mux.HandleFunc("GET /admin/cache", admin(cacheHandler.List))
+		mux.HandleFunc("GET /admin/cache/{domain}/{provider}/{mode}", admin(cacheHandler.Get))
+		mux.HandleFunc("DELETE /admin/cache", admin(cacheHandler.Invalidate))
+	} else {
+		log.Printf("ADMIN_TOKEN not set: /stats/providers, /usage and /admin routes are disabled")
+	}
+	mux.HandleFunc("GET /openapi.json", openapiHandler.Handle)
 
 	// Handlers serão adicionados nas próximas issues
 	// mux.HandleFunc("POST /abck", abckHandler.Handle)
@@ -57,3 +167,22 @@ func main() {
 
 	log.Println("Shutting down server...")
 }
+
+// openCacheStore cria o backend compartilhado do cache de providers.
+// Retorna nil para o backend file e quando o cache está desligado
+// (REQS_PROVIDER_CACHE_ENABLE=false).
+func openCacheStore(cfg *config.Config) (scraper.ProviderCacheStore, error) {
+	if !cfg.CacheEnabled {
+		log.Println("Provider cache disabled")
+		return nil, nil
+	}
+	switch cfg.CacheBackend {
+	case "", cachestore.BackendFile:
+		return nil, nil
+	case cachestore.BackendSQLite:
+		log.Printf("Provider cache backend: sqlite (%s)", cfg.CacheSQLitePath)
+	case cachestore.BackendRedis:
+		log.Printf("Provider cache backend: redis (%s)", config.RedactURL(cfg.CacheRedisURL))
+	}
+	return cachestore.Open(cfg.CacheBackend, cfg.CacheSQLitePath, cfg.CacheRedisURL)
+}
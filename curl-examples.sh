@@ -148,4 +148,76 @@ curl -X POST $BASE_URL/sbsd \
     "generateReport": true
   }' | jq '.'
 
+# ==============================================================================
+# 11. ESTATÍSTICAS POR PROVIDER
+# ==============================================================================
+echo -e "${GREEN}11. Estatísticas por Provider${NC}"
+curl -s "$BASE_URL/stats/providers?domain=www.nike.com.br" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 12. RELATÓRIO DE USO POR PROVIDER/TENANT
+# ==============================================================================
+echo -e "${GREEN}12. Relatório de Uso por Provider/Tenant${NC}"
+curl -s "$BASE_URL/usage?from=2026-10-01&to=2026-10-31&tenant=checkout-team" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 13. ADMINISTRAÇÃO DO CACHE DE PROVIDERS
+# ==============================================================================
+echo -e "${GREEN}13. Administração do Cache de Providers${NC}"
+curl -s "$BASE_URL/admin/cache?domain=www.nike.com.br" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
+
+curl -s -X DELETE "$BASE_URL/admin/cache?domain=www.nike.com.br&provider=n4s" \
+  -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 14. ESPECIFICAÇÃO OPENAPI
+# ==============================================================================
+echo -e "${GREEN}14. Especificação OpenAPI${NC}"
+curl -s "$BASE_URL/openapi.json" | jq '.paths | keys'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 15. POLÍTICA DE RETRY POR REQUEST
+# ==============================================================================
+echo -e "${GREEN}15. Política de Retry por Request${NC}"
+curl -s -X POST $BASE_URL/sbsd \
+  -H "Content-Type: application/json" \
+  -d '{
+    "url": "www.nike.com.br",
+    "retry": {"maxAttempts": 4, "initialBackoff": "500ms", "maxBackoff": "3s"}
+  }' | jq '.session'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 16. PERFIS DE NAVEGADOR SUPORTADOS
+# ==============================================================================
+echo -e "${GREEN}16. Perfis de Navegador Suportados${NC}"
+curl -s "$BASE_URL/profiles" | jq '.'
+
+echo -e "\n---\n"
+
+# ==============================================================================
+# 17. PROGRESSO VIA SERVER-SENT EVENTS
+# ==============================================================================
+echo -e "${GREEN}17. Progresso via Server-Sent Events${NC}"
+curl -sN -X POST "$BASE_URL/sbsd" \
+  -H "Content-Type: application/json" \
+  -H "Accept: text/event-stream" \
+  -d '{
+    "url": "www.voeazul.com.br"
+  }'
+
+echo -e "\n---\n"
+
 echo -e "\n${BLUE}=== Testes Concluídos ===${NC}\n"
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"gerador_cookies/scraper"
 )
 
 type Config struct {
@@ -13,73 +17,346 @@ type Config struct {
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
 
-	// TLS-API
-	TLSAPIUrl   string
-	TLSAPIToken string
+	// TLS-API (TLSAPIUrl aceita várias réplicas separadas por vírgula)
+	TLSAPIUrl             string
+	TLSAPIToken           string
+	TLSAPIHealthInterval  time.Duration
+	TLSAPIEjectDuration   time.Duration
+	TLSAPIProfilesRefresh time.Duration
 
 	// Provider API Keys
 	JeviAPIKey    string
 	N4SAPIKey     string
 	RoolinkAPIKey string
 
-	// Cache
-	CacheEnabled bool
+	// Provider failover
+	ProviderChain           []string
+	ProviderBreakerFailures int
+	ProviderBreakerCooldown time.Duration
+
+	// Roteamento adaptativo
+	AdaptiveRouting bool
+	ExplorationRate float64
+
+	// Política de retry padrão (perfis de site e requests podem sobrescrever)
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+
+	// Orçamentos de chamadas aos providers (JSON)
+	UsageBudgetsPath string
+
+	// Cache (CacheEnabled=false usa cache em memória e não abre sqlite/redis)
+	CacheEnabled      bool
+	CacheClearOnStart bool
+	CacheBackend      string // file, sqlite ou redis
+	CacheSQLitePath   string
+	CacheRedisURL     string
+	CacheTTL          time.Duration
+
+	// Token exigido nas rotas administrativas (vazio desativa essas rotas)
+	AdminToken string
+
+	// Chaves de API dos clientes (chave -> tenant). O tenant cobrado pelas
+	// chamadas aos providers vem da chave; sem chaves, tudo vai para "default".
+	TenantAPIKeys map[string]string
+
+	// Site profiles
+	SiteProfilesPath           string
+	SiteProfilesReloadInterval time.Duration
 
 	// Debug
 	Debug bool
+
+	// Valor efetivo e origem de cada variável (usado por --print-config)
+	settings []Setting
 }
 
+// Load lê a configuração de CONFIG_FILE (se definido), .env e variáveis de ambiente
 func Load() (*Config, error) {
+	return LoadFrom(Options{File: os.Getenv("CONFIG_FILE")})
+}
+
+// LoadFrom monta a configuração em camadas. Precedência (maior primeiro):
+// variáveis de ambiente, arquivo .env, arquivo de configuração, defaults.
+// Todos os valores inválidos são reportados juntos em um *ValidationError.
+func LoadFrom(opts Options) (*Config, error) {
+	l, err := newLoader(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		// Defaults
-		Port:            getEnvInt("SERVER_PORT", 9999),
-		ReadTimeout:     getEnvDuration("SERVER_READ_TIMEOUT", 10*time.Second),
-		WriteTimeout:    getEnvDuration("SERVER_WRITE_TIMEOUT", 60*time.Second),
-		ShutdownTimeout: getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
+		// Defaults (PORT/READ_TIMEOUT/WRITE_TIMEOUT são aceitos como aliases legados)
+		Port:            l.int("SERVER_PORT", 9999, "PORT"),
+		ReadTimeout:     l.duration("SERVER_READ_TIMEOUT", 10*time.Second, "READ_TIMEOUT"),
+		WriteTimeout:    l.duration("SERVER_WRITE_TIMEOUT", 60*time.Second, "WRITE_TIMEOUT"),
+		ShutdownTimeout: l.duration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
 
-		TLSAPIUrl:   getEnv("TLS_API_URL", "http://localhost:8080"),
-		TLSAPIToken: getEnv("TLS_API_TOKEN", ""),
+		TLSAPIUrl:             l.string("TLS_API_URL", "http://localhost:8080"),
+		TLSAPIToken:           l.string("TLS_API_TOKEN", ""),
+		TLSAPIHealthInterval:  l.duration("TLS_API_HEALTH_INTERVAL", 10*time.Second),
+		TLSAPIEjectDuration:   l.duration("TLS_API_EJECT_DURATION", 30*time.Second),
+		TLSAPIProfilesRefresh: l.duration("TLS_API_PROFILES_REFRESH", 5*time.Minute),
 
 		// Provider API Keys (sem defaults - devem ser configurados)
-		JeviAPIKey:    getEnv("JEVI_API_KEY", ""),
-		N4SAPIKey:     getEnv("N4S_API_KEY", ""),
-		RoolinkAPIKey: getEnv("ROOLINK_API_KEY", ""),
+		JeviAPIKey:    l.string("JEVI_API_KEY", ""),
+		N4SAPIKey:     l.string("N4S_API_KEY", ""),
+		RoolinkAPIKey: l.string("ROOLINK_API_KEY", ""),
+
+		// Ordem de fallback entre providers (ex: "jevi,n4s,roolink")
+		ProviderChain:           l.list("PROVIDER_CHAIN"),
+		ProviderBreakerFailures: l.int("PROVIDER_BREAKER_FAILURES", 3),
+		ProviderBreakerCooldown: l.duration("PROVIDER_BREAKER_COOLDOWN", 60*time.Second),
+
+		AdaptiveRouting: l.bool("PROVIDER_ADAPTIVE_ROUTING", false),
+		ExplorationRate: l.float("PROVIDER_EXPLORATION_RATE", 0.1),
+
+		RetryMaxAttempts:    l.int("RETRY_MAX_ATTEMPTS", 3),
+		RetryInitialBackoff: l.duration("RETRY_INITIAL_BACKOFF", 250*time.Millisecond),
+		RetryMaxBackoff:     l.duration("RETRY_MAX_BACKOFF", 5*time.Second),
+
+		UsageBudgetsPath: l.string("USAGE_BUDGETS_PATH", ""),
+
+		CacheEnabled:      l.bool("REQS_PROVIDER_CACHE_ENABLE", true),
+		CacheClearOnStart: l.bool("REQS_PROVIDER_CACHE_CLEAR_ON_START", false),
+		CacheBackend:      l.string("CACHE_BACKEND", "file"),
+		CacheSQLitePath:   l.string("CACHE_SQLITE_PATH", ""),
+		CacheRedisURL:     l.string("CACHE_REDIS_URL", "redis://localhost:6379/0"),
+		CacheTTL:          l.duration("PROVIDER_CACHE_TTL", 24*time.Hour),
+
+		AdminToken:    l.string("ADMIN_TOKEN", ""),
+		TenantAPIKeys: l.tenantKeys("TENANT_API_KEYS"),
 
-		CacheEnabled: getEnvBool("REQS_PROVIDER_CACHE_ENABLE", true),
-		Debug:        getEnvBool("DEBUG", false),
+		SiteProfilesPath:           l.string("SITE_PROFILES_PATH", ""),
+		SiteProfilesReloadInterval: l.duration("SITE_PROFILES_RELOAD_INTERVAL", 30*time.Second),
+
+		Debug: l.bool("DEBUG", false),
+	}
+	// REQS_PROVIDER_CACHE_DISABLE (legado) tem precedência sobre _ENABLE
+	if l.bool("REQS_PROVIDER_CACHE_DISABLE", false) {
+		cfg.CacheEnabled = false
 	}
+	cfg.settings = l.settings
 
+	l.checkUnknownFileKeys()
+	cfg.validate(l)
+	if len(l.problems) > 0 {
+		return cfg, &ValidationError{Problems: l.problems}
+	}
 	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// TLSAPIURLs devolve as réplicas da TLS-API configuradas em TLS_API_URL,
+// sem barra final e sem repetições
+func (c *Config) TLSAPIURLs() []string {
+	return scraper.ParseTLSAPIURLs(c.TLSAPIUrl)
+}
+
+// loader resolve cada variável nas camadas e acumula os problemas encontrados
+type loader struct {
+	origin    map[string]string // Variáveis exportadas a partir do .env ou do arquivo
+	fileKeys  map[string]string // Nome da variável -> chave original no arquivo
+	problems  []string
+	settings  []Setting
+	used      map[string]bool
+	providers []string // Providers aceitos em PROVIDER_CHAIN (Options.Providers)
+}
+
+// passthroughKeys são lidas direto do ambiente pelo pacote scraper, mas
+// podem vir do arquivo de configuração
+var passthroughKeys = []string{
+	"DEBUG_PROXY",
+}
+
+// newLoader exporta para o ambiente do processo os valores do .env e do
+// arquivo que ainda não estão definidos, para que o pacote scraper (que lê
+// TLS_API_URL, N4S_API_KEY etc. via os.Getenv) enxergue a mesma configuração
+func newLoader(opts Options) (*loader, error) {
+	l := &loader{
+		origin:    make(map[string]string),
+		fileKeys:  make(map[string]string),
+		used:      make(map[string]bool),
+		providers: opts.Providers,
 	}
-	return defaultValue
+	for _, k := range passthroughKeys {
+		l.used[k] = true
+	}
+
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	dotenv, err := readDotEnv(envFile, opts.EnvFile != "")
+	if err != nil {
+		return nil, err
+	}
+	l.export(dotenv, SourceDotEnv)
+
+	if opts.File != "" {
+		file, keys, err := readConfigFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		l.fileKeys = keys
+		l.export(file, SourceFile)
+	}
+	return l, nil
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if v := os.Getenv(key); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			return i
+func (l *loader) export(values map[string]string, source string) {
+	for _, k := range sortedKeys(values) {
+		if _, set := os.LookupEnv(k); set {
+			continue
 		}
+		os.Setenv(k, values[k])
+		l.origin[k] = source
 	}
-	return defaultValue
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if v := os.Getenv(key); v != "" {
-		return v == "1" || v == "true" || v == "yes"
+// lookup devolve a variável resolvida (key ou o primeiro alias definido), o valor e a origem
+func (l *loader) lookup(key string, aliases ...string) (string, string, string, bool) {
+	for _, k := range append([]string{key}, aliases...) {
+		l.used[k] = true
+		if v := os.Getenv(k); v != "" {
+			src := SourceEnv
+			if o, ok := l.origin[k]; ok {
+				src = o
+			}
+			return k, v, src, true
+		}
 	}
-	return defaultValue
+	return key, "", SourceDefault, false
+}
+
+func (l *loader) record(key, value, source string) {
+	l.settings = append(l.settings, Setting{Key: key, Value: value, Source: source})
+}
+
+func (l *loader) problem(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if v := os.Getenv(key); v != "" {
-		if d, err := time.ParseDuration(v); err == nil {
-			return d
+func (l *loader) string(key, defaultValue string, aliases ...string) string {
+	k, v, src, ok := l.lookup(key, aliases...)
+	if !ok {
+		v = defaultValue
+	}
+	l.record(key, v, sourceLabel(src, k, key))
+	return v
+}
+
+func (l *loader) list(key string) []string {
+	_, v, src, _ := l.lookup(key)
+	l.record(key, v, src)
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
 		}
 	}
+	return out
+}
+
+// tenantKeys lê uma lista "tenant:chave,..." e devolve o mapa chave -> tenant
+func (l *loader) tenantKeys(key string) map[string]string {
+	items := l.list(key)
+	if len(items) == 0 {
+		return nil
+	}
+	keys := make(map[string]string, len(items))
+	for i, item := range items {
+		tenant, apiKey, ok := strings.Cut(item, ":")
+		tenant, apiKey = strings.TrimSpace(tenant), strings.TrimSpace(apiKey)
+		switch {
+		case !ok || tenant == "" || apiKey == "":
+			l.problem("%s: item %d must be tenant:key", key, i+1)
+		case keys[apiKey] != "":
+			l.problem("%s: item %d repeats the key of tenant %q", key, i+1, keys[apiKey])
+		default:
+			keys[apiKey] = tenant
+		}
+	}
+	return keys
+}
+
+func (l *loader) int(key string, defaultValue int, aliases ...string) int {
+	k, v, src, ok := l.lookup(key, aliases...)
+	if !ok {
+		l.record(key, strconv.Itoa(defaultValue), src)
+		return defaultValue
+	}
+	l.record(key, v, sourceLabel(src, k, key))
+	i, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		l.problem("%s: invalid integer %q", k, v)
+		return defaultValue
+	}
+	return i
+}
+
+func (l *loader) float(key string, defaultValue float64) float64 {
+	_, v, src, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatFloat(defaultValue, 'f', -1, 64), src)
+		return defaultValue
+	}
+	l.record(key, v, src)
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		l.problem("%s: invalid number %q", key, v)
+		return defaultValue
+	}
+	return f
+}
+
+func (l *loader) bool(key string, defaultValue bool) bool {
+	_, v, src, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatBool(defaultValue), src)
+		return defaultValue
+	}
+	l.record(key, v, src)
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	l.problem("%s: invalid boolean %q (use true/false)", key, v)
 	return defaultValue
 }
+
+func (l *loader) duration(key string, defaultValue time.Duration, aliases ...string) time.Duration {
+	k, v, src, ok := l.lookup(key, aliases...)
+	if !ok {
+		l.record(key, defaultValue.String(), src)
+		return defaultValue
+	}
+	l.record(key, v, sourceLabel(src, k, key))
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		l.problem("%s: invalid duration %q (e.g. 30s, 5m, 24h)", k, v)
+		return defaultValue
+	}
+	return d
+}
+
+// checkUnknownFileKeys reporta chaves do arquivo que não correspondem a nenhuma variável
+func (l *loader) checkUnknownFileKeys() {
+	for _, k := range sortedKeys(l.fileKeys) {
+		if !l.used[k] {
+			l.problem("config file: unknown key %q", l.fileKeys[k])
+		}
+	}
+}
+
+func sourceLabel(src, resolvedKey, key string) string {
+	if resolvedKey != key {
+		return fmt.Sprintf("%s (%s)", src, resolvedKey)
+	}
+	return src
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cleanEnv tira do ambiente as variáveis da configuração e restaura o
+// ambiente inteiro no fim do teste (LoadFrom exporta o .env e o arquivo)
+func cleanEnv(t *testing.T) {
+	t.Helper()
+	saved := os.Environ()
+	t.Cleanup(func() {
+		os.Clearenv()
+		for _, kv := range saved {
+			k, v, _ := strings.Cut(kv, "=")
+			os.Setenv(k, v)
+		}
+	})
+	keys := []string{"PORT", "READ_TIMEOUT", "WRITE_TIMEOUT", "CONFIG_FILE"}
+	keys = append(keys, passthroughKeys...)
+	for _, k := range keys {
+		os.Unsetenv(k)
+	}
+	// As chaves lidas por LoadFrom ficam em Settings
+	cfg, _ := LoadFrom(Options{EnvFile: writeFile(t, "empty.env", "")})
+	for _, s := range cfg.Settings() {
+		os.Unsetenv(s.Key)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setting(cfg *Config, key string) Setting {
+	for _, s := range cfg.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+	return Setting{}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	cleanEnv(t)
+	file := writeFile(t, "config.yaml", `
+server:
+  port: 7000
+  read_timeout: 20s
+tls_api:
+  url: http://file:8080
+provider:
+  chain: [jevi, n4s]
+retry:
+  max_attempts: 5
+`)
+	env := writeFile(t, ".env", "# comentário\nSERVER_PORT=8000\nexport TLS_API_URL=\"http://dotenv:8080\"\nRETRY_MAX_BACKOFF=9s # inline\n")
+	t.Setenv("SERVER_PORT", "9000")
+
+	cfg, err := LoadFrom(Options{File: file, EnvFile: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		key, value, source string
+	}{
+		{"SERVER_PORT", "9000", SourceEnv},                  // env > .env > arquivo
+		{"TLS_API_URL", "http://dotenv:8080", SourceDotEnv}, // .env > arquivo
+		{"SERVER_READ_TIMEOUT", "20s", SourceFile},
+		{"PROVIDER_CHAIN", "jevi,n4s", SourceFile},
+		{"RETRY_MAX_ATTEMPTS", "5", SourceFile},
+		{"RETRY_MAX_BACKOFF", "9s", SourceDotEnv},
+		{"SERVER_WRITE_TIMEOUT", "1m0s", SourceDefault},
+	} {
+		if got := setting(cfg, c.key); got.Value != c.value || got.Source != c.source {
+			t.Errorf("%s = %+v; want %q from %s", c.key, got, c.value, c.source)
+		}
+	}
+	if cfg.Port != 9000 || cfg.TLSAPIUrl != "http://dotenv:8080" || cfg.ReadTimeout != 20*time.Second ||
+		!reflect.DeepEqual(cfg.ProviderChain, []string{"jevi", "n4s"}) || cfg.RetryMaxAttempts != 5 ||
+		cfg.RetryMaxBackoff != 9*time.Second || cfg.WriteTimeout != time.Minute {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	// O .env e o arquivo são exportados para o pacote scraper
+	if os.Getenv("TLS_API_URL") != "http://dotenv:8080" || os.Getenv("RETRY_MAX_ATTEMPTS") != "5" {
+		t.Error("valores do .env e do arquivo não foram exportados")
+	}
+
+	// Um .env pedido explicitamente precisa existir
+	if _, err := LoadFrom(Options{EnvFile: filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Error("aceitou um .env inexistente")
+	}
+}
+
+func TestLoadAliases(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("PORT", "7777")
+	t.Setenv("READ_TIMEOUT", "3s")
+	env := writeFile(t, ".env", "")
+
+	cfg, err := LoadFrom(Options{EnvFile: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 7777 || cfg.ReadTimeout != 3*time.Second {
+		t.Errorf("Port = %d, ReadTimeout = %s", cfg.Port, cfg.ReadTimeout)
+	}
+	if got := setting(cfg, "SERVER_PORT"); got.Source != "env (PORT)" {
+		t.Errorf("SERVER_PORT = %+v; want the alias in the source", got)
+	}
+
+	// O nome novo tem precedência sobre o alias
+	t.Setenv("SERVER_PORT", "8888")
+	if cfg, err = LoadFrom(Options{EnvFile: env}); err != nil || cfg.Port != 8888 || setting(cfg, "SERVER_PORT").Source != SourceEnv {
+		t.Errorf("Port = %d, %v", cfg.Port, err)
+	}
+
+	// Erros de um alias citam o nome usado
+	os.Unsetenv("SERVER_PORT")
+	t.Setenv("PORT", "abc")
+	_, err = LoadFrom(Options{EnvFile: env})
+	if err == nil || !strings.Contains(err.Error(), `PORT: invalid integer "abc"`) || strings.Contains(err.Error(), "SERVER_PORT: invalid") {
+		t.Errorf("err = %v", err)
+	}
+
+	// REQS_PROVIDER_CACHE_DISABLE (legado) desliga o cache mesmo com _ENABLE
+	t.Setenv("PORT", "7777")
+	t.Setenv("REQS_PROVIDER_CACHE_ENABLE", "true")
+	t.Setenv("REQS_PROVIDER_CACHE_DISABLE", "1")
+	if cfg, err = LoadFrom(Options{EnvFile: env}); err != nil || cfg.CacheEnabled {
+		t.Errorf("CacheEnabled = %v, %v", cfg.CacheEnabled, err)
+	}
+}
+
+func TestLoadAggregatesProblems(t *testing.T) {
+	cleanEnv(t)
+	file := writeFile(t, "config.json", `{"server": {"port": 0}, "foo": {"bar": 1}}`)
+	env := writeFile(t, ".env", "RETRY_MAX_ATTEMPTS=abc\nDEBUG=maybe\n")
+	t.Setenv("CACHE_BACKEND", "memcached")
+	t.Setenv("PROVIDER_EXPLORATION_RATE", "2")
+	t.Setenv("PROVIDER_CHAIN", "jevi,hyper")
+	t.Setenv("TLS_API_URL", "ftp://tls-api")
+	t.Setenv("TENANT_API_KEYS", "acme:k1,beta:k1,nokey")
+
+	cfg, err := LoadFrom(Options{File: file, EnvFile: env, Providers: []string{"jevi", "n4s", "roolink"}})
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("err = %v; want a *ValidationError", err)
+	}
+	if cfg == nil {
+		t.Fatal("cfg nil junto com o ValidationError")
+	}
+	want := []string{
+		`RETRY_MAX_ATTEMPTS: invalid integer "abc"`,
+		`TENANT_API_KEYS: item 2 repeats the key of tenant "acme"`,
+		"TENANT_API_KEYS: item 3 must be tenant:key",
+		`DEBUG: invalid boolean "maybe" (use true/false)`,
+		`config file: unknown key "foo.bar"`,
+		"SERVER_PORT: must be between 1 and 65535, got 0",
+		`TLS_API_URL: must be an http(s) URL or a comma-separated list of them, got "ftp://tls-api"`,
+		`PROVIDER_CHAIN: unsupported provider "hyper" (supported: jevi, n4s, roolink)`,
+		"PROVIDER_EXPLORATION_RATE: must be between 0 and 1, got 2",
+		`CACHE_BACKEND: must be file, sqlite or redis, got "memcached"`,
+	}
+	if !reflect.DeepEqual(ve.Problems, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(ve.Problems, "\n"), strings.Join(want, "\n"))
+	}
+	if !strings.Contains(err.Error(), "(10 problems)") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	// Erros de leitura do arquivo não são de validação
+	bad := writeFile(t, "bad.yaml", "server: [1, {")
+	if _, err := LoadFrom(Options{File: bad, EnvFile: env}); err == nil || errors.As(err, &ve) {
+		t.Errorf("arquivo inválido: %v", err)
+	}
+}
+
+func TestTLSAPIURLs(t *testing.T) {
+	cfg := &Config{TLSAPIUrl: " http://a:8080/ ,http://b:8080,, http://a:8080"}
+	if got := cfg.TLSAPIURLs(); !reflect.DeepEqual(got, []string{"http://a:8080", "http://b:8080"}) {
+		t.Errorf("TLSAPIURLs = %v", got)
+	}
+}
+
+func TestWriteEffectiveRedacts(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("JEVI_API_KEY", "jevi-secret")
+	t.Setenv("TLS_API_TOKEN", "tls-secret")
+	t.Setenv("TENANT_API_KEYS", "acme:tenant-secret")
+	t.Setenv("TLS_API_URL", "http://user:url-secret@a:8080,http://b:8080")
+	t.Setenv("CACHE_REDIS_URL", "redis://:redis-secret@cache:6379/0")
+
+	cfg, err := LoadFrom(Options{EnvFile: writeFile(t, ".env", "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cfg.WriteEffective(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("segredo impresso:\n%s", out)
+	}
+	for _, line := range []string{
+		"JEVI_API_KEY=<redacted>  # env",
+		"TLS_API_TOKEN=<redacted>  # env",
+		"TENANT_API_KEYS=<redacted>  # env",
+		"N4S_API_KEY=  # default", // Vazio continua visível
+		"TLS_API_URL=http://user:redacted@a:8080,http://b:8080  # env",
+		"CACHE_REDIS_URL=redis://:redacted@cache:6379/0  # env",
+		"SERVER_PORT=9999  # default",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("falta %q em:\n%s", line, out)
+		}
+	}
+}
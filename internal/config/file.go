@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options controla de onde LoadFrom lê a configuração
+type Options struct {
+	File    string // Arquivo YAML ou JSON (opcional)
+	EnvFile string // Arquivo .env; vazio usa ".env" se existir
+
+	// Providers aceitos em PROVIDER_CHAIN (scraper.SupportedProviders);
+	// vazio não valida os nomes
+	Providers []string
+}
+
+// Origens de um valor, da menor para a maior precedência
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceDotEnv  = ".env"
+	SourceEnv     = "env"
+)
+
+// readConfigFile lê um arquivo YAML ou JSON (JSON é YAML válido) e achata as
+// seções em nomes de variáveis: {"server": {"port": 1}} vira SERVER_PORT=1.
+// Listas viram valores separados por vírgula.
+func readConfigFile(path string) (map[string]string, map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read config file: %w", err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	values := make(map[string]string)
+	keys := make(map[string]string)
+	if err := flattenConfig("", raw, values, keys); err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, keys, nil
+}
+
+func flattenConfig(prefix string, node map[string]interface{}, values, keys map[string]string) error {
+	for k, v := range node {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		envKey := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(path, val, values, keys); err != nil {
+				return err
+			}
+			continue
+		case []interface{}:
+			items := make([]string, 0, len(val))
+			for _, item := range val {
+				s, err := scalarString(item)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				items = append(items, s)
+			}
+			values[envKey] = strings.Join(items, ",")
+		default:
+			s, err := scalarString(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			values[envKey] = s
+		}
+		if prev, ok := keys[envKey]; ok {
+			return fmt.Errorf("keys %q and %q both set %s", prev, path, envKey)
+		}
+		keys[envKey] = path
+	}
+	return nil
+}
+
+func scalarString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v (%T)", v, v)
+	}
+}
+
+// readDotEnv lê um arquivo KEY=VALUE. Linhas vazias e comentários (#) são
+// ignorados; aspas simples ou duplas em volta do valor são removidas.
+// Um arquivo ausente só é erro quando foi pedido explicitamente.
+func readDotEnv(path string, required bool) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read env file: %w", err)
+	}
+	defer f.Close()
+
+	out := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", filepath.Base(path), n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		out[key] = value
+	}
+	return out, sc.Err()
+}
+
+// sortedKeys é usado para mensagens determinísticas
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
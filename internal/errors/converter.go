@@ -14,6 +14,23 @@ func (e *SolverError) ToErrorResponse() *response.ErrorResponse {
 			ElapsedMs:   e.ElapsedMs(),
 		}
 	}
+	if len(e.ScriptCandidates) > 0 {
+		if ctx == nil {
+			ctx = &response.ErrorContext{}
+		}
+		ctx.ScriptCandidates = e.ScriptCandidates
+	}
+	blockReason := ""
+	if e.Block != nil {
+		if ctx == nil {
+			ctx = &response.ErrorContext{}
+		}
+		blockReason = string(e.Block.Reason)
+		ctx.SiteStatus = e.Block.Status
+		ctx.ReferenceID = e.Block.ReferenceID
+		ctx.SuggestedAction = string(e.Block.Action)
+		ctx.RetryAfter = e.Block.RetryAfter
+	}
 
 	rawErrorMsg := ""
 	if e.RawError != nil {
@@ -31,6 +48,7 @@ func (e *SolverError) ToErrorResponse() *response.ErrorResponse {
 			HTTPStatus:  e.HTTPStatus(),
 			RawError:    rawErrorMsg,
 			Retryable:   e.IsRetryable(),
+			BlockReason: blockReason,
 			Context:     ctx,
 		},
 	}
@@ -42,6 +60,12 @@ func WithPartialCookies(resp *response.ErrorResponse, cookies *response.Cookies)
 	return resp
 }
 
+// WithTimeline adiciona a timeline dos steps executados à resposta de erro
+func WithTimeline(resp *response.ErrorResponse, timeline []response.TimelineEntry) *response.ErrorResponse {
+	resp.Timeline = timeline
+	return resp
+}
+
 // WithDebug adiciona informações de debug à resposta de erro
 func WithDebug(resp *response.ErrorResponse, reportPath string) *response.ErrorResponse {
 	if reportPath != "" {
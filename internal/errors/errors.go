@@ -3,6 +3,9 @@ package errors
 import (
 	"fmt"
 	"time"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
 )
 
 // StepCode representa o código do step onde ocorreu o erro
@@ -20,6 +23,7 @@ const (
 	StepSbsdPost         StepCode = "sbsd_post"
 	StepCookieValidation StepCode = "cookie_validation"
 	StepTLSAPIError      StepCode = "tls_api_error"
+	StepQuotaExceeded    StepCode = "quota_exceeded"
 )
 
 // stepInfo contém metadados de cada step
@@ -42,6 +46,7 @@ var stepInfoMap = map[StepCode]stepInfo{
 	StepSbsdPost:         {9, "Akamai rejeitou challenge SbSd", 518, true},
 	StepCookieValidation: {10, "Cookie gerado mas validação falhou", 518, true},
 	StepTLSAPIError:      {11, "TLS-API indisponível", 518, true},
+	StepQuotaExceeded:    {12, "Orçamento de chamadas ao provider esgotado", 429, false},
 }
 
 // SolverError representa um erro detalhado do solver
@@ -53,6 +58,12 @@ type SolverError struct {
 	Attempt     int
 	MaxAttempts int
 	StartTime   time.Time
+
+	// Candidatos ranqueados quando nenhum script foi aceito (script_url_extraction)
+	ScriptCandidates []response.ScriptCandidate
+
+	// Classificação da homepage bloqueada (script_url_extraction)
+	Block *scraper.BlockClassification
 }
 
 // Error implementa a interface error
@@ -71,6 +82,10 @@ func (e *SolverError) HTTPStatus() int {
 
 // IsRetryable indica se o erro permite retry
 func (e *SolverError) IsRetryable() bool {
+	// Homepage bloqueada: decide pela ação sugerida (407 ou bloqueio desconhecido não melhoram com retry)
+	if e.Block != nil {
+		return e.Block.Action != scraper.ActionGiveUp
+	}
 	if info, ok := stepInfoMap[e.Step]; ok {
 		return info.Retryable
 	}
@@ -208,3 +223,13 @@ func NewTLSAPIError(err error, domain string) *SolverError {
 		StartTime: time.Now(),
 	}
 }
+
+func NewQuotaExceededError(err error, provider, domain string) *SolverError {
+	return &SolverError{
+		Step:      StepQuotaExceeded,
+		Provider:  provider,
+		Domain:    domain,
+		RawError:  err,
+		StartTime: time.Now(),
+	}
+}
@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"fmt"
+	"time"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// FromSolveError converte o erro de scraper.Solve no erro do step equivalente.
+// Erros que não vêm de scraper.Solve são devolvidos sem alteração.
+func FromSolveError(err error, domain string) error {
+	se, ok := err.(*scraper.SolveError)
+	if !ok {
+		return err
+	}
+	started := time.Now()
+	switch se.Step {
+	case scraper.StepScraperInit:
+		return NewScraperInitError(se.Err, domain)
+	case scraper.StepScriptURLExtract:
+		e := NewScriptURLExtractionError(se.Err, domain)
+		switch cause := se.Err.(type) {
+		case *scraper.ScriptDiscoveryError:
+			e.ScriptCandidates = scriptCandidates(cause.Candidates)
+		case *scraper.HomepageBlockedError:
+			e.Block = cause.Classification
+		}
+		return e
+	case scraper.StepScriptFetch:
+		return NewScriptFetchError(se.Err, domain)
+	case scraper.StepScriptDecode:
+		return NewScriptDecodeError(se.Err, domain)
+	case scraper.StepBmSoExtraction:
+		return NewBmSoExtractionError(domain)
+	case scraper.StepSensorPost:
+		return NewSensorPostError(se.Err, se.Provider, domain, se.Attempt, se.MaxAttempts, started)
+	case scraper.StepSbsdGeneration:
+		return NewSbsdGenerationError(se.Err, se.Provider, domain)
+	case scraper.StepSbsdPost:
+		e := NewSbsdPostError(rawError(se.Err), se.Provider, domain)
+		e.Attempt, e.MaxAttempts = se.Attempt, se.MaxAttempts
+		return e
+	case scraper.StepCookieValidation:
+		return NewCookieValidationError(domain, se.Attempt, se.MaxAttempts, started)
+	case scraper.StepTLSAPIError:
+		return NewTLSAPIError(se.Err, domain)
+	case scraper.StepQuotaExceeded:
+		provider := se.Provider
+		if qe, ok := se.Err.(*scraper.SolverError); ok && qe.Provider != "" {
+			provider = qe.Provider
+		}
+		return NewQuotaExceededError(rawError(se.Err), provider, domain)
+	default:
+		return NewProviderCallError(se.Err, se.Provider, domain, se.Attempt, se.MaxAttempts, started)
+	}
+}
+
+// scriptCandidates converte os candidatos da descoberta para a resposta
+func scriptCandidates(in []scraper.ScriptCandidate) []response.ScriptCandidate {
+	out := make([]response.ScriptCandidate, 0, len(in))
+	for _, c := range in {
+		out = append(out, response.ScriptCandidate{
+			URL:      c.URL,
+			Source:   c.Source,
+			Score:    c.Score,
+			Eligible: c.Eligible,
+			Reasons:  c.Reasons,
+		})
+	}
+	return out
+}
+
+// rawError devolve só a mensagem original quando err é um erro do scraper
+func rawError(err error) error {
+	if se, ok := err.(*scraper.SolverError); ok {
+		return fmt.Errorf("%s", se.RawError)
+	}
+	return err
+}
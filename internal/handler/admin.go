@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gerador_cookies/internal/response"
+)
+
+// RequireAdminToken protege rotas administrativas com "Authorization: Bearer <token>".
+// Com token vazio todas as requests são recusadas; o servidor nem monta as
+// rotas administrativas sem ADMIN_TOKEN.
+func RequireAdminToken(token string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				response.WriteError(w, http.StatusUnauthorized, &response.ErrorResponse{
+					Success: false,
+					Error: &response.ErrorDetail{
+						Step:        "admin_auth",
+						Description: "Token administrativo inválido ou ausente",
+						RawError:    "unauthorized",
+					},
+				})
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// CacheEntriesResponse é o payload de GET /admin/cache
+type CacheEntriesResponse struct {
+	Entries     []CacheEntry `json:"entries"`
+	GeneratedAt time.Time    `json:"generated_at"`
+}
+
+// CacheEntry é uma entrada do cache de providers na API. O formato gravado
+// pelos backends (scraper.ProviderCacheEntry) não muda com ela.
+type CacheEntry struct {
+	Domain         string    `json:"domain"`
+	Provider       string    `json:"provider"`
+	Mode           string    `json:"mode"`
+	ScriptURL      string    `json:"script_url"`
+	ScriptHash     string    `json:"script_hash,omitempty"`
+	ScriptHeadHash string    `json:"script_head_hash,omitempty"`
+	Dynamic        string    `json:"dynamic"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func newCacheEntry(e scraper.ProviderCacheEntry) CacheEntry {
+	return CacheEntry{
+		Domain:         e.Domain,
+		Provider:       e.Provider,
+		Mode:           e.Mode,
+		ScriptURL:      e.ScriptURL,
+		ScriptHash:     e.ScriptHash,
+		ScriptHeadHash: e.ScriptHeadHash,
+		Dynamic:        e.Dynamic,
+		ExpiresAt:      e.ExpiresAt,
+		UpdatedAt:      e.UpdatedAt,
+	}
+}
+
+// CacheInvalidateResponse é o payload de DELETE /admin/cache
+type CacheInvalidateResponse struct {
+	Removed int `json:"removed"`
+}
+
+type CacheHandler struct {
+	cache *scraper.ProviderCache
+}
+
+// NewCacheHandler recebe o cache compartilhado pelos solvers
+// (scraper.DefaultProviderCache), para que as rotas vejam as mesmas entradas
+func NewCacheHandler(cache *scraper.ProviderCache) *CacheHandler {
+	return &CacheHandler{
+		cache: cache,
+	}
+}
+
+// List retorna as entradas do cache, inclusive expiradas.
+// Filtros opcionais: ?domain=, ?provider=, ?mode=
+func (h *CacheHandler) List(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.cache.List(cacheFilterFromQuery(r))
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	resp := &CacheEntriesResponse{
+		Entries:     make([]CacheEntry, 0, len(entries)),
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, newCacheEntry(e))
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Get retorna uma entrada: GET /admin/cache/{domain}/{provider}/{mode}
+func (h *CacheHandler) Get(w http.ResponseWriter, r *http.Request) {
+	entry, found, err := h.cache.Entry(r.PathValue("domain"), r.PathValue("provider"), r.PathValue("mode"))
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	if !found {
+		response.WriteError(w, http.StatusNotFound, &response.ErrorResponse{
+			Success: false,
+			Error: &response.ErrorDetail{
+				Step:        "cache_lookup",
+				Description: "Entrada não encontrada no cache",
+				RawError:    "cache entry not found",
+			},
+		})
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, newCacheEntry(entry))
+}
+
+// Invalidate remove as entradas que casam com ?domain=, ?provider= e ?mode=.
+// Sem filtros é preciso passar ?all=true para limpar o cache inteiro.
+func (h *CacheHandler) Invalidate(w http.ResponseWriter, r *http.Request) {
+	filter := cacheFilterFromQuery(r)
+	if filter.IsEmpty() && r.URL.Query().Get("all") != "true" {
+		response.WriteError(w, http.StatusBadRequest, &response.ErrorResponse{
+			Success: false,
+			Error: &response.ErrorDetail{
+				Step:        "request_validation",
+				Description: "Informe domain, provider ou mode (ou all=true para limpar tudo)",
+				RawError:    "missing invalidation filter",
+			},
+		})
+		return
+	}
+	removed, err := h.cache.Invalidate(filter)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, &CacheInvalidateResponse{Removed: removed})
+}
+
+func cacheFilterFromQuery(r *http.Request) scraper.CacheFilter {
+	q := r.URL.Query()
+	return scraper.CacheFilter{
+		Domain:   q.Get("domain"),
+		Provider: q.Get("provider"),
+		Mode:     q.Get("mode"),
+	}
+}
+
+func writeCacheError(w http.ResponseWriter, err error) {
+	response.WriteError(w, http.StatusInternalServerError, &response.ErrorResponse{
+		Success: false,
+		Error: &response.ErrorDetail{
+			Step:        "cache_store",
+			Description: "Falha ao acessar o cache de providers",
+			RawError:    err.Error(),
+			Retryable:   true,
+		},
+	})
+}
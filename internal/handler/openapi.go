@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"gerador_cookies/internal/openapi"
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// APIVersion é a versão do contrato HTTP publicada em /openapi.json
+const APIVersion = "1.0.0"
+
+// BuildOpenAPI gera o documento OpenAPI 3.1 a partir dos tipos de request e
+// response usados pelos handlers. Novas rotas devem ser registradas aqui.
+func BuildOpenAPI() *openapi.Document {
+	doc := openapi.New(
+		"Gerador de Cookies Akamai",
+		APIVersion,
+		"Geração de cookies Akamai (_abck, bm_sz, sbsd) via TLS-API e providers externos.",
+	)
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"adminToken": {Type: "http", Scheme: "bearer"},
+		"apiKey":     {Type: "apiKey", In: "header", Name: APIKeyHeader},
+	}
+
+	errorResp := doc.SchemaFor(response.ErrorResponse{})
+	errorResponses := func(codes map[string]string) map[string]*openapi.Response {
+		out := make(map[string]*openapi.Response, len(codes))
+		for code, desc := range codes {
+			out[code] = &openapi.Response{Description: desc, Content: openapi.JSONBody(errorResp)}
+		}
+		return out
+	}
+
+	// POST /sbsd
+	sbsdResponses := errorResponses(map[string]string{
+		"400": "Request inválida; error.fields lista cada campo com problema (inclusive perfil não suportado pela TLS-API)",
+		"401": "Chave de API inválida ou ausente (quando o servidor define TENANT_API_KEYS)",
+		"429": "Orçamento de chamadas ao provider esgotado (quota_exceeded)",
+		"500": "Erro interno do servidor",
+		"518": "Falha em um step do fluxo; veja error.step e error.retryable",
+	})
+	sbsdResponses["200"] = &openapi.Response{
+		Description: "Cookies gerados. Com Accept: text/event-stream, eventos progress durante a geração e, por último, result (SuccessResponse) ou error (ErrorResponse)",
+		Content:     openapi.JSONBody(doc.SchemaFor(response.SuccessResponse{})),
+	}
+	sbsdResponses["200"].Content["text/event-stream"] = &openapi.MediaType{Schema: doc.SchemaFor(scraper.ProgressEvent{})}
+	doc.Add("POST", "/sbsd", &openapi.Operation{
+		OperationID: "generateSbsd",
+		Summary:     "Resolve o challenge SBSD e retorna os cookies",
+		Tags:        []string{"solve"},
+		Security:    []map[string][]string{{"apiKey": {}}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONBody(doc.SchemaFor(SbsdRequest{}))},
+		Responses:   sbsdResponses,
+	})
+	// Property e Item entram em pânico se a tag json mudar; o teste pega isso
+	req := doc.ComponentFor(SbsdRequest{})
+	req.Property("url").Description = "Host ou URL do site; normalizado para o host"
+	req.Property("akamaiUrl").Description = "Caminho do script anti-bot; substitui a detecção automática"
+	req.Property("proxy").Description = "http, https, socks5 ou socks5h com host e porta"
+	req.Property("akamaiProvider").Enum = scraper.SupportedProviders
+	req.Property("providerChain").Item().Enum = scraper.SupportedProviders
+	req.Property("randomUserAgent").Description = "Perfil de navegador da TLS-API (chrome_*, firefox_* ou safari_*); veja GET /profiles"
+
+	// GET /profiles
+	doc.Add("GET", "/profiles", &openapi.Operation{
+		OperationID: "listProfiles",
+		Summary:     "Perfis de navegador aceitos em randomUserAgent, conforme a TLS-API",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Perfis", Content: openapi.JSONBody(doc.SchemaFor(ProfilesResponse{}))},
+		},
+	})
+
+	// Rotas administrativas (stats, uso e cache) exigem o ADMIN_TOKEN
+	admin := []map[string][]string{{"adminToken": {}}}
+	unauthorized := &openapi.Response{Description: "Token administrativo inválido ou ausente", Content: openapi.JSONBody(errorResp)}
+
+	// GET /stats/providers
+	doc.Add("GET", "/stats/providers", &openapi.Operation{
+		OperationID: "getProviderStats",
+		Summary:     "Estatísticas por (domínio, provider, modo)",
+		Tags:        []string{"stats"},
+		Parameters:  queryParams("domain", "provider", "mode"),
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Estatísticas", Content: openapi.JSONBody(doc.SchemaFor(ProviderStatsResponse{}))},
+			"401": unauthorized,
+		},
+	})
+
+	// GET /usage
+	doc.Add("GET", "/usage", &openapi.Operation{
+		OperationID: "getUsage",
+		Summary:     "Chamadas aos providers por dia, tenant, provider e domínio",
+		Tags:        []string{"stats"},
+		Parameters:  queryParams("from", "to", "tenant", "provider", "domain"),
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Relatório de uso", Content: openapi.JSONBody(doc.SchemaFor(scraper.UsageReport{}))},
+			"401": unauthorized,
+		},
+	})
+
+	// /admin/cache
+	doc.Add("GET", "/admin/cache", &openapi.Operation{
+		OperationID: "listCacheEntries",
+		Summary:     "Lista entradas do cache de providers",
+		Tags:        []string{"admin"},
+		Parameters:  queryParams("domain", "provider", "mode"),
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Entradas", Content: openapi.JSONBody(doc.SchemaFor(CacheEntriesResponse{}))},
+			"401": unauthorized,
+		},
+	})
+	doc.Add("DELETE", "/admin/cache", &openapi.Operation{
+		OperationID: "invalidateCache",
+		Summary:     "Invalida entradas do cache (all=true limpa tudo)",
+		Tags:        []string{"admin"},
+		Parameters:  queryParams("domain", "provider", "mode", "all"),
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Quantidade removida", Content: openapi.JSONBody(doc.SchemaFor(CacheInvalidateResponse{}))},
+			"400": {Description: "Nenhum filtro informado", Content: openapi.JSONBody(errorResp)},
+			"401": unauthorized,
+		},
+	})
+	doc.Add("GET", "/admin/cache/{domain}/{provider}/{mode}", &openapi.Operation{
+		OperationID: "getCacheEntry",
+		Summary:     "Mostra uma entrada do cache",
+		Tags:        []string{"admin"},
+		Parameters:  pathParams("domain", "provider", "mode"),
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Entrada", Content: openapi.JSONBody(doc.SchemaFor(CacheEntry{}))},
+			"401": unauthorized,
+			"404": {Description: "Entrada não encontrada", Content: openapi.JSONBody(errorResp)},
+		},
+	})
+
+	// GET /openapi.json
+	doc.Add("GET", "/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "Este documento",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Documento OpenAPI 3.1", Content: openapi.JSONBody(&openapi.Schema{Type: "object"})},
+		},
+	})
+
+	return doc
+}
+
+func queryParams(names ...string) []openapi.Parameter {
+	out := make([]openapi.Parameter, 0, len(names))
+	for _, n := range names {
+		out = append(out, openapi.Parameter{Name: n, In: "query", Schema: &openapi.Schema{Type: "string"}})
+	}
+	return out
+}
+
+func pathParams(names ...string) []openapi.Parameter {
+	out := make([]openapi.Parameter, 0, len(names))
+	for _, n := range names {
+		out = append(out, openapi.Parameter{Name: n, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+	}
+	return out
+}
+
+// OpenAPIHandler serve o documento gerado (montado uma vez, na primeira request)
+type OpenAPIHandler struct {
+	once sync.Once
+	body []byte
+	err  error
+}
+
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{}
+}
+
+func (h *OpenAPIHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				h.err = fmt.Errorf("build openapi: %v", r)
+			}
+		}()
+		h.body, h.err = json.MarshalIndent(BuildOpenAPI(), "", "  ")
+	})
+	if h.err != nil {
+		http.Error(w, h.err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.body)
+}
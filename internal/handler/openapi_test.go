@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gerador_cookies/internal/openapi"
+	"gerador_cookies/internal/response"
+)
+
+// TestOpenAPISchemasMatchJSONTags confere cada schema publicado com as tags
+// json dos tipos de request e response (e dos structs que eles usam)
+func TestOpenAPISchemasMatchJSONTags(t *testing.T) {
+	doc := BuildOpenAPI()
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	seen := make(map[reflect.Type]bool)
+	for _, v := range []interface{}{SbsdRequest{}, response.SuccessResponse{}, response.ErrorResponse{}} {
+		checkStructSchema(t, doc, reflect.TypeOf(v), seen)
+	}
+}
+
+func checkStructSchema(t *testing.T, doc *openapi.Document, typ reflect.Type, seen map[reflect.Type]bool) {
+	t.Helper()
+	if seen[typ] {
+		return
+	}
+	seen[typ] = true
+
+	schema := doc.ComponentFor(reflect.New(typ).Elem().Interface())
+	var props, required []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		props = append(props, name)
+		omitempty := strings.Contains(opts, "omitempty")
+		if !omitempty {
+			required = append(required, name)
+		}
+
+		prop, ok := schema.Properties[name]
+		if !ok {
+			t.Errorf("%s: property %q missing", typ.Name(), name)
+			continue
+		}
+		if f.Type.Kind() == reflect.Ptr && !omitempty {
+			if len(prop.AnyOf) != 2 || prop.AnyOf[1].Type != "null" {
+				t.Errorf("%s.%s: pointer without omitempty must be nullable, got %+v", typ.Name(), name, prop)
+			}
+		}
+
+		elem := f.Type
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Slice || elem.Kind() == reflect.Map {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct && elem.Name() != "" && elem != reflect.TypeOf(time.Time{}) {
+			checkStructSchema(t, doc, elem, seen)
+		}
+	}
+
+	var got []string
+	for name := range schema.Properties {
+		got = append(got, name)
+	}
+	sort.Strings(got)
+	sort.Strings(props)
+	sort.Strings(required)
+	if !reflect.DeepEqual(got, props) {
+		t.Errorf("%s: properties %v, json tags %v", typ.Name(), got, props)
+	}
+	if !reflect.DeepEqual(schema.Required, required) {
+		t.Errorf("%s: required %v, fields without omitempty %v", typ.Name(), schema.Required, required)
+	}
+}
+
+// Session tem o mesmo nome de response.Session, de outro pacote
+type Session struct {
+	ID string `json:"id"`
+}
+
+func TestOpenAPIComponentsWithSameNameDoNotCollide(t *testing.T) {
+	doc := openapi.New("t", "1", "")
+	a := doc.SchemaFor(response.Session{})
+	b := doc.SchemaFor(Session{})
+	if a.Ref == b.Ref {
+		t.Fatalf("both types registered as %s", a.Ref)
+	}
+	if _, ok := doc.ComponentFor(response.Session{}).Properties["provider"]; !ok {
+		t.Errorf("response.Session was overwritten")
+	}
+	if _, ok := doc.ComponentFor(Session{}).Properties["id"]; !ok {
+		t.Errorf("handler.Session has the wrong schema")
+	}
+	if a2 := doc.SchemaFor(&response.Session{}); a2.Ref != a.Ref {
+		t.Errorf("same type registered twice: %s and %s", a.Ref, a2.Ref)
+	}
+}
+
+func TestOpenAPIPropertyPanicsWhenMissing(t *testing.T) {
+	doc := openapi.New("t", "1", "")
+	req := doc.ComponentFor(SbsdRequest{})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Property did not panic for a missing property")
+		}
+	}()
+	req.Property("akamai_url")
+}
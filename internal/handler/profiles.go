@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// DefaultProfileType é o perfil usado quando a request não informa randomUserAgent
+const DefaultProfileType = "chrome_144"
+
+// ProfilesResponse é o payload de GET /profiles
+type ProfilesResponse struct {
+	Profiles  []string   `json:"profiles"`             // Aceitos em randomUserAgent
+	Default   string     `json:"default"`              // Usado quando randomUserAgent é omitido
+	TLSAPI    []string   `json:"tls_api,omitempty"`    // Perfis reportados pela TLS-API
+	FetchedAt *time.Time `json:"fetched_at,omitempty"` // Última consulta bem-sucedida à TLS-API
+	Error     string     `json:"error,omitempty"`      // Falha da última consulta (a lista anterior é mantida)
+}
+
+type ProfilesHandler struct {
+	profiles *scraper.TLSAPIProfiles
+}
+
+func NewProfilesHandler(profiles *scraper.TLSAPIProfiles) *ProfilesHandler {
+	return &ProfilesHandler{
+		profiles: profiles,
+	}
+}
+
+// Handle lista os perfis de navegador aceitos: os perfis da TLS-API cuja
+// família tem conjunto de headers. Enquanto a TLS-API não respondeu, lista os
+// perfis conhecidos.
+func (h *ProfilesHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	tlsProfiles, fetchedAt, err := h.profiles.List()
+
+	candidates := tlsProfiles
+	if !h.profiles.Known() {
+		candidates = scraper.KnownProfileTypes
+	}
+	resp := &ProfilesResponse{
+		Profiles: make([]string, 0, len(candidates)),
+		Default:  DefaultProfileType,
+		TLSAPI:   tlsProfiles,
+	}
+	for _, p := range candidates {
+		if scraper.ProfileFamily(p) != "" {
+			resp.Profiles = append(resp.Profiles, p)
+		}
+	}
+	if !fetchedAt.IsZero() {
+		t := fetchedAt.UTC()
+		resp.FetchedAt = &t
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
@@ -1,25 +1,34 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"gerador_cookies/internal/config"
 	"gerador_cookies/internal/errors"
 	"gerador_cookies/internal/response"
 	"gerador_cookies/internal/service"
+	"gerador_cookies/scraper"
 )
 
+// SbsdRequest é o corpo de POST /sbsd. Só url é obrigatório; os demais
+// campos usam o perfil do site ou os defaults do servidor.
 type SbsdRequest struct {
-	URL            string `json:"url"`
-	AkamaiURL      string `json:"akamaiUrl"`
-	Proxy          string `json:"proxy"`
-	RandomUA       string `json:"randomUserAgent"`
-	UserAgent      string `json:"userAgent"`
-	SecChUa        string `json:"secChUa"`
-	Language       string `json:"language"`
-	AkamaiProvider string `json:"akamaiProvider"`
-	GenerateReport bool   `json:"generateReport"`
+	URL            string   `json:"url"`
+	AkamaiURL      string   `json:"akamaiUrl,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
+	RandomUA       string   `json:"randomUserAgent,omitempty"`
+	UserAgent      string   `json:"userAgent,omitempty"`
+	SecChUa        string   `json:"secChUa,omitempty"`
+	Language       string   `json:"language,omitempty"`
+	AkamaiProvider string   `json:"akamaiProvider,omitempty"`
+	ProviderChain  []string `json:"providerChain,omitempty"`
+	GenerateReport bool     `json:"generateReport,omitempty"`
+
+	// Sobrescreve a política de retry do perfil do site e do servidor
+	Retry *scraper.RetrySpec `json:"retry,omitempty"`
 }
 
 type SbsdHandler struct {
@@ -35,42 +44,54 @@ func NewSbsdHandler(cfg *config.Config, svc *service.SolverService) *SbsdHandler
 }
 
 func (h *SbsdHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// 1. Decode request
+	// 1. Decode request (campos desconhecidos e corpos grandes são rejeitados)
 	var req SbsdRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.WriteError(w, http.StatusBadRequest, &response.ErrorResponse{
-			Success: false,
-			Error: &response.ErrorDetail{
-				Step:        "request_decode",
-				StepNumber:  0,
-				Description: "Falha ao decodificar request JSON",
-				RawError:    err.Error(),
-				Retryable:   false,
-			},
-		})
+	fe := decodeStrict(w, r, &req)
+	if fe.hasBodyError() {
+		writeValidationError(w, fe)
 		return
 	}
 
-	// 2. Validar campos obrigatórios
-	if req.URL == "" {
-		response.WriteError(w, http.StatusBadRequest, &response.ErrorResponse{
-			Success: false,
-			Error: &response.ErrorDetail{
-				Step:        "request_validation",
-				StepNumber:  0,
-				Description: "Campo 'url' é obrigatório",
-				RawError:    "missing required field: url",
-				Retryable:   false,
-			},
-		})
+	// 2. Validar e normalizar campos (todos os erros voltam em um único 400)
+	if fe = fe.merge(req.normalize()); len(fe) > 0 {
+		writeValidationError(w, fe)
 		return
 	}
 
-	// 3. Aplicar defaults
+	// 3. Aplicar defaults e conferir o perfil contra a TLS-API
 	h.applyDefaults(&req)
+	if err := scraper.DefaultTLSAPIProfiles().Check(req.RandomUA); err != nil {
+		supported, _, _ := scraper.DefaultTLSAPIProfiles().List()
+		fe.add("randomUserAgent", "perfil %q não suportado pela TLS-API (%s)", req.RandomUA, strings.Join(supported, ", "))
+		writeValidationError(w, fe)
+		return
+	}
 
-	// 4. Executar fluxo SbSd
-	result, err := h.service.GenerateSbsd(r.Context(), &service.SbsdInput{
+	// 4. Executar fluxo SbSd (retry já validado em normalize). Com Accept:
+	// text/event-stream o progresso de cada step é enviado enquanto roda, com
+	// heartbeats, e a geração é cancelada se o cliente desconectar
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	var stream *response.EventStream
+	var progress scraper.ProgressFunc
+	if response.WantsEventStream(r) {
+		if stream = response.NewEventStream(w); stream != nil {
+			stream.KeepAlive(response.HeartbeatInterval)
+			defer stream.Close()
+			go func() {
+				select {
+				case <-stream.Failed():
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			progress = func(ev scraper.ProgressEvent) {
+				stream.Send(response.EventProgress, ev)
+			}
+		}
+	}
+	retry, _ := req.Retry.Policy()
+	result, err := h.service.GenerateSbsd(ctx, &service.SbsdInput{
 		Domain:         req.URL,
 		AkamaiURL:      req.AkamaiURL,
 		Proxy:          req.Proxy,
@@ -79,7 +100,11 @@ func (h *SbsdHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		SecChUa:        req.SecChUa,
 		Language:       req.Language,
 		AkamaiProvider: req.AkamaiProvider,
+		ProviderChain:  req.ProviderChain,
+		Tenant:         tenantFromRequest(r),
 		GenerateReport: req.GenerateReport,
+		Retry:          retry,
+		Progress:       progress,
 	})
 
 	// 5. Tratar erro
@@ -90,20 +115,24 @@ func (h *SbsdHandler) Handle(w http.ResponseWriter, r *http.Request) {
 			if result != nil && result.PartialCookies != nil {
 				errors.WithPartialCookies(errResp, result.PartialCookies)
 			}
+			if result != nil {
+				errors.WithTimeline(errResp, result.Timeline)
+			}
 			if req.GenerateReport && result != nil && result.ReportPath != "" {
 				errors.WithDebug(errResp, result.ReportPath)
 			}
 
-			response.WriteError(w, solverErr.HTTPStatus(), errResp)
+			writeSolveError(w, stream, solverErr.HTTPStatus(), errResp)
 			return
 		}
 
 		// Erro genérico
-		response.WriteError(w, http.StatusInternalServerError, &response.ErrorResponse{
+		writeSolveError(w, stream, http.StatusInternalServerError, &response.ErrorResponse{
 			Success: false,
 			Error: &response.ErrorDetail{
 				Step:        "unknown",
 				Description: "Erro interno do servidor",
+				HTTPStatus:  http.StatusInternalServerError,
 				RawError:    err.Error(),
 				Retryable:   false,
 			},
@@ -112,17 +141,83 @@ func (h *SbsdHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 6. Sucesso
-	response.WriteSuccess(w, &response.SuccessResponse{
+	resp := &response.SuccessResponse{
 		Success:   true,
 		Cookies:   result.Cookies,
 		Telemetry: result.Telemetry,
 		Session:   result.Session,
-	})
+		Timeline:  result.Timeline,
+	}
+	if stream != nil {
+		stream.Send(response.EventResult, resp)
+		return
+	}
+	response.WriteSuccess(w, resp)
+}
+
+// writeSolveError escreve o erro como JSON ou, com stream, como último evento
+// (o status HTTP já foi enviado; vale error.http_status)
+func writeSolveError(w http.ResponseWriter, stream *response.EventStream, status int, resp *response.ErrorResponse) {
+	if stream != nil {
+		stream.Send(response.EventError, resp)
+		return
+	}
+	response.WriteError(w, status, resp)
+}
+
+// normalize reduz url ao host, valida os demais campos e devolve todos os erros
+func (req *SbsdRequest) normalize() fieldErrors {
+	var fe fieldErrors
+
+	if host, err := normalizeHost(req.URL); err != nil {
+		fe.add("url", "%v", err)
+	} else {
+		req.URL = host
+	}
+	if req.AkamaiURL != "" {
+		if path, err := validatePath(req.AkamaiURL); err != nil {
+			fe.add("akamaiUrl", "%v", err)
+		} else {
+			req.AkamaiURL = path
+		}
+	}
+	if req.Proxy != "" {
+		if proxy, err := normalizeProxy(req.Proxy); err != nil {
+			fe.add("proxy", "%v", err)
+		} else {
+			req.Proxy = proxy
+		}
+	}
+	if req.AkamaiProvider != "" {
+		req.AkamaiProvider = validateProvider(&fe, "akamaiProvider", req.AkamaiProvider)
+	}
+	for i, p := range req.ProviderChain {
+		req.ProviderChain[i] = validateProvider(&fe, fmt.Sprintf("providerChain[%d]", i), p)
+	}
+	// A lista de perfis vem da TLS-API (conferida depois dos defaults); aqui só
+	// se exige um conjunto de headers para a família do navegador
+	if req.RandomUA != "" && scraper.ProfileFamily(req.RandomUA) == "" {
+		fe.add("randomUserAgent", "perfil %q sem conjunto de headers (chrome_*, firefox_* ou safari_*)", req.RandomUA)
+	}
+	if _, err := req.Retry.Policy(); err != nil {
+		fe.add("retry", "%v", err)
+	}
+	return fe
 }
 
 func (h *SbsdHandler) applyDefaults(req *SbsdRequest) {
+	// Perfil do site tem precedência sobre os defaults globais
+	if profile := scraper.LookupSiteProfile(req.URL); profile != nil {
+		if req.AkamaiProvider == "" {
+			req.AkamaiProvider = profile.Provider
+		}
+		if req.Language == "" {
+			req.Language = profile.Language
+		}
+	}
+
 	if req.RandomUA == "" {
-		req.RandomUA = "chrome_144"
+		req.RandomUA = DefaultProfileType
 	}
 	if req.UserAgent == "" {
 		req.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/144.0.0.0 Safari/537.36"
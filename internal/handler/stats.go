@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// ProviderStatsResponse é o payload de GET /stats/providers
+type ProviderStatsResponse struct {
+	Providers   []scraper.ProviderStat `json:"providers"`
+	GeneratedAt time.Time              `json:"generated_at"`
+}
+
+type StatsHandler struct {
+	stats *scraper.ProviderStats
+}
+
+func NewStatsHandler(stats *scraper.ProviderStats) *StatsHandler {
+	return &StatsHandler{
+		stats: stats,
+	}
+}
+
+// Providers retorna as estatísticas por (domínio, provider, modo).
+// Filtros opcionais: ?domain=, ?provider=, ?mode=
+func (h *StatsHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	domain, provider, mode := q.Get("domain"), q.Get("provider"), q.Get("mode")
+
+	items := make([]scraper.ProviderStat, 0)
+	for _, st := range h.stats.Snapshot() {
+		if domain != "" && st.Domain != domain {
+			continue
+		}
+		if provider != "" && st.Provider != provider {
+			continue
+		}
+		if mode != "" && st.Mode != mode {
+			continue
+		}
+		items = append(items, st)
+	}
+
+	response.WriteJSON(w, http.StatusOK, &ProviderStatsResponse{
+		Providers:   items,
+		GeneratedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"gerador_cookies/internal/response"
+	"gerador_cookies/scraper"
+)
+
+// APIKeyHeader autentica o cliente; o tenant cobrado pelas chamadas aos
+// providers é o da chave, nunca um valor escolhido pelo cliente
+const APIKeyHeader = "X-API-Key"
+
+type tenantContextKey struct{}
+
+type UsageHandler struct {
+	usage *scraper.UsageTracker
+}
+
+func NewUsageHandler(usage *scraper.UsageTracker) *UsageHandler {
+	return &UsageHandler{
+		usage: usage,
+	}
+}
+
+// Handle retorna o relatório de chamadas por dia, tenant, provider e domínio.
+// Filtros opcionais: ?from=YYYY-MM-DD&to=YYYY-MM-DD&tenant=&provider=&domain=
+func (h *UsageHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	response.WriteJSON(w, http.StatusOK, h.usage.Report(scraper.UsageFilter{
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		Tenant:   q.Get("tenant"),
+		Provider: q.Get("provider"),
+		Domain:   q.Get("domain"),
+	}))
+}
+
+// RequireAPIKey autentica o request pela chave em X-API-Key e guarda no
+// contexto o tenant dono da chave (keys: chave -> tenant). Sem chaves
+// configuradas os requests passam e são cobrados do tenant padrão.
+func RequireAPIKey(keys map[string]string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if len(keys) == 0 {
+			return next
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			tenant := lookupAPIKey(keys, r.Header.Get(APIKeyHeader))
+			if tenant == "" {
+				response.WriteError(w, http.StatusUnauthorized, &response.ErrorResponse{
+					Success: false,
+					Error: &response.ErrorDetail{
+						Step:        "api_key_auth",
+						Description: "Chave de API inválida ou ausente",
+						RawError:    "unauthorized",
+					},
+				})
+				return
+			}
+			next(w, r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, tenant)))
+		}
+	}
+}
+
+// lookupAPIKey devolve o tenant da chave, comparando em tempo constante
+func lookupAPIKey(keys map[string]string, got string) string {
+	if got == "" {
+		return ""
+	}
+	tenant := ""
+	for key, t := range keys {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) == 1 {
+			tenant = t
+		}
+	}
+	return tenant
+}
+
+// tenantFromRequest devolve o tenant autenticado por RequireAPIKey; requests
+// sem chave (servidor sem TENANT_API_KEYS) usam o tenant padrão
+func tenantFromRequest(r *http.Request) string {
+	if t, ok := r.Context().Value(tenantContextKey{}).(string); ok && t != "" {
+		return t
+	}
+	return scraper.DefaultTenant
+}
@@ -0,0 +1,281 @@
+// Package openapi builds an OpenAPI 3.1 document from the Go request and
+// response types, so the published contract cannot drift from the structs.
+package openapi
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	names map[reflect.Type]string // Component name of each registered struct
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`   // apiKey
+	Name   string `json:"name,omitempty"` // apiKey
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, path ou header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema 2020-12 used by the generator
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// New creates an empty document
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Add registers op under method and path
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	switch strings.ToUpper(method) {
+	case "GET":
+		item.Get = op
+	case "POST":
+		item.Post = op
+	case "DELETE":
+		item.Delete = op
+	}
+}
+
+// SchemaFor returns a schema for the type of v. Named structs are added to
+// components and referenced with $ref. Fields follow encoding/json: the json
+// tag gives the name, "-" skips the field and fields without omitempty are
+// required (they are always present in the JSON). Pointer fields without
+// omitempty are required but nullable.
+func (d *Document) SchemaFor(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Component returns the schema registered for a named struct, for adding
+// enums or descriptions that the Go type cannot express
+func (d *Document) Component(name string) *Schema {
+	return d.Components.Schemas[name]
+}
+
+// ComponentFor returns the schema registered for the type of v, registering it
+// if needed. It panics if v is not a named struct.
+func (d *Document) ComponentFor(v interface{}) *Schema {
+	ref := d.SchemaFor(v).Ref
+	if ref == "" {
+		panic(fmt.Sprintf("openapi: %T is not a named struct", v))
+	}
+	return d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+}
+
+// Property returns the schema of property name. It panics if the property does
+// not exist, so a renamed json tag breaks BuildOpenAPI instead of the document.
+func (s *Schema) Property(name string) *Schema {
+	p, ok := s.Properties[name]
+	if !ok {
+		panic(fmt.Sprintf("openapi: schema has no property %q", name))
+	}
+	return p
+}
+
+// Item returns the schema of the items of an array. It panics if s is not an array.
+func (s *Schema) Item() *Schema {
+	if s.Items == nil {
+		panic("openapi: schema is not an array")
+	}
+	return s.Items
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == reflect.TypeOf(time.Duration(0)):
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return d.structRef(t)
+	}
+	// interface{} and anything else: any JSON value
+	return &Schema{}
+}
+
+func (d *Document) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return d.structSchema(t)
+	}
+	name, ok := d.names[t]
+	if !ok {
+		name = d.componentName(t)
+		d.names[t] = name
+		// Placeholder first so recursive types terminate
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName is the type name, qualified with the package name when another
+// package already registered a type with the same name
+func (d *Document) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := d.Components.Schemas[name]; !taken {
+		return name
+	}
+	qualified := path.Base(t.PkgPath()) + "." + name
+	for i := 2; ; i++ {
+		if _, taken := d.Components.Schemas[qualified]; !taken {
+			return qualified
+		}
+		qualified = fmt.Sprintf("%s.%s%d", path.Base(t.PkgPath()), name, i)
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := d.schemaOf(f.Type)
+			if embedded.Ref != "" {
+				embedded = d.Components.Schemas[strings.TrimPrefix(embedded.Ref, "#/components/schemas/")]
+			}
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := d.schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+			if f.Type.Kind() == reflect.Ptr {
+				// nil is encoded as null
+				prop = &Schema{AnyOf: []*Schema{prop, {Type: "null"}}}
+			}
+		}
+		s.Properties[name] = prop
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// JSONBody wraps a schema as an application/json request or response body
+func JSONBody(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
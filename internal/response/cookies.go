@@ -0,0 +1,77 @@
+package response
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"gerador_cookies/scraper"
+)
+
+// NewCookies converte os cookies da sessão; nil quando não há nenhum
+func NewCookies(cookies []*http.Cookie) *Cookies {
+	if len(cookies) == 0 {
+		return nil
+	}
+	items := make([]CookieItem, 0, len(cookies))
+	for _, c := range cookies {
+		items = append(items, NewCookieItem(c))
+	}
+	return &Cookies{FullString: CookieHeader(cookies), Items: items}
+}
+
+// CookieHeader monta o valor do header Cookie ("a=1; b=2")
+func CookieHeader(cookies []*http.Cookie) string {
+	parts := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		parts = append(parts, c.Name+"="+c.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// NewSession descreve como o solve rodou: provider, perfil, tentativas e retries
+func NewSession(res *scraper.SolveResult, profile string) *Session {
+	return &Session{
+		Provider: res.Provider,
+		Profile:  profile,
+		Attempts: res.Attempts.SensorPosts + res.Attempts.SBSDPosts,
+		Retries: &Retries{
+			TLSAPI:   res.Attempts.TLSAPIRetries,
+			Provider: res.Attempts.ProviderRetries,
+			SbsdPost: res.Attempts.SBSDPostRetries,
+		},
+	}
+}
+
+// NewCookieItem converte um cookie, incluindo a visão decodificada quando é da Akamai
+func NewCookieItem(c *http.Cookie) CookieItem {
+	item := CookieItem{Name: c.Name, Value: c.Value, Domain: c.Domain}
+	if parsed := scraper.ParseAkamaiCookie(c.Name, c.Value); parsed != nil {
+		item.Parsed = &ParsedCookie{
+			State:  string(parsed.State()),
+			Fields: parsed.Fields(),
+		}
+	}
+	return item
+}
+
+// NewTelemetry monta a telemetria a partir dos cookies da Akamai da sessão e
+// do último sensor (ou body SBSD) enviado
+func NewTelemetry(cookies []*http.Cookie, sensorData string) *Telemetry {
+	ak := scraper.ParseAkamaiCookies(cookies)
+	t := &Telemetry{}
+	if sensorData != "" {
+		t.SensorDataEncoded = base64.StdEncoding.EncodeToString([]byte(sensorData))
+	}
+	if ak.Abck != nil {
+		t.AbckToken = ak.Abck.Hash
+		t.AbckState = string(ak.Abck.State())
+	}
+	if ak.BmSz != nil {
+		t.BmSzEncoded = base64.StdEncoding.EncodeToString([]byte(ak.BmSz.Raw))
+	}
+	if ak.BmS != nil {
+		t.BmSEncoded = base64.StdEncoding.EncodeToString([]byte(ak.BmS.Raw))
+	}
+	return t
+}
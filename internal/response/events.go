@@ -0,0 +1,145 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Nomes dos eventos enviados em text/event-stream
+const (
+	EventProgress = "progress" // scraper.ProgressEvent
+	EventResult   = "result"   // SuccessResponse, último evento em caso de sucesso
+	EventError    = "error"    // ErrorResponse, último evento em caso de falha
+)
+
+var errStreamClosed = errors.New("event stream fechado")
+
+// HeartbeatInterval é o intervalo padrão dos comentários enviados por KeepAlive
+const HeartbeatInterval = 15 * time.Second
+
+// WantsEventStream indica se o cliente pediu Server-Sent Events (Accept: text/event-stream)
+func WantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// EventStream escreve Server-Sent Events, um JSON por evento. Depois do
+// primeiro evento o status já é 200; falhas chegam como evento error. É
+// seguro para uso concorrente (eventos de progresso e heartbeats).
+type EventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+
+	mu       sync.Mutex
+	started  bool
+	closed   bool          // Close foi chamado: o handler pode já ter retornado
+	err      error         // Primeira falha de escrita; depois dela nada mais é escrito
+	failed   chan struct{} // Fechado na primeira falha de escrita
+	stop     chan struct{} // Fechado por Close
+	stopOnce sync.Once
+}
+
+// NewEventStream retorna nil quando w não suporta flush
+func NewEventStream(w http.ResponseWriter) *EventStream {
+	if _, ok := w.(http.Flusher); !ok {
+		return nil
+	}
+	return &EventStream{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		failed: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+// start envia os headers e remove o prazo de escrita do servidor
+// (SERVER_WRITE_TIMEOUT), que cortaria streams mais longos. Chamar com mu.
+func (s *EventStream) start() {
+	if s.started {
+		return
+	}
+	h := s.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	// Sem suporte (writers de teste) o prazo do servidor continua valendo
+	_ = s.rc.SetWriteDeadline(time.Time{})
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+// write escreve e faz flush, registrando a primeira falha. Chamar com mu.
+func (s *EventStream) write(format string, args ...interface{}) error {
+	if s.closed {
+		return errStreamClosed
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.start()
+	_, err := fmt.Fprintf(s.w, format, args...)
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	if err != nil {
+		s.err = err
+		close(s.failed)
+	}
+	return err
+}
+
+// Send escreve o evento e faz flush. Depois de uma falha de escrita (cliente
+// desconectado) devolve sempre o mesmo erro.
+func (s *EventStream) Send(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write("event: %s\ndata: %s\n\n", event, data)
+}
+
+// KeepAlive inicia o stream e envia um comentário a cada interval até Close
+// ou até uma escrita falhar, para que proxies não fechem a conexão ociosa
+func (s *EventStream) KeepAlive(interval time.Duration) {
+	s.mu.Lock()
+	s.start()
+	s.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-s.failed:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				s.write(": ping\n\n")
+				s.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Failed é fechado quando uma escrita falha, normalmente porque o cliente
+// desconectou
+func (s *EventStream) Failed() <-chan struct{} {
+	return s.failed
+}
+
+// Close para os heartbeats e impede novas escritas; chamar antes de o
+// handler retornar
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.stopOnce.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,74 @@
+package response
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEventStreamOutlivesWriteTimeout checks que o stream remove o prazo de
+// escrita do servidor e envia heartbeats enquanto espera
+func TestEventStreamOutlivesWriteTimeout(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := NewEventStream(w)
+		s.KeepAlive(20 * time.Millisecond)
+		defer s.Close()
+		time.Sleep(300 * time.Millisecond)
+		if err := s.Send(EventResult, map[string]bool{"success": true}); err != nil {
+			t.Errorf("Send: %v", err)
+		}
+	}))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream cortado: %v (recebido %q)", err, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(string(body), ": ping\n\n") {
+		t.Errorf("sem heartbeat em %q", body)
+	}
+	if !strings.HasSuffix(string(body), "event: result\ndata: {\"success\":true}\n\n") {
+		t.Errorf("sem o evento result em %q", body)
+	}
+}
+
+// TestEventStreamFailsWhenClientLeaves checks que Failed é fechado e Send
+// devolve erro depois que o cliente desconecta
+func TestEventStreamFailsWhenClientLeaves(t *testing.T) {
+	failed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := NewEventStream(w)
+		s.KeepAlive(10 * time.Millisecond)
+		defer s.Close()
+		select {
+		case <-s.Failed():
+			failed <- s.Send(EventProgress, map[string]string{"type": "step_started"})
+		case <-time.After(5 * time.Second):
+			failed <- nil
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := <-failed; err == nil {
+		t.Fatal("escrita após a desconexão não falhou")
+	}
+}
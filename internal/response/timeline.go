@@ -0,0 +1,25 @@
+package response
+
+import "gerador_cookies/scraper"
+
+// NewTimeline converte a timeline do scraper
+func NewTimeline(entries []scraper.TimelineEntry) []TimelineEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	out := make([]TimelineEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, TimelineEntry{
+			Step:       string(e.Step),
+			Provider:   e.Provider,
+			Attempt:    e.Attempt,
+			Status:     e.Status,
+			RequestID:  e.RequestID,
+			StartedAt:  e.StartedAt.UTC(),
+			DurationMs: e.Duration.Milliseconds(),
+			Detail:     e.Detail,
+			Error:      e.Error,
+		})
+	}
+	return out
+}
@@ -3,13 +3,22 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 )
 
 // CookieItem representa um cookie individual
 type CookieItem struct {
-	Name   string `json:"name"`
-	Value  string `json:"value"`
-	Domain string `json:"domain"`
+	Name   string        `json:"name"`
+	Value  string        `json:"value"`
+	Domain string        `json:"domain"`
+	Parsed *ParsedCookie `json:"parsed,omitempty"` // Campos decodificados dos cookies da Akamai
+}
+
+// ParsedCookie é a visão decodificada de um cookie da Akamai (_abck, bm_sz,
+// bm_s, bm_so/sbsd_o, ak_bmsc)
+type ParsedCookie struct {
+	State  string            `json:"state"` // valid, pending (_abck sem sensor aceito) ou malformed
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 // Cookies contém todos os cookies gerados
@@ -21,6 +30,7 @@ type Cookies struct {
 // Telemetry contém dados de telemetria
 type Telemetry struct {
 	AbckToken         string `json:"abck_token,omitempty"`
+	AbckState         string `json:"abck_state,omitempty"` // valid, pending ou malformed
 	BmSzEncoded       string `json:"bm_sz_encoded,omitempty"`
 	BmSEncoded        string `json:"bm_s_encoded,omitempty"`
 	SensorDataEncoded string `json:"sensor_data_encoded,omitempty"`
@@ -28,16 +38,58 @@ type Telemetry struct {
 
 // Session contém metadados da sessão
 type Session struct {
-	Provider string `json:"provider"`
-	Profile  string `json:"profile"`
-	Attempts int    `json:"attempts,omitempty"`
+	Provider string   `json:"provider"`
+	Profile  string   `json:"profile"`
+	Attempts int      `json:"attempts,omitempty"` // Posts de sensor/challenge feitos
+	Retries  *Retries `json:"retries,omitempty"`
+}
+
+// Retries conta as tentativas extras feitas pela política de retry
+type Retries struct {
+	TLSAPI   int `json:"tls_api"`
+	Provider int `json:"provider"`
+	SbsdPost int `json:"sbsd_post"`
+}
+
+// TimelineEntry é um step cronometrado da geração; requests upstream têm uma
+// entrada por tentativa na TLS-API
+type TimelineEntry struct {
+	Step       string    `json:"step"`                 // homepage, script_url, script_fetch, provider_call, sensor_post, sbsd_post...
+	Provider   string    `json:"provider,omitempty"`   // Só em provider_call
+	Attempt    int       `json:"attempt"`              // Ordem da entrada entre as do mesmo step (e provider)
+	Status     int       `json:"status,omitempty"`     // Status HTTP do upstream
+	RequestID  string    `json:"request_id,omitempty"` // ID do request na TLS-API
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Detail     string    `json:"detail,omitempty"` // URL do script em script_url
+	Error      string    `json:"error,omitempty"`
 }
 
 // ErrorContext contém contexto adicional do erro
 type ErrorContext struct {
-	Attempt     int   `json:"attempt,omitempty"`
-	MaxAttempts int   `json:"max_attempts,omitempty"`
-	ElapsedMs   int64 `json:"elapsed_ms,omitempty"`
+	Attempt          int               `json:"attempt,omitempty"`
+	MaxAttempts      int               `json:"max_attempts,omitempty"`
+	ElapsedMs        int64             `json:"elapsed_ms,omitempty"`
+	ScriptCandidates []ScriptCandidate `json:"script_candidates,omitempty"` // Candidatos rejeitados em script_url_extraction
+	SiteStatus       int               `json:"site_status,omitempty"`       // Status da homepage bloqueada
+	ReferenceID      string            `json:"reference_id,omitempty"`      // Referência da página Access Denied da Akamai
+	SuggestedAction  string            `json:"suggested_action,omitempty"`  // retry, retry_later, switch_proxy, switch_geo ou give_up
+	RetryAfter       string            `json:"retry_after,omitempty"`       // Retry-After enviado pelo site
+}
+
+// ScriptCandidate é um script considerado pela descoberta, com pontuação e motivos
+type ScriptCandidate struct {
+	URL      string   `json:"url"`
+	Source   string   `json:"source"`
+	Score    int      `json:"score"`
+	Eligible bool     `json:"eligible"`
+	Reasons  []string `json:"reasons"`
+}
+
+// FieldError descreve um campo inválido da request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // ErrorDetail contém detalhes do erro
@@ -50,7 +102,9 @@ type ErrorDetail struct {
 	HTTPStatus  int           `json:"http_status,omitempty"`
 	RawError    string        `json:"raw_error"`
 	Retryable   bool          `json:"retryable"`
+	BlockReason string        `json:"block_reason,omitempty"` // Classificação da homepage bloqueada (script_url_extraction)
 	Context     *ErrorContext `json:"context,omitempty"`
+	Fields      []FieldError  `json:"fields,omitempty"`
 }
 
 // Debug contém informações de debug
@@ -60,18 +114,20 @@ type Debug struct {
 
 // SuccessResponse é a resposta de sucesso padrão
 type SuccessResponse struct {
-	Success   bool       `json:"success"`
-	Cookies   *Cookies   `json:"cookies"`
-	Telemetry *Telemetry `json:"telemetry"`
-	Session   *Session   `json:"session"`
+	Success   bool            `json:"success"`
+	Cookies   *Cookies        `json:"cookies"`
+	Telemetry *Telemetry      `json:"telemetry"`
+	Session   *Session        `json:"session"`
+	Timeline  []TimelineEntry `json:"timeline,omitempty"`
 }
 
 // ErrorResponse é a resposta de erro padrão
 type ErrorResponse struct {
-	Success        bool         `json:"success"`
-	Error          *ErrorDetail `json:"error"`
-	PartialCookies *Cookies     `json:"partial_cookies,omitempty"`
-	Debug          *Debug       `json:"debug,omitempty"`
+	Success        bool            `json:"success"`
+	Error          *ErrorDetail    `json:"error"`
+	PartialCookies *Cookies        `json:"partial_cookies,omitempty"`
+	Timeline       []TimelineEntry `json:"timeline,omitempty"`
+	Debug          *Debug          `json:"debug,omitempty"`
 }
 
 // JSON helpers
@@ -90,3 +146,10 @@ func WriteError(w http.ResponseWriter, statusCode int, resp *ErrorResponse) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(resp)
 }
+
+// WriteJSON escreve qualquer payload como JSON com o status informado
+func WriteJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
@@ -3,8 +3,10 @@ package scraper
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -21,6 +23,17 @@ type ABCKSolver struct {
 	userAgent     string
 	browser       string
 	proxy         string
+	provider      string        // Provider currently being tried
+	providerCalls int           // Provider API calls made by the current attempt
+	sensorPosts   int           // Sensor posts made by the current attempt
+	quotaErr      error         // Budget error raised by the last provider call
+	script        ScriptVersion // Script the cached dynamics are derived from
+	retry         RetryPolicy   // Provider call retries and waits between sensor posts
+	stats         *AttemptStats // Attempt counters shared with the scraper
+	lastSensor    string        // Last sensor data posted
+	timeline      *Timeline     // Timeline shared with the scraper
+
+	ctx context.Context // Cancels waits between posts and retries
 }
 
 // NewABCKSolver creates a new ABCK solver
@@ -44,12 +57,16 @@ func NewABCKSolver(
 		userAgent:     userAgent,
 		browser:       browser,
 		proxy:         proxy,
+		retry:         DefaultRetryPolicy(),
+		ctx:           context.Background(),
 	}
 }
 
-// Solve executes the complete ABCK generation flow
+// Solve executes the complete ABCK generation flow. Providers in the chain
+// are tried in order; a retryable provider failure falls back to the next one.
 func (s *ABCKSolver) Solve(script string) (*ABCKResult, error) {
-	log.Printf("→ Starting ABCK solve flow (provider=%s)", s.config.AkamaiProvider)
+	chain := s.config.providerChain(s.config.AkamaiProvider)
+	log.Printf("→ Starting ABCK solve flow (providers=%s)", strings.Join(chain, ","))
 
 	result := &ABCKResult{
 		Session: SessionInfo{
@@ -62,23 +79,60 @@ func (s *ABCKSolver) Solve(script string) (*ABCKResult, error) {
 		},
 	}
 
+	stats := DefaultProviderStats()
+	chain = stats.Order(s.config.Domain, "sensor", chain)
+
+	breaker := DefaultProviderBreaker()
 	var success bool
 	var err error
+	attempted := false
 
-	switch s.config.AkamaiProvider {
-	case "jevi":
-		success, err = s.solveWithJevi(script)
-	case "n4s":
-		success, err = s.solveWithN4S(script)
-	case "roolink":
-		success, err = s.solveWithRoolink(script)
-	default:
-		err = NewError(PhaseInit, "invalid provider", fmt.Errorf("unknown provider: %s", s.config.AkamaiProvider))
+	for i, provider := range chain {
+		if !breaker.Allow(provider) {
+			log.Printf("→ Skipping provider %s (circuit open)", provider)
+			continue
+		}
+		attempted = true
+		s.provider = provider
+		s.providerCalls = 0
+		s.sensorPosts = 0
+		s.quotaErr = nil
+		result.Session.Provider = provider
+
+		started := time.Now()
+		success, err = s.solveWith(provider, script)
+		if s.quotaErr != nil {
+			err = s.quotaErr
+		}
+		stats.Record(s.config.Domain, provider, "sensor", SolveOutcome{
+			Success:  err == nil && success,
+			Attempts: s.sensorPosts,
+			Latency:  time.Since(started),
+			Calls:    s.providerCalls,
+		})
+		if err == nil {
+			breaker.RecordSuccess(provider)
+			break
+		}
+		if !canFallBack(err) {
+			break
+		}
+		if isProviderFailure(err) {
+			breaker.RecordFailure(provider)
+		}
+		if i < len(chain)-1 {
+			log.Printf("→ Provider %s failed, falling back: %v", provider, err)
+		}
+	}
+
+	if !attempted {
+		err = NewError(PhaseProviderCall, "no provider available", fmt.Errorf("all providers skipped (circuit open): %s", strings.Join(chain, ",")))
 	}
 
 	if err != nil {
 		result.Success = false
-		if solverErr, ok := err.(*SolverError); ok {
+		var solverErr *SolverError
+		if errors.As(err, &solverErr) {
 			result.Error = solverErr
 		} else {
 			result.Error = NewError(PhaseProviderCall, "solve failed", err)
@@ -96,10 +150,24 @@ func (s *ABCKSolver) Solve(script string) (*ABCKResult, error) {
 	result.Cookies = s.cookieJar.GetCookies(s.config.Domain)
 	result.CookieString = s.cookieJar.GetCookieString(s.config.Domain)
 
-	log.Printf("✓ ABCK solve succeeded (provider=%s)", s.config.AkamaiProvider)
+	log.Printf("✓ ABCK solve succeeded (provider=%s)", s.provider)
 	return result, nil
 }
 
+// solveWith dispatches to the flow of a single provider
+func (s *ABCKSolver) solveWith(provider, script string) (bool, error) {
+	switch provider {
+	case "jevi":
+		return s.solveWithJevi(script)
+	case "n4s":
+		return s.solveWithN4S(script)
+	case "roolink":
+		return s.solveWithRoolink(script)
+	default:
+		return false, NewError(PhaseInit, "invalid provider", fmt.Errorf("unknown provider: %s", provider))
+	}
+}
+
 // solveWithJevi implements the Jevi provider flow
 func (s *ABCKSolver) solveWithJevi(script string) (bool, error) {
 	// Check cache for encoded data
@@ -112,6 +180,9 @@ func (s *ABCKSolver) solveWithJevi(script string) (bool, error) {
 	}
 
 	for i := 0; i < s.config.SensorPostLimit; i++ {
+		if err := s.waitBeforePost(i); err != nil {
+			return false, err
+		}
 		sensorData, newEncodedData, err := s.callJeviAPI(script, encodedData, i)
 		if err != nil {
 			return false, NewErrorWithProvider(PhaseProviderCall, "jevi sensor generation", "jevi", err)
@@ -120,7 +191,7 @@ func (s *ABCKSolver) solveWithJevi(script string) (bool, error) {
 		// Update cached encoded data
 		if newEncodedData != "" && newEncodedData != encodedData {
 			encodedData = newEncodedData
-			s.providerCache.Upsert(s.config.Domain, "jevi", "sensor", nil, &encodedData)
+			s.providerCache.UpsertDynamic(s.config.Domain, "jevi", "sensor", encodedData, s.script)
 		}
 
 		success, err := s.postSensor(sensorData, i)
@@ -152,10 +223,13 @@ func (s *ABCKSolver) solveWithN4S(script string) (bool, error) {
 		if err != nil {
 			return false, NewErrorWithProvider(PhaseProviderCall, "n4s dynamic generation", "n4s", err)
 		}
-		s.providerCache.Upsert(s.config.Domain, "n4s", "sensor", nil, &dynamicData)
+		s.providerCache.UpsertDynamic(s.config.Domain, "n4s", "sensor", dynamicData, s.script)
 	}
 
 	for i := 0; i < s.config.SensorPostLimit; i++ {
+		if err := s.waitBeforePost(i); err != nil {
+			return false, err
+		}
 		sensorData, err := s.callN4SAPI(dynamicData, i)
 		if err != nil {
 			return false, NewErrorWithProvider(PhaseProviderCall, "n4s sensor generation", "n4s", err)
@@ -195,13 +269,15 @@ func (s *ABCKSolver) solveWithRoolink(script string) (bool, error) {
 			scriptData = nil
 		} else if scriptData != nil {
 			if b, err := json.Marshal(scriptData); err == nil {
-				dynamicStr := string(b)
-				s.providerCache.Upsert(s.config.Domain, "roolink", "sensor", nil, &dynamicStr)
+				s.providerCache.UpsertDynamic(s.config.Domain, "roolink", "sensor", string(b), s.script)
 			}
 		}
 	}
 
 	for i := 0; i < s.config.SensorPostLimit; i++ {
+		if err := s.waitBeforePost(i); err != nil {
+			return false, err
+		}
 		sensorData, err := s.callRoolinkAPI(scriptData, i)
 		if err != nil {
 			// Retry without scriptData
@@ -223,8 +299,34 @@ func (s *ABCKSolver) solveWithRoolink(script string) (bool, error) {
 	return false, nil
 }
 
+// waitBeforePost backs off before every sensor post after the first, using
+// the retry policy's schedule when sensor posts are a retried phase. It fails
+// when the solve is canceled during the wait.
+func (s *ABCKSolver) waitBeforePost(i int) error {
+	if i == 0 {
+		return nil
+	}
+	p := s.retry.forPhase(PhaseSensorPost)
+	if p.MaxAttempts == 1 {
+		return nil
+	}
+	wait := p.Backoff(i)
+	if wait > 0 {
+		log.Printf("→ Waiting %s before sensor post %d", wait.Round(time.Millisecond), i+1)
+	}
+	if err := sleepCtx(s.ctx, wait); err != nil {
+		return NewError(PhaseSensorPost, "wait before sensor post", err)
+	}
+	return nil
+}
+
 // postSensor sends sensor data to Akamai and validates response
 func (s *ABCKSolver) postSensor(sensorData string, index int) (bool, error) {
+	s.lastSensor = sensorData
+	s.sensorPosts++
+	if s.stats != nil {
+		s.stats.SensorPosts++
+	}
 	log.Printf("→ Posting sensor to Akamai [%d/%d]", index+1, s.config.SensorPostLimit)
 
 	// Build sensor URL (remove v= parameter for ABCK)
@@ -255,10 +357,11 @@ func (s *ABCKSolver) postSensor(sensorData string, index int) (bool, error) {
 		Cookies:       s.cookieJar.ToTLSAPICookies(s.config.Domain),
 		Proxy:         s.proxy,
 		ReturnCookies: true,
+		step:          TimelineSensorPost,
 	})
 
 	if err != nil {
-		return false, NewErrorWithProvider(PhaseSensorPost, "send sensor", s.config.AkamaiProvider, err)
+		return false, NewErrorWithProvider(PhaseSensorPost, "send sensor", s.provider, err)
 	}
 
 	// Store cookies from response
@@ -267,11 +370,18 @@ func (s *ABCKSolver) postSensor(sensorData string, index int) (bool, error) {
 	}
 
 	// Validate response
-	return s.validateSensorResponse(resp), nil
+	accepted := s.validateSensorResponse(resp)
+	s.timeline.PostResult(TimelineSensorPost, resp.GetStatus(), accepted)
+	return accepted, nil
 }
 
 // postSensorRoolink sends sensor data to Akamai for Roolink (slightly different format)
 func (s *ABCKSolver) postSensorRoolink(sensorData string, index int) (bool, error) {
+	s.lastSensor = sensorData
+	s.sensorPosts++
+	if s.stats != nil {
+		s.stats.SensorPosts++
+	}
 	log.Printf("→ Posting sensor to Akamai [%d/%d]", index+1, s.config.SensorPostLimit)
 
 	sensorURL := fmt.Sprintf("https://%s%s", s.config.Domain, s.config.SensorUrl)
@@ -292,6 +402,7 @@ func (s *ABCKSolver) postSensorRoolink(sensorData string, index int) (bool, erro
 		Cookies:       s.cookieJar.ToTLSAPICookies(s.config.Domain),
 		Proxy:         s.proxy,
 		ReturnCookies: true,
+		step:          TimelineSensorPost,
 	})
 
 	if err != nil {
@@ -303,7 +414,9 @@ func (s *ABCKSolver) postSensorRoolink(sensorData string, index int) (bool, erro
 		s.cookieJar.FromTLSAPICookies(s.config.Domain, resp.GetCookies())
 	}
 
-	return s.validateSensorResponse(resp), nil
+	accepted := s.validateSensorResponse(resp)
+	s.timeline.PostResult(TimelineSensorPost, resp.GetStatus(), accepted)
+	return accepted, nil
 }
 
 // validateSensorResponse checks if the sensor was accepted
@@ -315,15 +428,9 @@ func (s *ABCKSolver) validateSensorResponse(resp *TLSResponse) bool {
 
 	// Check _abck cookie
 	for _, cookie := range resp.GetCookies() {
-		if cookie.Name == "_abck" {
-			if strings.Contains(cookie.Value, "~0~") {
-				isValid = true
-				break
-			}
-			if s.config.LowSecurity && len(cookie.Value) == 541 {
-				isValid = true
-				break
-			}
+		if cookie.Name == "_abck" && ParseAbck(cookie.Value).Accepted(s.config.LowSecurity) {
+			isValid = true
+			break
 		}
 	}
 
@@ -390,7 +497,7 @@ func (s *ABCKSolver) callJeviAPI(script, encodedData string, index int) (string,
 	}
 	userAgentPrefix := strings.Split(apiKey, "-")[0]
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://new.jevi.dev/Solver/solve",
 		Method:  "POST",
 		Browser: s.browser,
@@ -457,7 +564,7 @@ func (s *ABCKSolver) generateN4SDynamic(script string) (string, error) {
 	req := map[string]string{"script": script}
 	jsonData, _ := json.Marshal(req)
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://n4s.xyz/v3_values",
 		Method:  "POST",
 		Browser: s.browser,
@@ -505,7 +612,7 @@ func (s *ABCKSolver) callN4SAPI(dynamicData string, index int) (string, error) {
 
 	jsonData, _ := json.Marshal(req)
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://n4s.xyz/sensor",
 		Method:  "POST",
 		Browser: s.browser,
@@ -569,7 +676,7 @@ func (s *ABCKSolver) parseRoolinkScript(script string) (*abckRoolinkScriptData,
 		}
 	}
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://www.roolink.io/api/v1/parse",
 		Method:  "POST",
 		Browser: s.browser,
@@ -628,7 +735,7 @@ func (s *ABCKSolver) callRoolinkAPI(scriptData *abckRoolinkScriptData, index int
 
 	jsonData, _ := json.Marshal(req)
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://www.roolink.io/api/v1/sensor",
 		Method:  "POST",
 		Browser: s.browser,
@@ -670,6 +777,25 @@ func (s *ABCKSolver) callRoolinkAPI(scriptData *abckRoolinkScriptData, index int
 // Helper Functions
 // ============================================================================
 
+// providerRequest sends a request to a provider API, counting it as one provider call.
+// Usage budgets are checked before the request is dispatched.
+func (s *ABCKSolver) providerRequest(req TLSRequest) (*TLSResponse, error) {
+	return retryProviderCall(s.ctx, s.retry, s.provider, func(attempt int) (*TLSResponse, error) {
+		if err := DefaultUsageTracker().Reserve(s.config.Tenant, s.provider, s.config.Domain); err != nil {
+			s.quotaErr = err
+			return nil, err
+		}
+		s.stats.countProviderCall(attempt)
+		s.providerCalls++
+		req.step, req.provider = TimelineProviderCall, s.provider
+		s.timeline.emit(&ProviderRequestEvent{Provider: s.provider, Mode: SolveABCK, Attempt: s.providerCalls, Request: req, Time: time.Now()})
+		started := time.Now()
+		resp, err := s.tlsClient.Request(req)
+		s.timeline.emit(&ProviderResponseEvent{Provider: s.provider, Mode: SolveABCK, Attempt: s.providerCalls, Response: resp, Duration: time.Since(started), Err: err, Time: time.Now()})
+		return resp, err
+	})
+}
+
 func (s *ABCKSolver) getAkamaiCookies() (abck, bmsz string) {
 	cookies := s.cookieJar.GetCookies(s.config.Domain)
 	for _, c := range cookies {
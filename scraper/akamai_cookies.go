@@ -0,0 +1,253 @@
+package scraper
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CookieState is the validity of a parsed Akamai cookie
+type CookieState string
+
+const (
+	CookieValid     CookieState = "valid"     // Well-formed; for _abck, the sensor was accepted
+	CookiePending   CookieState = "pending"   // _abck only: well-formed, sensor not accepted yet
+	CookieMalformed CookieState = "malformed" // Does not follow the known format
+)
+
+// LowSecurityAbckLength is the length of the _abck that low-security sites
+// issue once a sensor is accepted, without flipping its status to 0
+const LowSecurityAbckLength = 541
+
+// AkamaiCookie is a parsed Akamai cookie
+type AkamaiCookie interface {
+	CookieName() string
+	State() CookieState
+	Fields() map[string]string // Known fields, for display
+}
+
+// payloadRe matches the base64 payloads Akamai embeds in its cookies (YAAQ...)
+var payloadRe = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// AbckCookie is the _abck cookie: hash~status~payload~extra...
+type AbckCookie struct {
+	Raw     string
+	Hash    string   // Session identifier
+	Status  int      // 0 once a sensor is accepted, -1 before
+	Payload string   // Encrypted sensor state
+	Extra   []string // Trailing fields (-1~-1~-1 on most sites)
+}
+
+func (c *AbckCookie) CookieName() string { return "_abck" }
+
+func (c *AbckCookie) State() CookieState {
+	switch {
+	case c.Hash == "" || c.Payload == "":
+		return CookieMalformed
+	case c.Status == 0:
+		return CookieValid
+	default:
+		return CookiePending
+	}
+}
+
+// Accepted reports whether the sensor was accepted. Low-security sites keep
+// status -1 and signal acceptance with a _abck of LowSecurityAbckLength.
+func (c *AbckCookie) Accepted(lowSecurity bool) bool {
+	return c.State() == CookieValid || (lowSecurity && len(c.Raw) == LowSecurityAbckLength)
+}
+
+func (c *AbckCookie) Fields() map[string]string {
+	return fieldMap("hash", c.Hash, "status", strconv.Itoa(c.Status), "extra", strings.Join(c.Extra, "~"))
+}
+
+// ParseAbck parses an _abck value. A value that does not follow the format
+// is returned with State() == CookieMalformed.
+func ParseAbck(value string) *AbckCookie {
+	c := &AbckCookie{Raw: value, Status: -1}
+	parts := strings.Split(value, "~")
+	if len(parts) < 3 {
+		return c
+	}
+	status, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return c
+	}
+	c.Hash, c.Status, c.Payload, c.Extra = parts[0], status, parts[2], parts[3:]
+	return c
+}
+
+// BmSzCookie is the bm_sz cookie: hash~payload~numbers...
+type BmSzCookie struct {
+	Raw     string
+	Hash    string
+	Payload string
+	Extra   []string // Trailing numeric fields
+}
+
+func (c *BmSzCookie) CookieName() string { return "bm_sz" }
+
+func (c *BmSzCookie) State() CookieState {
+	if c.Hash == "" || !payloadRe.MatchString(c.Payload) {
+		return CookieMalformed
+	}
+	return CookieValid
+}
+
+func (c *BmSzCookie) Fields() map[string]string {
+	return fieldMap("hash", c.Hash, "extra", strings.Join(c.Extra, "~"))
+}
+
+// ParseBmSz parses a bm_sz value
+func ParseBmSz(value string) *BmSzCookie {
+	c := &BmSzCookie{Raw: value}
+	if parts := strings.Split(value, "~"); len(parts) >= 2 {
+		c.Hash, c.Payload, c.Extra = parts[0], parts[1], parts[2:]
+	}
+	return c
+}
+
+// BmSCookie is the bm_s cookie set by the SBSD flow: a single payload
+type BmSCookie struct {
+	Raw string
+}
+
+func (c *BmSCookie) CookieName() string { return "bm_s" }
+
+func (c *BmSCookie) State() CookieState {
+	if !payloadRe.MatchString(c.Raw) {
+		return CookieMalformed
+	}
+	return CookieValid
+}
+
+func (c *BmSCookie) Fields() map[string]string {
+	return fieldMap("length", strconv.Itoa(len(c.Raw)))
+}
+
+// ParseBmS parses a bm_s value
+func ParseBmS(value string) *BmSCookie {
+	return &BmSCookie{Raw: value}
+}
+
+// SbsdOCookie is the bm_so or sbsd_o cookie the SBSD providers take as
+// input: value^suffix, where only value is sent to some providers
+type SbsdOCookie struct {
+	Name   string // bm_so or sbsd_o
+	Raw    string
+	Value  string // Part before ^
+	Suffix string // Part after ^, when present
+}
+
+func (c *SbsdOCookie) CookieName() string { return c.Name }
+
+func (c *SbsdOCookie) State() CookieState {
+	if c.Value == "" {
+		return CookieMalformed
+	}
+	return CookieValid
+}
+
+func (c *SbsdOCookie) Fields() map[string]string {
+	return fieldMap("suffix", c.Suffix)
+}
+
+// ParseSbsdO parses a bm_so or sbsd_o value
+func ParseSbsdO(name, value string) *SbsdOCookie {
+	c := &SbsdOCookie{Name: name, Raw: value}
+	c.Value, c.Suffix, _ = strings.Cut(value, "^")
+	return c
+}
+
+// AkBmscCookie is the ak_bmsc cookie: hash~reserved~payload~extra...
+type AkBmscCookie struct {
+	Raw      string
+	Hash     string
+	Reserved string // Zero-filled on most sites
+	Payload  string
+	Extra    []string
+}
+
+func (c *AkBmscCookie) CookieName() string { return "ak_bmsc" }
+
+func (c *AkBmscCookie) State() CookieState {
+	if c.Hash == "" || c.Payload == "" {
+		return CookieMalformed
+	}
+	return CookieValid
+}
+
+func (c *AkBmscCookie) Fields() map[string]string {
+	return fieldMap("hash", c.Hash, "reserved", c.Reserved, "extra", strings.Join(c.Extra, "~"))
+}
+
+// ParseAkBmsc parses an ak_bmsc value
+func ParseAkBmsc(value string) *AkBmscCookie {
+	c := &AkBmscCookie{Raw: value}
+	if parts := strings.Split(value, "~"); len(parts) >= 3 {
+		c.Hash, c.Reserved, c.Payload, c.Extra = parts[0], parts[1], parts[2], parts[3:]
+	}
+	return c
+}
+
+// fieldMap builds a Fields map from key/value pairs, skipping empty values
+func fieldMap(kv ...string) map[string]string {
+	m := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			m[kv[i]] = kv[i+1]
+		}
+	}
+	return m
+}
+
+// ParseAkamaiCookie parses a cookie by name. It returns nil for cookies that
+// are not Akamai's. _abck and bm_sz match by prefix, as some sites suffix them.
+func ParseAkamaiCookie(name, value string) AkamaiCookie {
+	switch {
+	case strings.HasPrefix(name, "_abck"):
+		return ParseAbck(value)
+	case strings.HasPrefix(name, "bm_sz"):
+		return ParseBmSz(value)
+	case name == "bm_s":
+		return ParseBmS(value)
+	case name == "bm_so" || name == "sbsd_o":
+		return ParseSbsdO(name, value)
+	case name == "ak_bmsc":
+		return ParseAkBmsc(value)
+	}
+	return nil
+}
+
+// AkamaiCookies holds the parsed Akamai cookies of a session; absent ones are nil
+type AkamaiCookies struct {
+	Abck   *AbckCookie
+	BmSz   *BmSzCookie
+	BmS    *BmSCookie
+	SbsdO  *SbsdOCookie // bm_so, or sbsd_o when bm_so is absent
+	AkBmsc *AkBmscCookie
+}
+
+// ParseAkamaiCookies parses the Akamai cookies among cookies
+func ParseAkamaiCookies(cookies []*http.Cookie) *AkamaiCookies {
+	out := &AkamaiCookies{}
+	for _, ck := range cookies {
+		switch c := ParseAkamaiCookie(ck.Name, ck.Value).(type) {
+		case *AbckCookie:
+			out.Abck = c
+		case *BmSzCookie:
+			out.BmSz = c
+		case *BmSCookie:
+			out.BmS = c
+		case *SbsdOCookie:
+			// The first bm_so wins, else the first sbsd_o
+			if out.SbsdO == nil || (c.Name == "bm_so" && out.SbsdO.Name != "bm_so") {
+				out.SbsdO = c
+			}
+		case *AkBmscCookie:
+			out.AkBmsc = c
+		}
+	}
+	return out
+}
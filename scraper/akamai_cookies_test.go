@@ -0,0 +1,139 @@
+package scraper
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Cookie samples in the format Akamai sets them (payloads shortened)
+const (
+	abckAccepted      = "8C5A3B6E0F1D2C4B7A9E8D6F5C4B3A21~0~YAAQlQVaaGrJ2ZuMAQAAtN5hBQtY0nOcx7T0zm6Lf3y6wU1kH/rr6Rn6QqE5x0w+gIuBQ3cVHwv+bTJPZKhKIJOIQ6ZpdkQ+yo0xLWn3sW0Fz3Pk0sdhW5pO1OP9jzmyv8v1IlI3rLlr1/Nt/dYYLD0o3bhXmX9pYcU2ZQR0vM4JbTSGnMTfaE0l0SpGsUjs2s1HFsNfY8Yl0fEGBQOKD1q6Q==~-1~-1~-1"
+	abckAcceptedPipes = "F0E1D2C3B4A5968778695A4B3C2D1E0F~0~YAAQbQVaaNa02ZuMAQAAq7JiBQvLh3sCl3m6i1tU7v0Qy8dVn1lE3mQy0p0Z2xV1bq6o3c/4hRrK9sW0y1Y8hq1M2k5ZJQ1u8m2V3H2qk2E=~-1~||0||~-1"
+	abckAcceptedLong  = "2D4F6A8C0E1B3D5F7A9C1E3B5D7F9A0C~0~YAAQ1QVaaE7K2ZuMAQAAm8VjBQu5p2lY0vN6H5o9Q7Qe1x8W/8zS7H0g==~-1~-1~1700000000~AAQAAAAE%2f%2f%2f%2f%2f%2bS8Vd0P0z1yq9wq%2fRgJY~-1"
+	abckPending       = "8C5A3B6E0F1D2C4B7A9E8D6F5C4B3A21~-1~YAAQlQVaaGrJ2ZuMAQAAtN5hBQtY0nOcx7T0zm6Lf3y6wU1kH/rr6Rn6QqE5x0w+gIuBQ3cVHwv+bTJPZKhKIJOIQ6Zp==~-1~-1~-1"
+	bmSzSample        = "6A1F2E3D4C5B6A7980F1E2D3C4B5A697~YAAQlQVaaJ7J2ZuMAQAAtN5hBRv3Q7V0cS5h1k0m4lK+v3n6r1Z9b0Hq0K2/F7mZtY5O8d3w==~4539188~3359287"
+	sbsdOSample       = "0A1B2C3D4E5F60718293A4B5C6D7E8F9~YAAQlQVaaKfJ2ZuMAQAA1m1hBRqO6T3y0k0p1M2n7c8Z+g==^1700000000000"
+	akBmscSample      = "3E5A7C9B1D2F4A6C8E0B2D4F6A8C0E1B~000000000000000000000000000000~YAAQlQVaaH3J2ZuMAQAAkV1hBRnH2b1t8sW0+1Qz4y==~extra"
+)
+
+// legacyAbckAccepted is the check used before ParseAbck
+func legacyAbckAccepted(value string, lowSecurity bool) bool {
+	return strings.Contains(value, "~0~") || (lowSecurity && len(value) == LowSecurityAbckLength)
+}
+
+// lowSecurityAbck builds a pending _abck of LowSecurityAbckLength
+func lowSecurityAbck() string {
+	head, tail := "8C5A3B6E0F1D2C4B7A9E8D6F5C4B3A21~-1~YAAQ", "~-1~-1~-1"
+	return head + strings.Repeat("A", LowSecurityAbckLength-len(head)-len(tail)) + tail
+}
+
+func TestParseAbck(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		value       string
+		lowSecurity bool
+		state       CookieState
+		accepted    bool
+	}{
+		{"accepted", abckAccepted, false, CookieValid, true},
+		{"accepted with || fields", abckAcceptedPipes, false, CookieValid, true},
+		{"accepted with long trailer", abckAcceptedLong, false, CookieValid, true},
+		{"pending", abckPending, false, CookiePending, false},
+		{"pending on low-security site", abckPending, true, CookiePending, false},
+		{"low-security length", lowSecurityAbck(), true, CookiePending, true},
+		{"low-security length on normal site", lowSecurityAbck(), false, CookiePending, false},
+		{"too few fields", "8C5A3B6E0F1D2C4B~0", false, CookieMalformed, false},
+		{"non-numeric status", "8C5A3B6E0F1D2C4B~x~YAAQ==~-1", false, CookieMalformed, false},
+		{"empty", "", false, CookieMalformed, false},
+	} {
+		ck := ParseAbck(c.value)
+		if got := ck.State(); got != c.state {
+			t.Errorf("%s: State = %s; want %s", c.name, got, c.state)
+		}
+		if got := ck.Accepted(c.lowSecurity); got != c.accepted {
+			t.Errorf("%s: Accepted = %v; want %v", c.name, got, c.accepted)
+		}
+		// Every cookie the old check accepted is still accepted
+		if legacyAbckAccepted(c.value, c.lowSecurity) && !ck.Accepted(c.lowSecurity) {
+			t.Errorf("%s: accepted by the old ~0~ check, rejected now", c.name)
+		}
+	}
+
+	ck := ParseAbck(abckAccepted)
+	if ck.Hash != "8C5A3B6E0F1D2C4B7A9E8D6F5C4B3A21" || ck.Status != 0 || !strings.HasPrefix(ck.Payload, "YAAQ") ||
+		strings.Join(ck.Extra, "~") != "-1~-1~-1" {
+		t.Errorf("ParseAbck = %+v", ck)
+	}
+	if f := ck.Fields(); f["hash"] != ck.Hash || f["status"] != "0" || f["extra"] != "-1~-1~-1" {
+		t.Errorf("Fields = %v", f)
+	}
+}
+
+func TestParseOtherAkamaiCookies(t *testing.T) {
+	bmsz := ParseBmSz(bmSzSample)
+	if bmsz.State() != CookieValid || bmsz.Hash != "6A1F2E3D4C5B6A7980F1E2D3C4B5A697" || strings.Join(bmsz.Extra, "~") != "4539188~3359287" {
+		t.Errorf("ParseBmSz = %+v (%s)", bmsz, bmsz.State())
+	}
+	if s := ParseBmSz("6A1F2E3D~not base64!~1").State(); s != CookieMalformed {
+		t.Errorf("bm_sz with a bad payload: %s; want malformed", s)
+	}
+
+	for _, name := range []string{"sbsd_o", "bm_so"} {
+		o := ParseSbsdO(name, sbsdOSample)
+		if o.State() != CookieValid || o.CookieName() != name || o.Suffix != "1700000000000" ||
+			o.Value+"^"+o.Suffix != sbsdOSample || o.Raw != sbsdOSample {
+			t.Errorf("ParseSbsdO(%s) = %+v", name, o)
+		}
+	}
+	if o := ParseSbsdO("sbsd_o", "0A1B2C3D~YAAQ=="); o.State() != CookieValid || o.Suffix != "" {
+		t.Errorf("sbsd_o without suffix = %+v", o)
+	}
+	if s := ParseSbsdO("sbsd_o", "^1700000000000").State(); s != CookieMalformed {
+		t.Errorf("sbsd_o without value: %s; want malformed", s)
+	}
+
+	if s := ParseBmS("YAAQlQVaaH3J2ZuMAQAAkV1hBRnH2b1t8sW0+1Qz4y==").State(); s != CookieValid {
+		t.Errorf("bm_s: %s; want valid", s)
+	}
+	if a := ParseAkBmsc(akBmscSample); a.State() != CookieValid || a.Reserved != "000000000000000000000000000000" {
+		t.Errorf("ParseAkBmsc = %+v", a)
+	}
+
+	if ParseAkamaiCookie("session_id", "x") != nil {
+		t.Error("ParseAkamaiCookie parsed a non-Akamai cookie")
+	}
+	if _, ok := ParseAkamaiCookie("_abck_eu", abckAccepted).(*AbckCookie); !ok {
+		t.Error("suffixed _abck not parsed as _abck")
+	}
+}
+
+// TestParseAkamaiCookiesSbsdOPrecedence keeps the old selection: the first
+// bm_so, else the first sbsd_o
+func TestParseAkamaiCookiesSbsdOPrecedence(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		cookies []*http.Cookie
+		want    string
+	}{
+		{"sbsd_o only", []*http.Cookie{{Name: "sbsd_o", Value: "first"}, {Name: "sbsd_o", Value: "second"}}, "first"},
+		{"bm_so after sbsd_o", []*http.Cookie{{Name: "sbsd_o", Value: "o"}, {Name: "bm_so", Value: "so"}}, "so"},
+		{"bm_so before sbsd_o", []*http.Cookie{{Name: "bm_so", Value: "so"}, {Name: "sbsd_o", Value: "o"}}, "so"},
+		{"two bm_so", []*http.Cookie{{Name: "bm_so", Value: "first"}, {Name: "bm_so", Value: "second"}}, "first"},
+	} {
+		got := ParseAkamaiCookies(c.cookies).SbsdO
+		if got == nil || got.Raw != c.want {
+			t.Errorf("%s: SbsdO = %+v; want %q", c.name, got, c.want)
+		}
+	}
+
+	all := ParseAkamaiCookies([]*http.Cookie{
+		{Name: "_abck", Value: abckAccepted},
+		{Name: "bm_sz", Value: bmSzSample},
+		{Name: "ak_bmsc", Value: akBmscSample},
+		{Name: "other", Value: "x"},
+	})
+	if all.Abck == nil || all.BmSz == nil || all.AkBmsc == nil || all.BmS != nil || all.SbsdO != nil {
+		t.Errorf("ParseAkamaiCookies = %+v", all)
+	}
+}
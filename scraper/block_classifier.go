@@ -0,0 +1,133 @@
+package scraper
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// BlockReason classifies a non-2xx homepage response
+type BlockReason string
+
+const (
+	BlockAccessDenied     BlockReason = "akamai_access_denied" // Akamai edge denied the request (IP or fingerprint flagged)
+	BlockSBSDInterstitial BlockReason = "sbsd_interstitial"    // SBSD/bot-manager challenge page instead of the site
+	BlockRateLimited      BlockReason = "rate_limited"         // Too many requests from this client
+	BlockGeo              BlockReason = "geo_blocked"          // Site not served to the proxy's country
+	BlockMaintenance      BlockReason = "maintenance"          // Site down for maintenance
+	BlockProxyAuth        BlockReason = "proxy_auth_failed"    // Proxy rejected the credentials (407)
+	BlockServerError      BlockReason = "server_error"         // Origin or edge failure without a known page
+	BlockUnknown          BlockReason = "unknown"
+)
+
+// BlockAction is what the caller should do about a block
+type BlockAction string
+
+const (
+	ActionRetry       BlockAction = "retry"        // Try again as is
+	ActionRetryLater  BlockAction = "retry_later"  // Try again after a wait (see RetryAfter)
+	ActionSwitchProxy BlockAction = "switch_proxy" // Try again from another IP
+	ActionSwitchGeo   BlockAction = "switch_geo"   // Try again from a proxy in another country
+	ActionGiveUp      BlockAction = "give_up"      // Retrying will not help until the setup changes
+)
+
+// BlockClassification is the result of ClassifyBlock
+type BlockClassification struct {
+	Reason      BlockReason
+	Action      BlockAction
+	Status      int    // Homepage HTTP status
+	ReferenceID string // Akamai reference ("18.5d7c1402.1700000000.1a2b3c4d"), when present
+	RetryAfter  string // Retry-After header, when present
+	Evidence    string // What matched, for logs
+}
+
+// HomepageBlockedError is returned by GetAntiBotScriptURL when the homepage
+// answers non-2xx
+type HomepageBlockedError struct {
+	Domain         string
+	Classification *BlockClassification
+	BodyPreview    string // First 2 KiB of the body
+}
+
+func (e *HomepageBlockedError) Error() string {
+	c := e.Classification
+	msg := fmt.Sprintf("homepage blocked: status=%d reason=%s", c.Status, c.Reason)
+	if c.ReferenceID != "" {
+		msg += " reference=" + c.ReferenceID
+	}
+	return msg + " body_preview=" + e.BodyPreview
+}
+
+var (
+	// Reference #18.5d7c1402.1700000000.1a2b3c4d (matched after HTML unescaping,
+	// Akamai writes it as Reference&#32;&#35;18&#46;5d7c1402...)
+	akamaiReferenceRe = regexp.MustCompile(`Reference\s*#\s*([0-9a-fA-F]+(?:\.[0-9a-fA-F]+)+)`)
+	geoBlockRe        = regexp.MustCompile(`(?i)(not available in your (country|region|location)|unavailable in your (country|region)|access from your (country|region)|geo.?block|não está disponível (no seu país|na sua região))`)
+	rateLimitRe       = regexp.MustCompile(`(?i)(too many requests|rate.?limit)`)
+	sbsdMarkers       = []string{"bm-verify", "sec-if-cpt-container", "sec-cpt-if", "/_sec/cp_challenge", "behavioral-content"}
+
+	// Maintenance phrases only: the bare word shows up in footers and links of any error page
+	maintenanceRe = regexp.MustCompile(`(?i)(under (scheduled )?maintenance|(down|closed|offline|unavailable) for (scheduled )?maintenance|(scheduled|planned) maintenance|maintenance (mode|in progress)|(em|sob) manuten[cç][aã]o|manuten[cç][aã]o (programada|em andamento)|be right back|voltamos (em breve|logo))`)
+)
+
+// ClassifyBlock inspects a non-2xx homepage response. Status codes decide
+// first (407, 429, 451), then the body and headers.
+func ClassifyBlock(resp *SiteResponse) *BlockClassification {
+	body := html.UnescapeString(string(resp.Body))
+	c := &BlockClassification{
+		Status:     resp.Status,
+		RetryAfter: headerValue(resp.Headers, "Retry-After"),
+	}
+	set := func(reason BlockReason, action BlockAction, evidence string) *BlockClassification {
+		c.Reason, c.Action, c.Evidence = reason, action, evidence
+		return c
+	}
+	if m := akamaiReferenceRe.FindStringSubmatch(body); m != nil {
+		c.ReferenceID = m[1]
+	}
+
+	switch resp.Status {
+	case 407:
+		return set(BlockProxyAuth, ActionGiveUp, "status 407")
+	case 429:
+		return set(BlockRateLimited, ActionRetryLater, "status 429")
+	case 451:
+		return set(BlockGeo, ActionSwitchGeo, "status 451")
+	}
+
+	if m := geoBlockRe.FindString(body); m != "" {
+		return set(BlockGeo, ActionSwitchGeo, "body contains "+m)
+	}
+	// Edge deny pages may embed a bm-verify snippet: the IP is what is flagged
+	if strings.Contains(body, "Access Denied") || c.ReferenceID != "" || strings.Contains(body, "errors.edgesuite.net") {
+		evidence := "access denied page"
+		if c.ReferenceID != "" {
+			evidence += " with reference"
+		}
+		return set(BlockAccessDenied, ActionSwitchProxy, evidence)
+	}
+	for _, marker := range sbsdMarkers {
+		if strings.Contains(body, marker) {
+			return set(BlockSBSDInterstitial, ActionRetry, "body contains "+marker)
+		}
+	}
+	for _, ck := range resp.Cookies {
+		if ck.Name == "sbsd_o" || ck.Name == "bm_so" {
+			return set(BlockSBSDInterstitial, ActionRetry, "response sets "+ck.Name)
+		}
+	}
+	if m := rateLimitRe.FindString(body); m != "" {
+		return set(BlockRateLimited, ActionRetryLater, "body contains "+m)
+	}
+	if resp.Status == 503 || resp.Status == 502 || resp.Status == 504 || resp.Status == 500 {
+		if m := maintenanceRe.FindString(body); m != "" {
+			return set(BlockMaintenance, ActionRetryLater, "body contains "+m)
+		}
+		return set(BlockServerError, ActionRetry, fmt.Sprintf("status %d", resp.Status))
+	}
+	if resp.Status == 403 && strings.Contains(strings.ToLower(headerValue(resp.Headers, "Server")), "akamai") {
+		return set(BlockAccessDenied, ActionSwitchProxy, "status 403 from "+headerValue(resp.Headers, "Server"))
+	}
+	return set(BlockUnknown, ActionGiveUp, fmt.Sprintf("status %d", resp.Status))
+}
@@ -0,0 +1,148 @@
+package scraper
+
+import "testing"
+
+// akamaiDeniedPage is an edge deny page as Akamai serves it, with the
+// reference and the URL HTML-escaped
+const akamaiDeniedPage = `<HTML><HEAD>
+<TITLE>Access Denied</TITLE>
+</HEAD><BODY>
+<H1>Access Denied</H1>
+
+You don't have permission to access "http&#58;&#47;&#47;www&#46;example&#46;com&#47;" on this server.<P>
+Reference&#32;&#35;18&#46;5d7c1402&#46;1700000000&#46;1a2b3c4d
+<P>https&#58;&#47;&#47;errors&#46;edgesuite&#46;net&#47;18&#46;5d7c1402&#46;1700000000&#46;1a2b3c4d</P>
+</BODY>
+</HTML>`
+
+const sbsdInterstitialPage = `<html><head><script src="/Ab12cd/Ef34gh/Ij56kl?v=1a2b-3c4d"></script></head>
+<body><div id="sec-if-cpt-container"><iframe id="sec-cpt-if" class="bm-verify"></iframe></div></body></html>`
+
+func TestClassifyBlock(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		resp     SiteResponse
+		reason   BlockReason
+		action   BlockAction
+		ref      string
+		evidence string // Expected Evidence, when it matters
+	}{
+		{
+			name:   "proxy auth",
+			resp:   SiteResponse{Status: 407, Body: []byte("Proxy Authentication Required")},
+			reason: BlockProxyAuth, action: ActionGiveUp,
+		},
+		{
+			name:   "429 status",
+			resp:   SiteResponse{Status: 429, Headers: map[string]string{"retry-after": "30"}},
+			reason: BlockRateLimited, action: ActionRetryLater,
+		},
+		{
+			name:   "rate limit body",
+			resp:   SiteResponse{Status: 403, Body: []byte("<h1>Too Many Requests</h1>")},
+			reason: BlockRateLimited, action: ActionRetryLater,
+		},
+		{
+			name:   "451 status",
+			resp:   SiteResponse{Status: 451},
+			reason: BlockGeo, action: ActionSwitchGeo,
+		},
+		{
+			name:   "geo block body",
+			resp:   SiteResponse{Status: 403, Body: []byte("<p>This site is not available in your country.</p>")},
+			reason: BlockGeo, action: ActionSwitchGeo,
+		},
+		{
+			name:   "akamai deny page with escaped reference",
+			resp:   SiteResponse{Status: 403, Body: []byte(akamaiDeniedPage)},
+			reason: BlockAccessDenied, action: ActionSwitchProxy,
+			ref: "18.5d7c1402.1700000000.1a2b3c4d", evidence: "access denied page with reference",
+		},
+		{
+			name:   "escaped reference alone",
+			resp:   SiteResponse{Status: 403, Body: []byte(`<p>Reference&#32;&#35;18&#46;9f8e7d6c&#46;1700000001&#46;abcdef01</p>`)},
+			reason: BlockAccessDenied, action: ActionSwitchProxy,
+			ref: "18.9f8e7d6c.1700000001.abcdef01",
+		},
+		{
+			name:   "edgesuite link",
+			resp:   SiteResponse{Status: 403, Body: []byte(`<a href="https://errors.edgesuite.net/">details</a>`)},
+			reason: BlockAccessDenied, action: ActionSwitchProxy, evidence: "access denied page",
+		},
+		{
+			name:   "deny page embedding a bm-verify snippet",
+			resp:   SiteResponse{Status: 403, Body: []byte(akamaiDeniedPage + `<script>var bm-verify = "x";</script>`)},
+			reason: BlockAccessDenied, action: ActionSwitchProxy,
+			ref: "18.5d7c1402.1700000000.1a2b3c4d",
+		},
+		{
+			name:   "deny page setting sbsd_o",
+			resp:   SiteResponse{Status: 403, Body: []byte(akamaiDeniedPage), Cookies: []Cookie{{Name: "sbsd_o", Value: "x"}}},
+			reason: BlockAccessDenied, action: ActionSwitchProxy,
+			ref: "18.5d7c1402.1700000000.1a2b3c4d",
+		},
+		{
+			name:   "403 from AkamaiGHost",
+			resp:   SiteResponse{Status: 403, Headers: map[string]string{"server": "AkamaiGHost"}},
+			reason: BlockAccessDenied, action: ActionSwitchProxy, evidence: "status 403 from AkamaiGHost",
+		},
+		{
+			name:   "sbsd interstitial markers",
+			resp:   SiteResponse{Status: 428, Body: []byte(sbsdInterstitialPage)},
+			reason: BlockSBSDInterstitial, action: ActionRetry,
+		},
+		{
+			name:   "sbsd cookie",
+			resp:   SiteResponse{Status: 403, Cookies: []Cookie{{Name: "bm_so", Value: "x"}}},
+			reason: BlockSBSDInterstitial, action: ActionRetry, evidence: "response sets bm_so",
+		},
+		{
+			name:   "maintenance page",
+			resp:   SiteResponse{Status: 503, Body: []byte("<h1>We are down for scheduled maintenance</h1>")},
+			reason: BlockMaintenance, action: ActionRetryLater,
+		},
+		{
+			name:   "manutenção",
+			resp:   SiteResponse{Status: 503, Body: []byte("<h1>Site em manuten&ccedil;&atilde;o</h1>")},
+			reason: BlockMaintenance, action: ActionRetryLater,
+		},
+		{
+			name:   "bare maintenance word in a 5xx",
+			resp:   SiteResponse{Status: 502, Body: []byte(`<h1>Bad Gateway</h1><footer><a href="/maintenance-policy">Maintenance policy</a></footer>`)},
+			reason: BlockServerError, action: ActionRetry, evidence: "status 502",
+		},
+		{
+			name:   "maintenance phrase outside 5xx",
+			resp:   SiteResponse{Status: 404, Body: []byte("under maintenance")},
+			reason: BlockUnknown, action: ActionGiveUp,
+		},
+		{
+			name:   "server error",
+			resp:   SiteResponse{Status: 500, Body: []byte("Internal Server Error")},
+			reason: BlockServerError, action: ActionRetry,
+		},
+		{
+			name:   "unknown",
+			resp:   SiteResponse{Status: 404, Body: []byte("Not Found")},
+			reason: BlockUnknown, action: ActionGiveUp,
+		},
+	} {
+		got := ClassifyBlock(&c.resp)
+		if got.Reason != c.reason || got.Action != c.action {
+			t.Errorf("%s: %s/%s (%s); want %s/%s", c.name, got.Reason, got.Action, got.Evidence, c.reason, c.action)
+		}
+		if got.ReferenceID != c.ref {
+			t.Errorf("%s: ReferenceID = %q; want %q", c.name, got.ReferenceID, c.ref)
+		}
+		if c.evidence != "" && got.Evidence != c.evidence {
+			t.Errorf("%s: Evidence = %q; want %q", c.name, got.Evidence, c.evidence)
+		}
+		if got.Status != c.resp.Status {
+			t.Errorf("%s: Status = %d; want %d", c.name, got.Status, c.resp.Status)
+		}
+	}
+
+	if got := ClassifyBlock(&SiteResponse{Status: 429, Headers: map[string]string{"Retry-After": "120"}}); got.RetryAfter != "120" {
+		t.Errorf("RetryAfter = %q; want 120", got.RetryAfter)
+	}
+}
@@ -0,0 +1,42 @@
+package cachestore
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gerador_cookies/scraper"
+)
+
+// Backend names accepted by Open
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+	BackendRedis  = "redis"
+)
+
+// DefaultSQLitePath is the database used when no SQLite path is configured
+func DefaultSQLitePath() string {
+	return filepath.Join(scraper.DefaultProviderCacheDir(), "provider-cache.db")
+}
+
+// Open opens the backend by name. path is the JSON file (file) or database
+// (sqlite); an empty path selects the default location. redisURL is only
+// used by the redis backend.
+func Open(backend, path, redisURL string) (scraper.ProviderCacheStore, error) {
+	switch backend {
+	case "", BackendFile:
+		if path == "" {
+			path = scraper.DefaultProviderCachePath()
+		}
+		return scraper.LoadFileCacheStore(path)
+	case BackendSQLite:
+		if path == "" {
+			path = DefaultSQLitePath()
+		}
+		return NewSQLiteStore(path)
+	case BackendRedis:
+		return NewRedisStore(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", backend)
+	}
+}
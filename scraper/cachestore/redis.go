@@ -0,0 +1,321 @@
+package cachestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gerador_cookies/scraper"
+)
+
+const (
+	// DefaultRedisPrefix namespaces provider cache keys in Redis
+	DefaultRedisPrefix = "reqs:provider-cache:"
+
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 5 * time.Second
+)
+
+// RedisStore keeps provider cache entries in any server speaking the Redis
+// protocol (Redis, KeyDB, Valkey, Dragonfly). Entries carry a TTL matching
+// their ExpiresAt so the server evicts them on its own.
+type RedisStore struct {
+	mu       sync.Mutex
+	addr     string
+	password string
+	username string
+	db       int
+	prefix   string
+	conn     net.Conn
+	rd       *bufio.Reader
+}
+
+var _ scraper.ProviderCacheStore = (*RedisStore)(nil)
+
+// NewRedisStore connects to rawURL (redis://[user:password@]host:port[/db])
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// *url.Error repeats the raw URL, password included
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported redis scheme: %q", u.Scheme)
+	}
+	rs := &RedisStore{addr: u.Host, prefix: DefaultRedisPrefix}
+	if !strings.Contains(rs.addr, ":") {
+		rs.addr += ":6379"
+	}
+	if u.User != nil {
+		rs.username = u.User.Username()
+		rs.password, _ = u.User.Password()
+		if rs.password == "" {
+			// redis://password@host is a common shorthand
+			rs.password, rs.username = rs.username, ""
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis db %q", db)
+		}
+		rs.db = n
+	}
+	if p := u.Query().Get("prefix"); p != "" {
+		rs.prefix = p
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err := rs.connectLocked(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (rs *RedisStore) Load(key string) (scraper.ProviderCacheEntry, bool, error) {
+	reply, err := rs.do("GET", rs.prefix+key)
+	if err != nil {
+		return scraper.ProviderCacheEntry{}, false, err
+	}
+	if reply == nil {
+		return scraper.ProviderCacheEntry{}, false, nil
+	}
+	raw, ok := reply.(string)
+	if !ok {
+		return scraper.ProviderCacheEntry{}, false, fmt.Errorf("unexpected GET reply %T", reply)
+	}
+	var entry scraper.ProviderCacheEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return scraper.ProviderCacheEntry{}, false, fmt.Errorf("decode entry %s: %w", key, err)
+	}
+	return entry, true, nil
+}
+
+func (rs *RedisStore) Store(key string, entry scraper.ProviderCacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", rs.prefix + key, string(b)}
+	if !entry.ExpiresAt.IsZero() {
+		ttl := time.Until(entry.ExpiresAt).Milliseconds()
+		if ttl <= 0 {
+			return rs.Delete(key)
+		}
+		args = append(args, "PX", strconv.FormatInt(ttl, 10))
+	}
+	_, err = rs.do(args...)
+	return err
+}
+
+func (rs *RedisStore) Delete(key string) error {
+	_, err := rs.do("DEL", rs.prefix+key)
+	return err
+}
+
+// Entries scans the prefix and fetches each page of keys with one MGET
+func (rs *RedisStore) Entries() (map[string]scraper.ProviderCacheEntry, error) {
+	out := make(map[string]scraper.ProviderCacheEntry)
+	cursor := "0"
+	for {
+		reply, err := rs.do("SCAN", cursor, "MATCH", rs.prefix+"*", "COUNT", "200")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %T", reply)
+		}
+		cursor, _ = parts[0].(string)
+		keys, _ := parts[1].([]interface{})
+		if err := rs.mget(keys, out); err != nil {
+			return nil, err
+		}
+		if cursor == "0" || cursor == "" {
+			return out, nil
+		}
+	}
+}
+
+// mget loads the full keys returned by SCAN into out. Keys that expired
+// since the SCAN or hold undecodable values are skipped.
+func (rs *RedisStore) mget(keys []interface{}, out map[string]scraper.ProviderCacheEntry) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "MGET")
+	for _, k := range keys {
+		full, _ := k.(string)
+		args = append(args, full)
+	}
+	reply, err := rs.do(args...)
+	if err != nil {
+		return err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != len(keys) {
+		return fmt.Errorf("unexpected MGET reply %T", reply)
+	}
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var entry scraper.ProviderCacheEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		out[strings.TrimPrefix(args[i+1], rs.prefix)] = entry
+	}
+	return nil
+}
+
+func (rs *RedisStore) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.conn == nil {
+		return nil
+	}
+	err := rs.conn.Close()
+	rs.conn = nil
+	return err
+}
+
+// do sends one command, reconnecting once if the connection was dropped
+func (rs *RedisStore) do(args ...string) (interface{}, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	reply, err := rs.doLocked(args...)
+	if err == nil {
+		return reply, nil
+	}
+	if _, isRedisErr := err.(redisError); isRedisErr {
+		return nil, err
+	}
+	if cerr := rs.connectLocked(); cerr != nil {
+		return nil, cerr
+	}
+	return rs.doLocked(args...)
+}
+
+func (rs *RedisStore) connectLocked() error {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn = nil
+	}
+	conn, err := net.DialTimeout("tcp", rs.addr, redisDialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect redis %s: %w", rs.addr, err)
+	}
+	rs.conn = conn
+	rs.rd = bufio.NewReader(conn)
+
+	if rs.password != "" {
+		auth := []string{"AUTH", rs.password}
+		if rs.username != "" {
+			auth = []string{"AUTH", rs.username, rs.password}
+		}
+		if _, err := rs.doLocked(auth...); err != nil {
+			return fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if rs.db != 0 {
+		if _, err := rs.doLocked("SELECT", strconv.Itoa(rs.db)); err != nil {
+			return fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return nil
+}
+
+func (rs *RedisStore) doLocked(args ...string) (interface{}, error) {
+	if rs.conn == nil {
+		return nil, io.ErrClosedPipe
+	}
+	_ = rs.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(rs.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(rs.rd)
+}
+
+// redisError is an error reply (-ERR ...) returned by the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readRESP parses one RESP2 reply. Bulk strings and simple strings are
+// returned as string, integers as int64, arrays as []interface{} and nil
+// bulk/array replies as nil.
+func readRESP(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readRESP(rd)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,153 @@
+// Package cachestore provides shared ProviderCacheStore backends (SQLite and
+// Redis) for deployments where several processes or replicas use one cache.
+package cachestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gerador_cookies/scraper"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlitePurgeInterval is how often Store deletes expired rows
+const sqlitePurgeInterval = 10 * time.Minute
+
+// SQLiteStore keeps provider cache entries in a SQLite database. WAL mode
+// lets several processes on the same host share the file. Expired rows are
+// purged on open and then every sqlitePurgeInterval, on write.
+type SQLiteStore struct {
+	db *sql.DB
+
+	mu         sync.Mutex
+	lastPurged time.Time
+}
+
+var _ scraper.ProviderCacheStore = (*SQLiteStore)(nil)
+
+// NewSQLiteStore opens (or creates) the database at path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("create sqlite dir: %w", err)
+		}
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS provider_cache (
+		key        TEXT PRIMARY KEY,
+		entry      TEXT NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS provider_cache_expires_at ON provider_cache (expires_at)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create provider_cache table: %w", err)
+	}
+	s := &SQLiteStore{db: db}
+	if _, err := s.Purge(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("purge expired entries: %w", err)
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) Load(key string) (scraper.ProviderCacheEntry, bool, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT entry FROM provider_cache WHERE key = ?`, key).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return scraper.ProviderCacheEntry{}, false, nil
+	}
+	if err != nil {
+		return scraper.ProviderCacheEntry{}, false, err
+	}
+	var entry scraper.ProviderCacheEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return scraper.ProviderCacheEntry{}, false, fmt.Errorf("decode entry %s: %w", key, err)
+	}
+	return entry, true, nil
+}
+
+func (s *SQLiteStore) Store(key string, entry scraper.ProviderCacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO provider_cache (key, entry, expires_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET entry = excluded.entry, expires_at = excluded.expires_at`,
+		key, string(b), unixOrZero(entry.ExpiresAt),
+	)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	due := time.Since(s.lastPurged) >= sqlitePurgeInterval
+	s.mu.Unlock()
+	if due {
+		_, err = s.Purge()
+	}
+	return err
+}
+
+// Purge deletes the rows whose entry has expired and returns how many
+func (s *SQLiteStore) Purge() (int64, error) {
+	now := time.Now()
+	res, err := s.db.Exec(`DELETE FROM provider_cache WHERE expires_at > 0 AND expires_at <= ?`, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.lastPurged = now
+	s.mu.Unlock()
+	return res.RowsAffected()
+}
+
+func (s *SQLiteStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM provider_cache WHERE key = ?`, key)
+	return err
+}
+
+func (s *SQLiteStore) Entries() (map[string]scraper.ProviderCacheEntry, error) {
+	rows, err := s.db.Query(`SELECT key, entry FROM provider_cache`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]scraper.ProviderCacheEntry)
+	for rows.Next() {
+		var key, raw string
+		if err := rows.Scan(&key, &raw); err != nil {
+			return nil, err
+		}
+		var entry scraper.ProviderCacheEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		out[key] = entry
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
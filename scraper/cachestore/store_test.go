@@ -0,0 +1,496 @@
+package cachestore
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gerador_cookies/scraper"
+)
+
+// testEntry builds an entry for key "domain|provider|mode" expiring in ttl
+func testEntry(domain, provider, mode string, ttl time.Duration) scraper.ProviderCacheEntry {
+	now := time.Now().Truncate(time.Second)
+	return scraper.ProviderCacheEntry{
+		ScriptURL:  "https://" + domain + "/abc/def",
+		ScriptHash: "hash-" + provider,
+		Dynamic:    "dynamic-" + provider + "-" + mode,
+		ExpiresAt:  now.Add(ttl),
+		UpdatedAt:  now,
+		Domain:     domain,
+		Provider:   provider,
+		Mode:       mode,
+	}
+}
+
+func key(e scraper.ProviderCacheEntry) string {
+	return e.Domain + "|" + e.Provider + "|" + e.Mode
+}
+
+func sameEntry(a, b scraper.ProviderCacheEntry) bool {
+	return a.ScriptURL == b.ScriptURL && a.ScriptHash == b.ScriptHash && a.Dynamic == b.Dynamic &&
+		a.Domain == b.Domain && a.Provider == b.Provider && a.Mode == b.Mode &&
+		a.ExpiresAt.Equal(b.ExpiresAt) && a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+// testStoreContract runs the ProviderCacheStore contract against store.
+// reopen, when not nil, returns a new store over the same data.
+func testStoreContract(t *testing.T, store scraper.ProviderCacheStore, reopen func() scraper.ProviderCacheStore) {
+	t.Helper()
+	a := testEntry("www.example.com", "jevi", "sensor", time.Hour)
+	b := testEntry("www.example.com", "n4s", "sbsd", time.Hour)
+
+	if _, found, err := store.Load(key(a)); err != nil || found {
+		t.Fatalf("Load(missing) = found %v, err %v; want not found", found, err)
+	}
+	for _, e := range []scraper.ProviderCacheEntry{a, b} {
+		if err := store.Store(key(e), e); err != nil {
+			t.Fatalf("Store(%s): %v", key(e), err)
+		}
+	}
+	got, found, err := store.Load(key(a))
+	if err != nil || !found || !sameEntry(got, a) {
+		t.Fatalf("Load(%s) = %+v, %v, %v; want %+v", key(a), got, found, err, a)
+	}
+
+	a.Dynamic = "dynamic-updated"
+	if err := store.Store(key(a), a); err != nil {
+		t.Fatalf("Store(overwrite): %v", err)
+	}
+	if got, _, _ := store.Load(key(a)); got.Dynamic != "dynamic-updated" {
+		t.Fatalf("overwrite not visible: Dynamic = %q", got.Dynamic)
+	}
+
+	all, err := store.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(all) != 2 || !sameEntry(all[key(a)], a) || !sameEntry(all[key(b)], b) {
+		t.Fatalf("Entries = %+v; want %s and %s", all, key(a), key(b))
+	}
+
+	if reopen != nil {
+		if err := store.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		store = reopen()
+		if got, found, err := store.Load(key(b)); err != nil || !found || !sameEntry(got, b) {
+			t.Fatalf("Load after reopen = %+v, %v, %v; want %+v", got, found, err, b)
+		}
+	}
+
+	if err := store.Delete(key(a)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(key(a)); err != nil {
+		t.Fatalf("Delete(missing): %v", err)
+	}
+	if _, found, _ := store.Load(key(a)); found {
+		t.Fatalf("Load after Delete: still found")
+	}
+	if all, _ := store.Entries(); len(all) != 1 {
+		t.Fatalf("Entries after Delete = %d entries; want 1", len(all))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestFileStoreContract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-cache.json")
+	open := func() scraper.ProviderCacheStore {
+		fs, err := scraper.LoadFileCacheStore(path)
+		if err != nil {
+			t.Fatalf("LoadFileCacheStore: %v", err)
+		}
+		return fs
+	}
+	testStoreContract(t, open(), open)
+}
+
+func TestSQLiteStoreContract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-cache.db")
+	open := func() scraper.ProviderCacheStore {
+		s, err := NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		return s
+	}
+	testStoreContract(t, open(), open)
+}
+
+func TestRedisStoreContract(t *testing.T) {
+	srv := newFakeRedis(t)
+	open := func() scraper.ProviderCacheStore {
+		rs, err := NewRedisStore("redis://" + srv.addr())
+		if err != nil {
+			t.Fatalf("NewRedisStore: %v", err)
+		}
+		return rs
+	}
+	testStoreContract(t, open(), open)
+}
+
+func TestSQLiteStorePurgesExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-cache.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	expired := testEntry("old.example.com", "jevi", "sensor", -time.Minute)
+	live := testEntry("new.example.com", "jevi", "sensor", time.Hour)
+	noExpiry := testEntry("forever.example.com", "jevi", "sensor", 0)
+	noExpiry.ExpiresAt = time.Time{}
+	for _, e := range []scraper.ProviderCacheEntry{expired, live, noExpiry} {
+		if err := s.Store(key(e), e); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	n, err := s.Purge()
+	if err != nil || n != 1 {
+		t.Fatalf("Purge = %d, %v; want 1 row", n, err)
+	}
+	all, _ := s.Entries()
+	if _, ok := all[key(expired)]; ok || len(all) != 2 {
+		t.Fatalf("Entries after Purge = %v; want only the live entries", all)
+	}
+
+	// Rows that expire while the store is closed are purged on open
+	if err := s.Store(key(expired), expired); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	s.Close()
+	s, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if _, found, _ := s.Load(key(expired)); found {
+		t.Fatalf("expired row survived reopen")
+	}
+}
+
+func TestRedisStoreEntriesBatchesGets(t *testing.T) {
+	srv := newFakeRedis(t)
+	rs, err := NewRedisStore("redis://" + srv.addr())
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer rs.Close()
+
+	const n = 450 // Three SCAN pages of COUNT 200
+	for i := 0; i < n; i++ {
+		e := testEntry(fmt.Sprintf("site%d.example.com", i), "jevi", "sensor", time.Hour)
+		if err := rs.Store(key(e), e); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+	srv.other.Set("unrelated", "not json") // Outside the prefix: ignored
+
+	srv.resetCounts()
+	all, err := rs.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(all) != n {
+		t.Fatalf("Entries = %d; want %d", len(all), n)
+	}
+	counts := srv.counts()
+	if counts["GET"] != 0 || counts["MGET"] != counts["SCAN"] || counts["SCAN"] != 3 {
+		t.Fatalf("commands = %v; want 3 SCAN + 3 MGET and no GET", counts)
+	}
+}
+
+func TestRedisStoreExpiry(t *testing.T) {
+	srv := newFakeRedis(t)
+	rs, err := NewRedisStore("redis://" + srv.addr())
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer rs.Close()
+
+	e := testEntry("www.example.com", "jevi", "sensor", time.Hour)
+	if err := rs.Store(key(e), e); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if ttl := srv.ttl(DefaultRedisPrefix + key(e)); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("TTL = %s; want about 1h", ttl)
+	}
+
+	// Storing an already expired entry deletes it
+	e.ExpiresAt = time.Now().Add(-time.Second)
+	if err := rs.Store(key(e), e); err != nil {
+		t.Fatalf("Store(expired): %v", err)
+	}
+	if _, found, _ := rs.Load(key(e)); found {
+		t.Fatalf("expired entry still stored")
+	}
+}
+
+func TestRedisStoreAuthAndDB(t *testing.T) {
+	srv := newFakeRedis(t)
+	srv.setPassword("s3cret")
+	rs, err := NewRedisStore("redis://:s3cret@" + srv.addr() + "/2?prefix=test:")
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer rs.Close()
+	e := testEntry("www.example.com", "jevi", "sensor", time.Hour)
+	if err := rs.Store(key(e), e); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if srv.db != "2" {
+		t.Fatalf("SELECT db = %q; want 2", srv.db)
+	}
+	if _, ok := srv.get("test:" + key(e)); !ok {
+		t.Fatalf("key not stored under the custom prefix")
+	}
+
+	if _, err := NewRedisStore("redis://:wrong@" + srv.addr()); err == nil {
+		t.Fatalf("NewRedisStore with a wrong password succeeded")
+	}
+	_, err = NewRedisStore("redis://:s3cret@[::1")
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("NewRedisStore with a bad URL: %v; want an error without the password", err)
+	}
+}
+
+// ============================================================================
+// Redis stand-in
+// ============================================================================
+
+// fakeRedis speaks the RESP subset RedisStore uses (AUTH, SELECT, GET, SET
+// with PX, DEL, MGET, SCAN with MATCH prefix* and COUNT)
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	db       string
+	other    fakeData
+
+	mu    sync.Mutex
+	data  map[string]fakeItem
+	calls map[string]int
+}
+
+type fakeItem struct {
+	value   string
+	expires time.Time
+}
+
+// fakeData writes directly into the stand-in, bypassing RedisStore
+type fakeData struct{ srv *fakeRedis }
+
+func (d fakeData) Set(k, v string) {
+	d.srv.mu.Lock()
+	defer d.srv.mu.Unlock()
+	d.srv.data[k] = fakeItem{value: v}
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &fakeRedis{ln: ln, data: make(map[string]fakeItem), calls: make(map[string]int)}
+	srv.other = fakeData{srv}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+// setPassword requires AUTH; the accept loop is already running, hence the lock
+func (f *fakeRedis) setPassword(p string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.password = p
+}
+
+func (f *fakeRedis) requiredPassword() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.password
+}
+
+func (f *fakeRedis) resetCounts() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = make(map[string]int)
+}
+
+func (f *fakeRedis) counts() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]int, len(f.calls))
+	for k, v := range f.calls {
+		out[k] = v
+	}
+	return out
+}
+
+func (f *fakeRedis) get(k string) (fakeItem, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	it, ok := f.data[k]
+	if ok && !it.expires.IsZero() && time.Now().After(it.expires) {
+		delete(f.data, k)
+		return fakeItem{}, false
+	}
+	return it, ok
+}
+
+func (f *fakeRedis) ttl(k string) time.Duration {
+	it, ok := f.get(k)
+	if !ok || it.expires.IsZero() {
+		return 0
+	}
+	return time.Until(it.expires)
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.requiredPassword() == ""
+	for {
+		req, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		parts, _ := req.([]interface{})
+		args := make([]string, len(parts))
+		for i, p := range parts {
+			args[i], _ = p.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		f.mu.Lock()
+		f.calls[cmd]++
+		f.mu.Unlock()
+
+		var reply string
+		switch {
+		case cmd == "AUTH":
+			if args[len(args)-1] != f.requiredPassword() {
+				reply = "-WRONGPASS invalid password\r\n"
+				break
+			}
+			authed = true
+			reply = "+OK\r\n"
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			f.mu.Lock()
+			f.db = args[1]
+			f.mu.Unlock()
+			reply = "+OK\r\n"
+		case cmd == "GET":
+			if it, ok := f.get(args[1]); ok {
+				reply = bulk(it.value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd == "SET":
+			it := fakeItem{value: args[2]}
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				it.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			f.mu.Lock()
+			f.data[args[1]] = it
+			f.mu.Unlock()
+			reply = "+OK\r\n"
+		case cmd == "DEL":
+			f.mu.Lock()
+			_, ok := f.data[args[1]]
+			delete(f.data, args[1])
+			f.mu.Unlock()
+			if ok {
+				reply = ":1\r\n"
+			} else {
+				reply = ":0\r\n"
+			}
+		case cmd == "MGET":
+			var b strings.Builder
+			fmt.Fprintf(&b, "*%d\r\n", len(args)-1)
+			for _, k := range args[1:] {
+				if it, ok := f.get(k); ok {
+					b.WriteString(bulk(it.value))
+				} else {
+					b.WriteString("$-1\r\n")
+				}
+			}
+			reply = b.String()
+		case cmd == "SCAN":
+			reply = f.scan(args)
+		default:
+			reply = "-ERR unknown command '" + cmd + "'\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// scan pages through the sorted keys; the cursor is the offset of the next page
+func (f *fakeRedis) scan(args []string) string {
+	cursor, _ := strconv.Atoi(args[1])
+	prefix, count := "", 10
+	for i := 2; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			prefix = strings.TrimSuffix(args[i+1], "*")
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.data))
+	for k := range f.data {
+		keys = append(keys, k)
+	}
+	f.mu.Unlock()
+	sort.Strings(keys)
+
+	end := cursor + count
+	next := strconv.Itoa(end)
+	if end >= len(keys) {
+		end, next = len(keys), "0"
+	}
+	var page []string
+	for _, k := range keys[min(cursor, len(keys)):end] {
+		if strings.HasPrefix(k, prefix) {
+			page = append(page, k)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*2\r\n%s*%d\r\n", bulk(next), len(page))
+	for _, k := range page {
+		b.WriteString(bulk(k))
+	}
+	return b.String()
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
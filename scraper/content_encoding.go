@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDecodedBodySize bounds a decoded response body, so a compression bomb
+// cannot exhaust memory
+const MaxDecodedBodySize = 64 << 20
+
+// SupportedContentEncodings lists the codings DecodeBody understands
+var SupportedContentEncodings = []string{"gzip", "deflate", "br", "zstd"}
+
+// ErrUnsupportedEncoding is wrapped by ContentDecodeError for unknown codings
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ContentDecodeError reports a response body that could not be decoded
+type ContentDecodeError struct {
+	Encoding string // Coding that failed
+	Err      error
+}
+
+func (e *ContentDecodeError) Error() string {
+	return fmt.Sprintf("decode %s body: %v", e.Encoding, e.Err)
+}
+
+func (e *ContentDecodeError) Unwrap() error { return e.Err }
+
+var (
+	zstdDecoderOnce sync.Once
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+)
+
+// DecodeBody reverses the codings listed in contentEncoding ("gzip",
+// "br", "gzip, br"...), last applied first. identity is skipped. When a
+// coding fails on a body that is already plain text (the TLS-API may decode
+// it and keep the header) the body is returned as-is; any other failure is a
+// *ContentDecodeError.
+func DecodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if len(body) == 0 || coding == "" || coding == "identity" {
+			continue
+		}
+		decoded, err := decodeOne(body, coding)
+		if err != nil {
+			if looksDecoded(body) {
+				return body, nil
+			}
+			return nil, &ContentDecodeError{Encoding: coding, Err: err}
+		}
+		body = decoded
+	}
+	return body, nil
+}
+
+func decodeOne(body []byte, coding string) ([]byte, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r)
+	case "deflate":
+		// deflate is zlib-wrapped per RFC 9110, but some servers send raw deflate
+		if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			defer r.Close()
+			return readLimited(r)
+		}
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		return readLimited(r)
+	case "br":
+		return readLimited(brotli.NewReader(bytes.NewReader(body)))
+	case "zstd":
+		zstdDecoderOnce.Do(func() {
+			zstdDecoder, zstdDecoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(MaxDecodedBodySize))
+		})
+		if zstdDecoderErr != nil {
+			return nil, zstdDecoderErr
+		}
+		return zstdDecoder.DecodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, coding)
+	}
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(r, MaxDecodedBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxDecodedBodySize {
+		return nil, fmt.Errorf("decoded body exceeds %d bytes", MaxDecodedBodySize)
+	}
+	return out, nil
+}
+
+// looksDecoded reports whether body is plain text rather than compressed data
+func looksDecoded(body []byte) bool {
+	return utf8.Valid(body) && bytes.IndexByte(body, 0) < 0
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
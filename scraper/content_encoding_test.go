@@ -0,0 +1,191 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+const samplePage = `<html><head><script src="/akam/13/abc"></script></head><body>ok</body></html>`
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func brotliBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func rawDeflateBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(b, nil)
+}
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte(samplePage)
+	for _, c := range []struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{"identity", plain, "identity"},
+		{"no header", plain, ""},
+		{"gzip", gzipBytes(t, plain), "gzip"},
+		{"x-gzip", gzipBytes(t, plain), "X-Gzip"},
+		{"br", brotliBytes(t, plain), "br"},
+		{"zstd", zstdBytes(t, plain), "zstd"},
+		{"deflate zlib", zlibBytes(t, plain), "deflate"},
+		{"deflate raw", rawDeflateBytes(t, plain), "deflate"},
+		// gzip applied first, then br: decoded in reverse order
+		{"stacked gzip, br", brotliBytes(t, gzipBytes(t, plain)), "gzip, br"},
+		{"stacked with identity", zstdBytes(t, gzipBytes(t, plain)), "gzip,identity, zstd"},
+		// The TLS-API may decode the body and keep the header
+		{"already decoded", plain, "gzip"},
+		{"already decoded stacked", plain, "gzip, br"},
+		{"already decoded unknown coding", plain, "compress"},
+	} {
+		got, err := DecodeBody(c.body, c.encoding)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if string(got) != samplePage {
+			t.Errorf("%s: got %q", c.name, got)
+		}
+	}
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	binary := []byte{0x1f, 0x00, 0xff, 0xfe, 0x00, 0x01, 0x02}
+	for _, c := range []struct {
+		name        string
+		body        []byte
+		encoding    string
+		wantCoding  string
+		unsupported bool
+	}{
+		{"corrupt gzip", binary, "gzip", "gzip", false},
+		{"corrupt br", binary, "br", "br", false},
+		{"corrupt zstd", binary, "zstd", "zstd", false},
+		{"corrupt deflate", binary, "deflate", "deflate", false},
+		{"unsupported", binary, "compress", "compress", true},
+		// The outer coding decodes, the inner one fails
+		{"stacked inner failure", gzipBytes(t, binary), "br, gzip", "br", false},
+	} {
+		_, err := DecodeBody(c.body, c.encoding)
+		var de *ContentDecodeError
+		if !errors.As(err, &de) {
+			t.Errorf("%s: err = %v; want *ContentDecodeError", c.name, err)
+			continue
+		}
+		if de.Encoding != c.wantCoding {
+			t.Errorf("%s: Encoding = %q; want %q", c.name, de.Encoding, c.wantCoding)
+		}
+		if got := errors.Is(err, ErrUnsupportedEncoding); got != c.unsupported {
+			t.Errorf("%s: errors.Is(ErrUnsupportedEncoding) = %v; want %v", c.name, got, c.unsupported)
+		}
+	}
+}
+
+func TestDecodeBodyLimits(t *testing.T) {
+	big := make([]byte, MaxDecodedBodySize+1)
+	for _, c := range []struct {
+		coding string
+		body   []byte
+	}{
+		{"zstd", zstdBytes(t, big)},
+		{"gzip", gzipBytes(t, big)},
+	} {
+		_, err := DecodeBody(c.body, c.coding)
+		var de *ContentDecodeError
+		if !errors.As(err, &de) || de.Encoding != c.coding {
+			t.Errorf("%s bomb: err = %v; want a ContentDecodeError", c.coding, err)
+		}
+	}
+}
+
+// TestSiteClientWrapsDecodeErrors checks that a body that fails to decode is
+// reported under the phase of the request that fetched it
+func TestSiteClientWrapsDecodeErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TLSResponse{Success: true, Data: &TLSResponseData{
+			Status:  200,
+			Headers: map[string]string{"content-encoding": "gzip"},
+			Body:    "\x1f\x8b\x00\xff\x00",
+		}})
+	}))
+	defer srv.Close()
+
+	tls := NewTLSAPIClientWithConfig(srv.URL, "", time.Second)
+	sc := NewSiteClient(tls, NewCookieJar(), &Config{Domain: "www.example.com"}, UserAgent{}, "", "")
+	for _, c := range []struct {
+		step  TimelineStep
+		phase ErrorPhase
+	}{
+		{TimelineHomepage, PhaseHomepage},
+		{TimelineScriptFetch, PhaseScriptFetch},
+		{TimelineSiteRequest, PhaseTLSAPI},
+	} {
+		_, err := sc.request(c.step, "GET", "https://www.example.com/", "", nil, nil)
+		var se *SolverError
+		if !errors.As(err, &se) {
+			t.Fatalf("%s: err = %v; want *SolverError", c.step, err)
+		}
+		if se.Phase != c.phase || se.Retryable || !strings.Contains(se.RawError, "decode gzip body") {
+			t.Errorf("%s: %+v; want phase %s, not retryable, with the decode error", c.step, se, c.phase)
+		}
+	}
+	if step := abckFailureStep(NewError(PhaseScriptFetch, "decode response body", nil)); step != StepScriptFetch {
+		t.Errorf("abckFailureStep = %s; want %s", step, StepScriptFetch)
+	}
+	if step := sbsdFailureStep(NewError(PhaseHomepage, "decode response body", nil)); step != StepScriptURLExtract {
+		t.Errorf("sbsdFailureStep = %s; want %s", step, StepScriptURLExtract)
+	}
+}
@@ -15,6 +15,7 @@ const (
 	PhaseCookieValidation ErrorPhase = "COOKIE_VALIDATION" // Validating _abck cookie
 	PhaseSBSDPost         ErrorPhase = "SBSD_POST"         // Posting SBSD challenge
 	PhaseTLSAPI           ErrorPhase = "TLS_API"           // TLS-API communication error
+	PhaseQuota            ErrorPhase = "QUOTA"             // Provider call budget exhausted
 )
 
 // SolverError represents a structured error with context
@@ -85,7 +86,7 @@ func isRetryablePhase(phase ErrorPhase) bool {
 	switch phase {
 	case PhaseHomepage, PhaseScriptFetch, PhaseProviderCall, PhaseSensorPost, PhaseSBSDPost:
 		return true
-	case PhaseInit, PhaseScriptExtract, PhaseCookieValidation:
+	case PhaseInit, PhaseScriptExtract, PhaseCookieValidation, PhaseQuota:
 		return false
 	default:
 		return false
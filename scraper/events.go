@@ -0,0 +1,128 @@
+package scraper
+
+import "time"
+
+// Event is passed to an EventHandler. Switch on the concrete type:
+// *PhaseStartEvent, *PhaseEndEvent, *ProviderRequestEvent,
+// *ProviderResponseEvent, *PostResultEvent or *CookiesChangedEvent.
+type Event interface {
+	EventTime() time.Time
+}
+
+// EventHandler observes a Scraper and its solvers. Handlers run on the
+// solving goroutine, in registration order, so they should return quickly.
+type EventHandler interface {
+	HandleEvent(Event)
+}
+
+// EventHandlerFunc adapts a function to EventHandler
+type EventHandlerFunc func(Event)
+
+func (f EventHandlerFunc) HandleEvent(e Event) { f(e) }
+
+// PhaseStartEvent fires before a step runs: each upstream request attempt
+// and the script URL resolution
+type PhaseStartEvent struct {
+	Phase    ErrorPhase
+	Step     TimelineStep
+	Provider string // provider_call only
+	Attempt  int
+	Time     time.Time
+}
+
+// PhaseEndEvent fires when a step ends; it carries its timeline entry
+type PhaseEndEvent struct {
+	Phase ErrorPhase
+	Entry TimelineEntry
+	Time  time.Time
+}
+
+// ProviderRequestEvent fires before a provider API call
+type ProviderRequestEvent struct {
+	Provider string
+	Mode     SolveMode // SolveABCK or SolveSBSD
+	Attempt  int       // Provider calls made by the current solve attempt, this one included
+	Request  TLSRequest
+	Time     time.Time
+}
+
+// ProviderResponseEvent fires after a provider API call. Response is nil
+// when the call failed before an answer (see Err).
+type ProviderResponseEvent struct {
+	Provider string
+	Mode     SolveMode
+	Attempt  int
+	Response *TLSResponse
+	Duration time.Duration
+	Err      error
+	Time     time.Time
+}
+
+// PostResultEvent fires when a sensor or SBSD post was accepted or rejected
+type PostResultEvent struct {
+	Step     TimelineStep // TimelineSensorPost or TimelineSbsdPost
+	Attempt  int
+	Status   int
+	Accepted bool
+	Time     time.Time
+}
+
+// CookiesChangedEvent fires when a response sets cookies to new values.
+// Cookies holds only those: a cookie set again with the value an earlier
+// response of the same scraper gave it is left out.
+type CookiesChangedEvent struct {
+	Step    TimelineStep // Step whose response set them
+	Cookies []Cookie
+	Time    time.Time
+}
+
+func (e *PhaseStartEvent) EventTime() time.Time       { return e.Time }
+func (e *PhaseEndEvent) EventTime() time.Time         { return e.Time }
+func (e *ProviderRequestEvent) EventTime() time.Time  { return e.Time }
+func (e *ProviderResponseEvent) EventTime() time.Time { return e.Time }
+func (e *PostResultEvent) EventTime() time.Time       { return e.Time }
+func (e *CookiesChangedEvent) EventTime() time.Time   { return e.Time }
+
+// stepPhases maps timeline steps to the phase reported in events
+var stepPhases = map[TimelineStep]ErrorPhase{
+	TimelineHomepage:     PhaseHomepage,
+	TimelineScriptURL:    PhaseScriptExtract,
+	TimelineScriptFetch:  PhaseScriptFetch,
+	TimelineScriptSeed:   PhaseScriptFetch,
+	TimelineProviderCall: PhaseProviderCall,
+	TimelineSensorPost:   PhaseSensorPost,
+	TimelineSbsdPost:     PhaseSBSDPost,
+	TimelineSiteRequest:  PhaseTLSAPI,
+}
+
+// progressEvent converts e for a ProgressFunc; provider events have no
+// progress counterpart
+func progressEvent(e Event) (ProgressEvent, bool) {
+	switch e := e.(type) {
+	case *PhaseStartEvent:
+		return ProgressEvent{Type: ProgressStepStarted, Step: e.Step, Provider: e.Provider, Attempt: e.Attempt, Time: e.Time}, true
+	case *PhaseEndEvent:
+		return ProgressEvent{
+			Type:       ProgressStepFinished,
+			Step:       e.Entry.Step,
+			Provider:   e.Entry.Provider,
+			Attempt:    e.Entry.Attempt,
+			Status:     e.Entry.Status,
+			RequestID:  e.Entry.RequestID,
+			DurationMs: e.Entry.Duration.Milliseconds(),
+			Detail:     e.Entry.Detail,
+			Error:      e.Entry.Error,
+			Time:       e.Time,
+		}, true
+	case *PostResultEvent:
+		accepted := e.Accepted
+		return ProgressEvent{Type: ProgressPostResult, Step: e.Step, Attempt: e.Attempt, Status: e.Status, Accepted: &accepted, Time: e.Time}, true
+	case *CookiesChangedEvent:
+		names := make([]string, 0, len(e.Cookies))
+		for _, c := range e.Cookies {
+			names = append(names, c.Name)
+		}
+		return ProgressEvent{Type: ProgressCookies, Step: e.Step, Cookies: names, Time: e.Time}, true
+	}
+	return ProgressEvent{}, false
+}
@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventLog renders events as short strings, prefixed with the handler name
+type eventLog struct {
+	lines []string
+}
+
+func (l *eventLog) handler(name string) EventHandler {
+	return EventHandlerFunc(func(e Event) {
+		if e.EventTime().IsZero() {
+			l.lines = append(l.lines, name+" zero time")
+		}
+		l.lines = append(l.lines, name+" "+describeEvent(e))
+	})
+}
+
+func describeEvent(e Event) string {
+	switch e := e.(type) {
+	case *PhaseStartEvent:
+		return fmt.Sprintf("start %s %s %s #%d", e.Phase, e.Step, e.Provider, e.Attempt)
+	case *PhaseEndEvent:
+		return fmt.Sprintf("end %s %s #%d status=%d id=%s err=%q", e.Phase, e.Entry.Step, e.Entry.Attempt, e.Entry.Status, e.Entry.RequestID, e.Entry.Error)
+	case *ProviderRequestEvent:
+		return fmt.Sprintf("provider request %s %s #%d %s", e.Provider, e.Mode, e.Attempt, e.Request.URL)
+	case *ProviderResponseEvent:
+		return fmt.Sprintf("provider response %s %s #%d status=%d err=%v", e.Provider, e.Mode, e.Attempt, e.Response.GetStatus(), e.Err)
+	case *PostResultEvent:
+		return fmt.Sprintf("post %s #%d status=%d accepted=%v", e.Step, e.Attempt, e.Status, e.Accepted)
+	case *CookiesChangedEvent:
+		names := make([]string, len(e.Cookies))
+		for i, c := range e.Cookies {
+			names[i] = c.Name + "=" + c.Value
+		}
+		return fmt.Sprintf("cookies %s %s", e.Step, strings.Join(names, ","))
+	}
+	return fmt.Sprintf("unknown %T", e)
+}
+
+func tlsResponse(status int, requestID string, cookies ...Cookie) *TLSResponse {
+	return &TLSResponse{
+		Success:  true,
+		Data:     &TLSResponseData{Status: status, Cookies: cookies},
+		Metadata: &TLSMetadata{RequestID: requestID},
+	}
+}
+
+func TestTimelineEvents(t *testing.T) {
+	var log eventLog
+	var progress []ProgressEvent
+	tl := &Timeline{}
+	tl.AddEventHandler(log.handler("a"))
+	tl.AddEventHandler(nil)
+	tl.AddEventHandler(log.handler("b"))
+	tl.SetProgress(func(e ProgressEvent) { progress = append(progress, e) })
+
+	started := time.Now()
+	tl.Start(TimelineHomepage, "")
+	tl.Record(TimelineHomepage, "", started, tlsResponse(200, "req-1", Cookie{Name: "bm_sz", Value: "1"}, Cookie{Name: "_abck", Value: "pending"}), nil)
+	tl.emit(&ProviderRequestEvent{Provider: "jevi", Mode: SolveABCK, Attempt: 1, Request: TLSRequest{URL: "https://new.jevi.dev/Solver/solve"}, Time: time.Now()})
+	tl.emit(&ProviderResponseEvent{Provider: "jevi", Mode: SolveABCK, Attempt: 1, Response: tlsResponse(200, "req-2"), Time: time.Now()})
+	tl.Start(TimelineSensorPost, "")
+	tl.Record(TimelineSensorPost, "", started, tlsResponse(201, "req-3", Cookie{Name: "bm_sz", Value: "1"}, Cookie{Name: "_abck", Value: "valid"}), nil)
+	tl.PostResult(TimelineSensorPost, 201, true)
+	tl.Start(TimelineSensorPost, "")
+	tl.Record(TimelineSensorPost, "", started, tlsResponse(201, "req-4", Cookie{Name: "_abck", Value: "valid"}), nil)
+	tl.Record(TimelineSensorPost, "", started, nil, NewErrorWithStatus(PhaseTLSAPI, "request", 502, errors.New("bad gateway")))
+
+	// Every handler sees each event, in registration order, before the next
+	// event is dispatched
+	var want []string
+	for _, e := range []string{
+		"start HOMEPAGE homepage  #1",
+		`end HOMEPAGE homepage #1 status=200 id=req-1 err=""`,
+		"cookies homepage bm_sz=1,_abck=pending",
+		"provider request jevi abck #1 https://new.jevi.dev/Solver/solve",
+		"provider response jevi abck #1 status=200 err=<nil>",
+		"start SENSOR_POST sensor_post  #1",
+		`end SENSOR_POST sensor_post #1 status=201 id=req-3 err=""`,
+		"cookies sensor_post _abck=valid", // bm_sz kept its value
+		"post sensor_post #1 status=201 accepted=true",
+		"start SENSOR_POST sensor_post  #2",
+		`end SENSOR_POST sensor_post #2 status=201 id=req-4 err=""`, // nothing changed: no cookies event
+		`end SENSOR_POST sensor_post #3 status=502 id= err="[TLS_API] request (status=502): bad gateway"`,
+	} {
+		want = append(want, "a "+e, "b "+e)
+	}
+	if !reflect.DeepEqual(log.lines, want) {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(log.lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The progress function gets everything but the provider events
+	var types []string
+	for _, e := range progress {
+		types = append(types, string(e.Type))
+	}
+	wantTypes := []string{
+		"step_started", "step_finished", "cookies_updated",
+		"step_started", "step_finished", "cookies_updated", "post_result",
+		"step_started", "step_finished", "step_finished",
+	}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("progress = %v; want %v", types, wantTypes)
+	}
+	if c := progress[5]; !reflect.DeepEqual(c.Cookies, []string{"_abck"}) || c.Step != TimelineSensorPost {
+		t.Errorf("cookies progress = %+v", c)
+	}
+	if p := progress[6]; p.Accepted == nil || !*p.Accepted || p.Attempt != 1 || p.Status != 201 {
+		t.Errorf("post progress = %+v", p)
+	}
+	if f := progress[1]; f.RequestID != "req-1" || f.Status != 200 || f.Step != TimelineHomepage {
+		t.Errorf("finished progress = %+v", f)
+	}
+
+	// A nil timeline drops everything
+	var none *Timeline
+	none.Start(TimelineHomepage, "")
+	none.Record(TimelineHomepage, "", started, tlsResponse(200, "x"), nil)
+	none.PostResult(TimelineSensorPost, 200, true)
+	none.emit(&PostResultEvent{})
+	if none.Entries() != nil {
+		t.Error("nil timeline recorded entries")
+	}
+}
+
+// TestCookiesChangedPerDomain checks that cookies of the same name on other
+// domains are tracked apart
+func TestCookiesChangedPerDomain(t *testing.T) {
+	var log eventLog
+	tl := &Timeline{}
+	tl.AddEventHandler(log.handler("h"))
+	started := time.Now()
+	tl.Record(TimelineHomepage, "", started, tlsResponse(200, "", Cookie{Name: "sid", Value: "1", Domain: "a.com"}), nil)
+	tl.Record(TimelineProviderCall, "jevi", started, tlsResponse(200, "", Cookie{Name: "sid", Value: "1", Domain: "new.jevi.dev"}), nil)
+	tl.Record(TimelineHomepage, "", started, tlsResponse(200, "", Cookie{Name: "sid", Value: "1", Domain: "a.com"}), nil)
+
+	var cookies []string
+	for _, l := range log.lines {
+		if strings.HasPrefix(l, "h cookies") {
+			cookies = append(cookies, l)
+		}
+	}
+	if want := []string{"h cookies homepage sid=1", "h cookies provider_call sid=1"}; !reflect.DeepEqual(cookies, want) {
+		t.Errorf("cookie events = %v; want %v", cookies, want)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !unix
+
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	lockPollInterval = 10 * time.Millisecond
+	lockTimeout      = 10 * time.Second
+	lockStaleAfter   = 30 * time.Second
+)
+
+// lockFile takes an exclusive lock by creating path+".lock". A lock file
+// older than lockStaleAfter is assumed to belong to a dead process.
+func lockFile(path string) (unlock func(), err error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, serr := os.Stat(lockPath); serr == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for lock %s", lockPath)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
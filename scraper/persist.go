@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultSaveDelay is how long the statistics and usage stores wait before
+// writing their file, so a burst of solves costs a single write
+const DefaultSaveDelay = 2 * time.Second
+
+// debouncedFile writes a store's snapshot to path at most once per delay.
+// snapshot takes the store's own lock; the file is written without it.
+// Stores shared by several processes set save instead, to merge with the file.
+type debouncedFile struct {
+	path     string
+	delay    time.Duration
+	snapshot func() ([]byte, error)
+	save     func(path string) error // Replaces snapshot + writeFileAtomic when set
+
+	mu    sync.Mutex // guards timer and dirty
+	timer *time.Timer
+	dirty bool
+
+	writeMu sync.Mutex // serializes writes
+}
+
+func newDebouncedFile(path string, snapshot func() ([]byte, error)) *debouncedFile {
+	return &debouncedFile{path: path, delay: DefaultSaveDelay, snapshot: snapshot}
+}
+
+// newMergedFile is newDebouncedFile for stores that merge their changes into
+// the file under lockFile (see UsageTracker.save)
+func newMergedFile(path string, save func(path string) error) *debouncedFile {
+	return &debouncedFile{path: path, delay: DefaultSaveDelay, save: save}
+}
+
+// schedule marks the store changed and writes it after the delay, unless a
+// write is already scheduled
+func (d *debouncedFile) schedule() {
+	if d.path == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dirty = true
+	if d.timer != nil {
+		return
+	}
+	d.timer = time.AfterFunc(d.delay, func() {
+		if err := d.flush(); err != nil {
+			log.Printf("failed to save %s: %v", d.path, err)
+		}
+	})
+}
+
+// flush writes pending changes now and cancels the scheduled write
+func (d *debouncedFile) flush() error {
+	if d.path == "" {
+		return nil
+	}
+	d.mu.Lock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	dirty := d.dirty
+	d.dirty = false
+	d.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	if d.save != nil {
+		return d.save(d.path)
+	}
+	b, err := d.snapshot()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(d.path, b)
+}
+
+// writeFileAtomic replaces path with b through a temporary file
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
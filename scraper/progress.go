@@ -0,0 +1,33 @@
+package scraper
+
+import "time"
+
+// ProgressEventType is the kind of a ProgressEvent
+type ProgressEventType string
+
+const (
+	ProgressStepStarted  ProgressEventType = "step_started"    // A timeline step is about to run
+	ProgressStepFinished ProgressEventType = "step_finished"   // A timeline step ended (see Error)
+	ProgressPostResult   ProgressEventType = "post_result"     // A sensor or SBSD post was accepted or rejected
+	ProgressCookies      ProgressEventType = "cookies_updated" // A response changed cookies
+)
+
+// ProgressEvent reports the progress of a solve while it runs
+type ProgressEvent struct {
+	Type       ProgressEventType `json:"type"`
+	Step       TimelineStep      `json:"step,omitempty"`
+	Provider   string            `json:"provider,omitempty"`
+	Attempt    int               `json:"attempt,omitempty"`
+	Status     int               `json:"status,omitempty"`      // Upstream status (step_finished, post_result)
+	RequestID  string            `json:"request_id,omitempty"`  // TLS-API request ID (step_finished)
+	DurationMs int64             `json:"duration_ms,omitempty"` // step_finished
+	Detail     string            `json:"detail,omitempty"`      // Script URL found (script_url)
+	Accepted   *bool             `json:"accepted,omitempty"`    // post_result
+	Cookies    []string          `json:"cookies,omitempty"`     // Names of the cookies changed (cookies_updated)
+	Error      string            `json:"error,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// ProgressFunc receives progress events. It runs on the solving goroutine,
+// so it should return quickly.
+type ProgressFunc func(ProgressEvent)
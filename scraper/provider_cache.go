@@ -3,32 +3,106 @@ package scraper
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
 
-var providerCacheClearOnce sync.Once
+// DefaultProviderCacheTTL is how long entries live when neither the site
+// profile nor SetDefaultProviderCacheTTL say otherwise
+const DefaultProviderCacheTTL = 24 * time.Hour
 
-type providerCacheEntry struct {
-	ScriptURL  string    `json:"scriptUrl"`
-	Dynamic    string    `json:"dynamic"`
-	ExpiresAt  time.Time `json:"expiresAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
-	Domain     string    `json:"domain"`
-	Provider   string    `json:"provider"`
-	Mode       string    `json:"mode"`
+var (
+	providerCacheTTLMu   sync.RWMutex
+	providerCacheTTL     = DefaultProviderCacheTTL
+	providerCacheEnabled = true
+)
+
+// ProviderCacheEntry is a cached script URL and provider dynamic for one
+// (domain, provider, mode). ScriptHash/ScriptHeadHash fingerprint the script
+// the dynamic was derived from so a rotated script invalidates it.
+type ProviderCacheEntry struct {
+	ScriptURL      string    `json:"scriptUrl"`
+	ScriptHash     string    `json:"scriptHash,omitempty"`
+	ScriptHeadHash string    `json:"scriptHeadHash,omitempty"`
+	Dynamic        string    `json:"dynamic"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	Domain         string    `json:"domain"`
+	Provider       string    `json:"provider"`
+	Mode           string    `json:"mode"`
+}
+
+// ProviderCacheStore is the storage backend behind ProviderCache. Keys are
+// built by cacheKey ("domain|provider|mode"). Implementations must be safe
+// for concurrent use; expiry is handled by ProviderCache.
+type ProviderCacheStore interface {
+	Load(key string) (ProviderCacheEntry, bool, error)
+	Store(key string, entry ProviderCacheEntry) error
+	Delete(key string) error
+	Entries() (map[string]ProviderCacheEntry, error)
+	Close() error
 }
 
+// ProviderCache caches script URLs and provider dynamics on top of a ProviderCacheStore
 type ProviderCache struct {
-	mu      sync.Mutex
-	path    string
-	entries map[string]providerCacheEntry
+	mu    sync.Mutex
+	store ProviderCacheStore
+}
+
+var (
+	defaultProviderCacheStoreMu sync.RWMutex
+	defaultProviderCacheStore   ProviderCacheStore
+	defaultProviderCache        *ProviderCache
+)
+
+// NewProviderCache creates a cache on top of store
+func NewProviderCache(store ProviderCacheStore) *ProviderCache {
+	if store == nil {
+		store = NewFileCacheStore("")
+	}
+	return &ProviderCache{store: store}
+}
+
+// SetDefaultProviderCacheStore makes LoadProviderCacheDefault use store instead
+// of the JSON file (see SetProviderCacheEnabled), and
+// resets the shared cache so the next DefaultProviderCache call uses it
+func SetDefaultProviderCacheStore(store ProviderCacheStore) {
+	defaultProviderCacheStoreMu.Lock()
+	defer defaultProviderCacheStoreMu.Unlock()
+	defaultProviderCacheStore = store
+	defaultProviderCache = nil
 }
 
-func defaultProviderCachePath() string {
+// DefaultProviderCache returns the cache shared by every scraper in the
+// process, opening it with LoadProviderCacheDefault on first use. Admin
+// tooling should inspect this instance so it sees what the solvers use.
+func DefaultProviderCache() *ProviderCache {
+	defaultProviderCacheStoreMu.Lock()
+	defer defaultProviderCacheStoreMu.Unlock()
+	if defaultProviderCache == nil {
+		pc, err := loadProviderCacheDefault(defaultProviderCacheStore)
+		if err != nil {
+			log.Printf("failed to load provider cache: %v", err)
+		}
+		defaultProviderCache = pc
+	}
+	return defaultProviderCache
+}
+
+// SetDefaultProviderCache replaces the cache shared by every scraper
+func SetDefaultProviderCache(pc *ProviderCache) {
+	defaultProviderCacheStoreMu.Lock()
+	defer defaultProviderCacheStoreMu.Unlock()
+	defaultProviderCache = pc
+}
+
+// DefaultProviderCachePath returns the JSON file used by the file backend
+func DefaultProviderCachePath() string {
 	if dir, err := os.UserCacheDir(); err == nil && dir != "" {
 		p := filepath.Join(dir, "reqs")
 		_ = os.MkdirAll(p, 0o700)
@@ -37,101 +111,533 @@ func defaultProviderCachePath() string {
 	return filepath.Join(os.TempDir(), "reqs-provider-cache.json")
 }
 
+// DefaultProviderCacheDir returns the directory holding the provider cache and
+// the files stored next to it (stats, usage)
+func DefaultProviderCacheDir() string {
+	return filepath.Dir(DefaultProviderCachePath())
+}
+
+// SetDefaultProviderCacheTTL changes the TTL used for domains whose site
+// profile has no cacheTtl
+func SetDefaultProviderCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultProviderCacheTTL
+	}
+	providerCacheTTLMu.Lock()
+	defer providerCacheTTLMu.Unlock()
+	providerCacheTTL = ttl
+}
+
+// SetProviderCacheEnabled turns the file backend on or off. When off,
+// LoadProviderCacheDefault returns an in-memory cache and provider stats are
+// not persisted. Stores set with SetDefaultProviderCacheStore are not affected.
+// The shared cache is reset so the next DefaultProviderCache call follows it.
+func SetProviderCacheEnabled(enabled bool) {
+	providerCacheTTLMu.Lock()
+	providerCacheEnabled = enabled
+	providerCacheTTLMu.Unlock()
+
+	defaultProviderCacheStoreMu.Lock()
+	defaultProviderCache = nil
+	defaultProviderCacheStoreMu.Unlock()
+}
+
+// ProviderCacheEnabled reports whether the file backend is on (the default)
+func ProviderCacheEnabled() bool {
+	providerCacheTTLMu.RLock()
+	defer providerCacheTTLMu.RUnlock()
+	return providerCacheEnabled
+}
+
+// ClearProviderCacheFile removes the JSON file of the file backend
+func ClearProviderCacheFile() error {
+	if err := os.Remove(DefaultProviderCachePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// providerCacheTTLFor returns the TTL for domain: site profile first, then the default
+func providerCacheTTLFor(domain string) time.Duration {
+	if p := LookupSiteProfile(domain); p != nil && p.CacheTTLDuration() > 0 {
+		return p.CacheTTLDuration()
+	}
+	providerCacheTTLMu.RLock()
+	defer providerCacheTTLMu.RUnlock()
+	return providerCacheTTL
+}
+
 func cacheKey(domain, provider, mode string) string {
 	return fmt.Sprintf("%s|%s|%s", domain, provider, mode)
 }
 
+// LoadProviderCache creates a cache backed by the JSON file at path
 func LoadProviderCache(path string) (*ProviderCache, error) {
-	pc := &ProviderCache{path: path, entries: map[string]providerCacheEntry{}}
-	b, err := os.ReadFile(path)
+	fs, err := LoadFileCacheStore(path)
+	return NewProviderCache(fs), err
+}
+
+// LoadProviderCacheDefault opens a new cache over the default store (see
+// SetDefaultProviderCacheStore) or the JSON file. Scrapers share the one
+// returned by DefaultProviderCache instead.
+func LoadProviderCacheDefault() (*ProviderCache, error) {
+	defaultProviderCacheStoreMu.RLock()
+	store := defaultProviderCacheStore
+	defaultProviderCacheStoreMu.RUnlock()
+	return loadProviderCacheDefault(store)
+}
+
+func loadProviderCacheDefault(store ProviderCacheStore) (*ProviderCache, error) {
+	if store != nil {
+		return NewProviderCache(store), nil
+	}
+
+	if !ProviderCacheEnabled() {
+		return NewProviderCache(NewFileCacheStore("")), nil
+	}
+	return LoadProviderCache(DefaultProviderCachePath())
+}
+
+// Store returns the backend of the cache
+func (pc *ProviderCache) Store() ProviderCacheStore {
+	return pc.store
+}
+
+// Save flushes file-backed caches to disk; other backends persist on write
+func (pc *ProviderCache) Save() error {
+	if fs, ok := pc.store.(*FileCacheStore); ok {
+		return fs.Save()
+	}
+	return nil
+}
+
+func (pc *ProviderCache) Get(domain, provider, mode string) (ProviderCacheEntry, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	k := cacheKey(domain, provider, mode)
+	v, ok, err := pc.store.Load(k)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return pc, nil
+		log.Printf("provider cache load failed (key=%s): %v", k, err)
+		return ProviderCacheEntry{}, false
+	}
+	if !ok {
+		return ProviderCacheEntry{}, false
+	}
+	if !v.ExpiresAt.IsZero() && time.Now().After(v.ExpiresAt) {
+		_ = pc.store.Delete(k)
+		return ProviderCacheEntry{}, false
+	}
+	return v, true
+}
+
+// Entry returns the stored entry of (domain, provider, mode) as is: expired
+// entries are returned (and kept) so operators can inspect them
+func (pc *ProviderCache) Entry(domain, provider, mode string) (ProviderCacheEntry, bool, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.store.Load(cacheKey(domain, provider, mode))
+}
+
+func (pc *ProviderCache) Upsert(domain, provider, mode string, scriptURL *string, dynamic *string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.updateLocked(domain, provider, mode, func(cur *ProviderCacheEntry) {
+		if scriptURL != nil && *scriptURL != "" {
+			cur.ScriptURL = *scriptURL
+		}
+		if dynamic != nil && *dynamic != "" {
+			cur.Dynamic = *dynamic
 		}
-		return pc, err
+	})
+}
+
+// UpsertDynamic stores a dynamic together with the script it was derived from
+func (pc *ProviderCache) UpsertDynamic(domain, provider, mode, dynamic string, script ScriptVersion) {
+	if dynamic == "" {
+		return
 	}
-	if len(b) == 0 {
-		return pc, nil
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.updateLocked(domain, provider, mode, func(cur *ProviderCacheEntry) {
+		cur.Dynamic = dynamic
+		script.applyTo(cur)
+	})
+}
+
+// TrackScript compares the current script of (domain, mode) with the one each
+// provider's entry was derived from. Entries whose script URL or hash changed
+// lose their dynamic so the provider regenerates it; every entry then records
+// the current version. It returns how many dynamics were invalidated.
+// Only the SupportedProviders keys of domain are read, never the whole store.
+func (pc *ProviderCache) TrackScript(domain, mode string, script ScriptVersion) int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	invalidated := 0
+	for _, provider := range SupportedProviders {
+		k := cacheKey(domain, provider, mode)
+		e, found, err := pc.store.Load(k)
+		if err != nil {
+			log.Printf("provider cache load failed (key=%s): %v", k, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		before := e
+		if reason := script.changeReason(e); reason != "" && e.Dynamic != "" {
+			log.Printf("→ Provider cache dynamic invalidated (key=%s): %s", k, reason)
+			e.Dynamic = ""
+			e.ScriptHash = ""
+			e.ScriptHeadHash = ""
+			invalidated++
+		}
+		script.applyTo(&e)
+		if e == before {
+			continue
+		}
+		if err := pc.store.Store(k, e); err != nil {
+			log.Printf("provider cache store failed (key=%s): %v", k, err)
+		}
 	}
-	var raw map[string]providerCacheEntry
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return pc, nil
+	return invalidated
+}
+
+// updateLocked loads the entry, applies fn and stores it with a fresh TTL
+func (pc *ProviderCache) updateLocked(domain, provider, mode string, fn func(*ProviderCacheEntry)) {
+	k := cacheKey(domain, provider, mode)
+	cur, _, err := pc.store.Load(k)
+	if err != nil {
+		log.Printf("provider cache load failed (key=%s): %v", k, err)
+	}
+	cur.Domain = domain
+	cur.Provider = provider
+	cur.Mode = mode
+	fn(&cur)
+	cur.UpdatedAt = time.Now()
+	cur.ExpiresAt = time.Now().Add(providerCacheTTLFor(domain))
+	if err := pc.store.Store(k, cur); err != nil {
+		log.Printf("provider cache store failed (key=%s): %v", k, err)
 	}
-	pc.entries = raw
-	return pc, nil
 }
 
-func LoadProviderCacheDefault() (*ProviderCache, error) {
-	if os.Getenv("REQS_PROVIDER_CACHE_ENABLE") != "1" || os.Getenv("REQS_PROVIDER_CACHE_DISABLE") == "1" {
-		return &ProviderCache{path: "", entries: map[string]providerCacheEntry{}}, nil
-	}
-	path := defaultProviderCachePath()
-	if os.Getenv("REQS_PROVIDER_CACHE_CLEAR_ON_START") == "1" {
-		providerCacheClearOnce.Do(func() {
-			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-				log.Printf("failed to clear provider cache: %v", err)
+// CacheFilter selects cache entries; empty fields match everything
+type CacheFilter struct {
+	Domain   string
+	Provider string
+	Mode     string
+}
+
+// IsEmpty reports whether the filter matches every entry
+func (f CacheFilter) IsEmpty() bool {
+	return f.Domain == "" && f.Provider == "" && f.Mode == ""
+}
+
+func (f CacheFilter) match(e ProviderCacheEntry) bool {
+	return (f.Domain == "" || e.Domain == f.Domain) &&
+		(f.Provider == "" || e.Provider == f.Provider) &&
+		(f.Mode == "" || e.Mode == f.Mode)
+}
+
+// cacheModes are the modes entries are cached under
+var cacheModes = []string{"sensor", "sbsd"}
+
+// entries returns the entries matching f by key. With a domain the keys of
+// SupportedProviders and cacheModes are loaded one by one; without one the
+// whole store is listed. It does not take pc.mu: stores are safe for
+// concurrent use and callers only read.
+func (pc *ProviderCache) entries(f CacheFilter) (map[string]ProviderCacheEntry, error) {
+	if f.Domain == "" {
+		all, err := pc.store.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for k, e := range all {
+			if !f.match(e) {
+				delete(all, k)
 			}
-		})
+		}
+		return all, nil
+	}
+	providers, modes := SupportedProviders, cacheModes
+	if f.Provider != "" {
+		providers = []string{f.Provider}
+	}
+	if f.Mode != "" {
+		modes = []string{f.Mode}
 	}
-	return LoadProviderCache(path)
+	out := make(map[string]ProviderCacheEntry)
+	for _, p := range providers {
+		for _, m := range modes {
+			k := cacheKey(f.Domain, p, m)
+			e, found, err := pc.store.Load(k)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				out[k] = e
+			}
+		}
+	}
+	return out, nil
 }
 
-func (pc *ProviderCache) Save() error {
+// List returns the entries matching f, sorted by domain, provider and mode.
+// Expired entries are included so operators can see them before they are purged.
+func (pc *ProviderCache) List(f CacheFilter) ([]ProviderCacheEntry, error) {
+	all, err := pc.entries(f)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProviderCacheEntry, 0, len(all))
+	for _, e := range all {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return cacheKey(out[i].Domain, out[i].Provider, out[i].Mode) < cacheKey(out[j].Domain, out[j].Provider, out[j].Mode)
+	})
+	return out, nil
+}
+
+// Invalidate removes the entries matching f and returns how many were removed.
+// An empty filter removes everything.
+func (pc *ProviderCache) Invalidate(f CacheFilter) (int, error) {
+	all, err := pc.entries(f)
+	if err != nil {
+		return 0, err
+	}
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
-	return pc.saveLocked()
+	removed := 0
+	for k := range all {
+		if err := pc.store.Delete(k); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("→ Provider cache invalidated: %d entries (domain=%q provider=%q mode=%q)", removed, f.Domain, f.Provider, f.Mode)
+	}
+	return removed, nil
+}
+
+// ============================================================================
+// File Store
+// ============================================================================
+
+// FileCacheStore keeps all entries in memory backed by a JSON file. Several
+// processes may share the file: writes take a cross-process lock, re-read the
+// file and apply only their own change, and reads reload the file when
+// another process modified it. An empty path keeps the cache in memory only.
+type FileCacheStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]ProviderCacheEntry
+	synced  os.FileInfo // File version entries were last synced with (nil: no file)
+}
+
+// NewFileCacheStore creates an empty file store
+func NewFileCacheStore(path string) *FileCacheStore {
+	return &FileCacheStore{path: path, entries: map[string]ProviderCacheEntry{}}
+}
+
+// LoadFileCacheStore creates a file store and reads the existing file, if any
+func LoadFileCacheStore(path string) (*FileCacheStore, error) {
+	fs := NewFileCacheStore(path)
+	if path == "" {
+		return fs, nil
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.reloadLocked(); err != nil {
+		return fs, err
+	}
+	return fs, nil
+}
+
+func (fs *FileCacheStore) Load(key string) (ProviderCacheEntry, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.refreshLocked()
+	v, ok := fs.entries[key]
+	return v, ok, nil
+}
+
+func (fs *FileCacheStore) Store(key string, entry ProviderCacheEntry) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.writeLocked(func(m map[string]ProviderCacheEntry) bool {
+		m[key] = entry
+		return true
+	})
+}
+
+func (fs *FileCacheStore) Delete(key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.writeLocked(func(m map[string]ProviderCacheEntry) bool {
+		if _, ok := m[key]; !ok {
+			return false
+		}
+		delete(m, key)
+		return true
+	})
+}
+
+func (fs *FileCacheStore) Entries() (map[string]ProviderCacheEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.refreshLocked()
+	out := make(map[string]ProviderCacheEntry, len(fs.entries))
+	for k, v := range fs.entries {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (fs *FileCacheStore) Close() error {
+	return nil
+}
+
+// Save merges the in-memory entries into the file. For keys present on both
+// sides the most recently updated entry wins.
+func (fs *FileCacheStore) Save() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	mine := fs.entries
+	return fs.writeLocked(func(m map[string]ProviderCacheEntry) bool {
+		for k, v := range mine {
+			if cur, ok := m[k]; !ok || !cur.UpdatedAt.After(v.UpdatedAt) {
+				m[k] = v
+			}
+		}
+		return true
+	})
+}
+
+// refreshLocked reloads the file if another process changed it since the
+// last sync. Only the file's metadata is compared (see sameFileVersion), so a
+// read costs a stat, not a parse.
+func (fs *FileCacheStore) refreshLocked() {
+	if fs.path == "" {
+		return
+	}
+	info, err := os.Stat(fs.path)
+	if os.IsNotExist(err) && fs.synced != nil {
+		// Removed by another process (cachectl, clear on start)
+		fs.entries = map[string]ProviderCacheEntry{}
+		fs.syncedLocked(nil)
+		return
+	}
+	if err != nil || sameFileVersion(info, fs.synced) {
+		return
+	}
+	if err := fs.reloadLocked(); err != nil {
+		log.Printf("provider cache reload failed (keeping previous): %v", err)
+	}
 }
 
-func (pc *ProviderCache) saveLocked() error {
-	if pc.path == "" {
+// reloadLocked replaces the in-memory entries with the file contents.
+// A missing file is an empty cache; a corrupt file is ignored.
+func (fs *FileCacheStore) reloadLocked() error {
+	entries, info, err := readCacheFile(fs.path)
+	if err != nil {
+		return err
+	}
+	if entries != nil {
+		fs.entries = entries
+	}
+	fs.syncedLocked(info)
+	return nil
+}
+
+// writeLocked applies change to the current file contents under the
+// cross-process lock and writes the result back. change reports whether it
+// modified the map; unchanged maps are not rewritten.
+func (fs *FileCacheStore) writeLocked(change func(map[string]ProviderCacheEntry) bool) error {
+	if fs.path == "" {
+		change(fs.entries)
 		return nil
 	}
-	if err := os.MkdirAll(filepath.Dir(pc.path), 0o700); err != nil {
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0o700); err != nil {
 		return err
 	}
-	b, err := json.MarshalIndent(pc.entries, "", "  ")
+	unlock, err := lockFile(fs.path)
+	if err != nil {
+		return fmt.Errorf("lock provider cache: %w", err)
+	}
+	defer unlock()
+
+	current, info, err := readCacheFile(fs.path)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = map[string]ProviderCacheEntry{}
+	}
+	if !change(current) {
+		fs.entries = current
+		fs.syncedLocked(info)
+		return nil
+	}
+
+	b, err := json.MarshalIndent(current, "", "  ")
 	if err != nil {
 		return err
 	}
-	tmp := pc.path + ".tmp"
+	tmp := fs.path + ".tmp"
 	if err := os.WriteFile(tmp, b, 0o600); err != nil {
 		return err
 	}
-	return os.Rename(tmp, pc.path)
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return err
+	}
+	fs.entries = current
+	info, _ = os.Stat(fs.path)
+	fs.syncedLocked(info)
+	return nil
 }
 
-func (pc *ProviderCache) Get(domain, provider, mode string) (providerCacheEntry, bool) {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-	k := cacheKey(domain, provider, mode)
-	v, ok := pc.entries[k]
-	if !ok {
-		return providerCacheEntry{}, false
-	}
-	if !v.ExpiresAt.IsZero() && time.Now().After(v.ExpiresAt) {
-		delete(pc.entries, k)
-		_ = pc.saveLocked()
-		return providerCacheEntry{}, false
-	}
-	return v, true
+func (fs *FileCacheStore) syncedLocked(info os.FileInfo) {
+	fs.synced = info
 }
 
-func (pc *ProviderCache) Upsert(domain, provider, mode string, scriptURL *string, dynamic *string) {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-	k := cacheKey(domain, provider, mode)
-	cur, _ := pc.entries[k]
-	cur.Domain = domain
-	cur.Provider = provider
-	cur.Mode = mode
-	if scriptURL != nil && *scriptURL != "" {
-		cur.ScriptURL = *scriptURL
+// sameFileVersion reports whether a and b describe the same version of a
+// file. Writers replace the file with a rename, so each write has a new inode
+// even when size and modification time match. A write that reuses the inode
+// freed by the previous one, with the same size within the filesystem's
+// mtime granularity, still goes unnoticed until the next change.
+func sameFileVersion(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b) &&
+		a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// readCacheFile returns the entries in path and its FileInfo. A missing file
+// returns nil entries and nil info; a corrupt file returns empty entries.
+func readCacheFile(path string) (map[string]ProviderCacheEntry, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer f.Close()
+	// Stat the open file: the version must describe the content read
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
 	}
-	if dynamic != nil && *dynamic != "" {
-		cur.Dynamic = *dynamic
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
 	}
-	cur.UpdatedAt = time.Now()
-	cur.ExpiresAt = time.Now().Add(24 * time.Hour)
-	pc.entries[k] = cur
-	_ = pc.saveLocked()
+	entries := map[string]ProviderCacheEntry{}
+	if len(b) == 0 {
+		return entries, info, nil
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		log.Printf("provider cache file %s is corrupt, ignoring: %v", path, err)
+		return map[string]ProviderCacheEntry{}, info, nil
+	}
+	return entries, info, nil
 }
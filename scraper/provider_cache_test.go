@@ -0,0 +1,170 @@
+package scraper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingStore wraps a FileCacheStore and counts full-store listings
+type countingStore struct {
+	*FileCacheStore
+	entriesCalls int
+}
+
+func (s *countingStore) Entries() (map[string]ProviderCacheEntry, error) {
+	s.entriesCalls++
+	return s.FileCacheStore.Entries()
+}
+
+func TestTrackScriptLoadsDomainKeysOnly(t *testing.T) {
+	store := &countingStore{FileCacheStore: NewFileCacheStore("")}
+	pc := NewProviderCache(store)
+	old := ScriptVersion{URL: "/old.js", Hash: "aaa"}
+	pc.UpsertDynamic("www.example.com", "jevi", "sensor", "dyn-jevi", old)
+	pc.UpsertDynamic("www.example.com", "n4s", "sensor", "dyn-n4s", old)
+	pc.UpsertDynamic("www.example.com", "jevi", "sbsd", "dyn-sbsd", old)
+	pc.UpsertDynamic("other.example.com", "jevi", "sensor", "dyn-other", old)
+
+	if n := pc.TrackScript("www.example.com", "sensor", ScriptVersion{URL: "/new.js"}); n != 2 {
+		t.Fatalf("TrackScript invalidated %d dynamics; want 2", n)
+	}
+	if store.entriesCalls != 0 {
+		t.Fatalf("TrackScript listed the whole store %d times", store.entriesCalls)
+	}
+	for _, c := range []struct {
+		domain, provider, mode, dynamic, url string
+	}{
+		{"www.example.com", "jevi", "sensor", "", "/new.js"},
+		{"www.example.com", "n4s", "sensor", "", "/new.js"},
+		{"www.example.com", "jevi", "sbsd", "dyn-sbsd", "/old.js"},
+		{"other.example.com", "jevi", "sensor", "dyn-other", "/old.js"},
+	} {
+		e, _, _ := pc.Entry(c.domain, c.provider, c.mode)
+		if e.Dynamic != c.dynamic || e.ScriptURL != c.url {
+			t.Errorf("%s|%s|%s = dynamic %q url %q; want %q %q", c.domain, c.provider, c.mode, e.Dynamic, e.ScriptURL, c.dynamic, c.url)
+		}
+	}
+
+	// Same script again: nothing to invalidate
+	if n := pc.TrackScript("www.example.com", "sensor", ScriptVersion{URL: "/new.js"}); n != 0 {
+		t.Fatalf("second TrackScript invalidated %d dynamics; want 0", n)
+	}
+}
+
+func TestListAndInvalidateByDomain(t *testing.T) {
+	store := &countingStore{FileCacheStore: NewFileCacheStore("")}
+	pc := NewProviderCache(store)
+	v := ScriptVersion{URL: "/s.js"}
+	pc.UpsertDynamic("www.example.com", "jevi", "sensor", "a", v)
+	pc.UpsertDynamic("www.example.com", "roolink", "sbsd", "b", v)
+	pc.UpsertDynamic("other.example.com", "jevi", "sensor", "c", v)
+
+	list, err := pc.List(CacheFilter{Domain: "www.example.com"})
+	if err != nil || len(list) != 2 || list[0].Provider != "jevi" || list[1].Provider != "roolink" {
+		t.Fatalf("List(domain) = %+v, %v; want jevi and roolink", list, err)
+	}
+	removed, err := pc.Invalidate(CacheFilter{Domain: "www.example.com", Mode: "sbsd"})
+	if err != nil || removed != 1 {
+		t.Fatalf("Invalidate(domain, sbsd) = %d, %v; want 1", removed, err)
+	}
+	if store.entriesCalls != 0 {
+		t.Fatalf("domain filters listed the whole store %d times", store.entriesCalls)
+	}
+
+	all, err := pc.List(CacheFilter{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("List(all) = %d entries, %v; want 2", len(all), err)
+	}
+	if store.entriesCalls != 1 {
+		t.Fatalf("List without domain listed the store %d times; want 1", store.entriesCalls)
+	}
+}
+
+// TestFileCacheStoreSharedFile runs two stores on one file, as two processes
+// sharing a cache would
+func TestFileCacheStoreSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-cache.json")
+	a, err := LoadFileCacheStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadFileCacheStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(dynamic string, updated time.Time) ProviderCacheEntry {
+		return ProviderCacheEntry{ScriptURL: "/s.js", Dynamic: dynamic, UpdatedAt: updated}
+	}
+
+	// Different keys written by each store both survive
+	if err := a.Store("a.com|jevi|sensor", entry("from-a", t0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Store("b.com|jevi|sensor", entry("from-b", t0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*FileCacheStore{a, b} {
+		if got, _ := s.Entries(); len(got) != 2 {
+			t.Fatalf("Entries = %v; want both keys", got)
+		}
+	}
+
+	// A delete in one store is seen by the other
+	if err := a.Delete("b.com|jevi|sensor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Load("b.com|jevi|sensor"); ok {
+		t.Fatal("entry deleted by a still loaded by b")
+	}
+
+	// Save keeps the newer entry, whichever store holds it
+	if err := b.Store("a.com|jevi|sensor", entry("newer", t0.Add(time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	a.entries["a.com|jevi|sensor"] = entry("stale", t0)
+	a.entries["c.com|jevi|sensor"] = entry("only-a", t0)
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _, _ := b.Load("a.com|jevi|sensor"); e.Dynamic != "newer" {
+		t.Errorf("after Save: %q; want the newer entry", e.Dynamic)
+	}
+	if e, _, _ := b.Load("c.com|jevi|sensor"); e.Dynamic != "only-a" {
+		t.Errorf("entry only a held: %q; want it saved", e.Dynamic)
+	}
+
+	// A rewrite with the same size and modification time is still noticed:
+	// the rename gives the file a new identity
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := a.Entries()
+	e := current["c.com|jevi|sensor"]
+	e.Dynamic = "rewrit"
+	current["c.com|jevi|sensor"] = e
+	raw, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(raw)) != info.Size() {
+		t.Fatalf("rewrite is %d bytes; want %d", len(raw), info.Size())
+	}
+	tmp := path + ".other"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if e, _, _ := b.Load("c.com|jevi|sensor"); e.Dynamic != "rewrit" {
+		t.Errorf("same-size rewrite: %q; want %q", e.Dynamic, "rewrit")
+	}
+}
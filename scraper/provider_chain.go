@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failures that opens a provider circuit
+	DefaultBreakerThreshold = 3
+
+	// DefaultBreakerCooldown is how long an open circuit skips the provider
+	DefaultBreakerCooldown = 60 * time.Second
+)
+
+// SupportedProviders lists the provider names understood by the solvers
+var SupportedProviders = []string{"jevi", "n4s", "roolink"}
+
+// IsSupportedProvider reports whether name is a known provider
+func IsSupportedProvider(name string) bool {
+	for _, p := range SupportedProviders {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildProviderChain returns primary followed by fallbacks, normalized and
+// without duplicates or empty entries
+func BuildProviderChain(primary string, fallbacks []string) []string {
+	seen := make(map[string]bool)
+	chain := make([]string, 0, len(fallbacks)+1)
+	for _, p := range append([]string{primary}, fallbacks...) {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		chain = append(chain, p)
+	}
+	return chain
+}
+
+// ParseProviderChain splits a comma-separated provider list ("jevi,n4s")
+func ParseProviderChain(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return BuildProviderChain("", strings.Split(s, ","))
+}
+
+// breakerState tracks consecutive failures for one provider
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+// ProviderBreaker is a per-provider circuit breaker. After Threshold
+// consecutive failures the provider is skipped for Cooldown. Then the circuit
+// is half-open: one call is let through (the others wait another cooldown)
+// and closes the circuit on success or reopens it on failure.
+type ProviderBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	states    map[string]*breakerState
+	now       func() time.Time
+}
+
+var (
+	defaultProviderBreakerMu sync.RWMutex
+	defaultProviderBreaker   = NewProviderBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown)
+)
+
+// NewProviderBreaker creates a breaker; zero values fall back to the defaults
+func NewProviderBreaker(threshold int, cooldown time.Duration) *ProviderBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &ProviderBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		states:    make(map[string]*breakerState),
+		now:       time.Now,
+	}
+}
+
+// DefaultProviderBreaker returns the breaker shared by all solvers
+func DefaultProviderBreaker() *ProviderBreaker {
+	defaultProviderBreakerMu.RLock()
+	defer defaultProviderBreakerMu.RUnlock()
+	return defaultProviderBreaker
+}
+
+// SetDefaultProviderBreaker replaces the breaker shared by all solvers
+func SetDefaultProviderBreaker(b *ProviderBreaker) {
+	if b == nil {
+		b = NewProviderBreaker(0, 0)
+	}
+	defaultProviderBreakerMu.Lock()
+	defer defaultProviderBreakerMu.Unlock()
+	defaultProviderBreaker = b
+}
+
+// Allow reports whether provider may be called now
+func (b *ProviderBreaker) Allow(provider string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.states[provider]
+	if !ok || st.openUntil.IsZero() {
+		return true
+	}
+	now := b.now()
+	if now.Before(st.openUntil) {
+		return false
+	}
+	// Half-open: this call probes the provider; a probe that never reports
+	// back only delays the next one by a cooldown
+	st.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// RecordSuccess closes the circuit for provider
+func (b *ProviderBreaker) RecordSuccess(provider string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, provider)
+}
+
+// RecordFailure counts a failure and opens the circuit once the threshold is reached
+func (b *ProviderBreaker) RecordFailure(provider string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.states[provider]
+	if !ok {
+		st = &breakerState{}
+		b.states[provider] = st
+	}
+	st.failures++
+	if st.failures >= b.threshold {
+		st.openUntil = b.now().Add(b.cooldown)
+		log.Printf("→ Provider circuit open: %s (failures=%d, cooldown=%s)", provider, st.failures, b.cooldown)
+	}
+}
+
+// stepProviderQuota is the Step of a PhaseQuota error raised by a provider's
+// own budget (see UsageTracker.Reserve)
+const stepProviderQuota = "provider quota exceeded"
+
+// isProviderFailure reports whether err should count against the provider
+// in the breaker
+func isProviderFailure(err error) bool {
+	var se *SolverError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.Phase == PhaseProviderCall && se.Retryable
+}
+
+// canFallBack reports whether the next provider in the chain may succeed
+// where this one failed: a provider failure, or the provider's own budget
+// being exhausted (tenant and domain budgets apply to every provider)
+func canFallBack(err error) bool {
+	var se *SolverError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return isProviderFailure(err) || (se.Phase == PhaseQuota && se.Step == stepProviderQuota)
+}
+
+// providerChain returns the ordered providers to try for the configured mode
+func (c *Config) providerChain(primary string) []string {
+	if len(c.ProviderChain) > 0 {
+		return BuildProviderChain("", c.ProviderChain)
+	}
+	return BuildProviderChain(primary, nil)
+}
@@ -0,0 +1,285 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildProviderChain(t *testing.T) {
+	for _, c := range []struct {
+		primary   string
+		fallbacks []string
+		want      []string
+	}{
+		{"jevi", nil, []string{"jevi"}},
+		{"jevi", []string{"n4s", "roolink"}, []string{"jevi", "n4s", "roolink"}},
+		{" N4S ", []string{"jevi", "n4s", "", "JEVI"}, []string{"n4s", "jevi"}},
+		{"", []string{"roolink", "jevi"}, []string{"roolink", "jevi"}},
+		{"", nil, []string{}},
+	} {
+		if got := BuildProviderChain(c.primary, c.fallbacks); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("BuildProviderChain(%q, %v) = %v; want %v", c.primary, c.fallbacks, got, c.want)
+		}
+	}
+	if got := ParseProviderChain(" jevi, n4s ,,jevi"); !reflect.DeepEqual(got, []string{"jevi", "n4s"}) {
+		t.Errorf("ParseProviderChain = %v", got)
+	}
+	if got := ParseProviderChain("  "); got != nil {
+		t.Errorf("ParseProviderChain(blank) = %v; want nil", got)
+	}
+
+	// An explicit chain replaces the primary provider
+	cfg := &Config{ProviderChain: []string{"n4s", "roolink"}}
+	if got := cfg.providerChain("jevi"); !reflect.DeepEqual(got, []string{"n4s", "roolink"}) {
+		t.Errorf("providerChain with a chain = %v", got)
+	}
+	if got := (&Config{}).providerChain("jevi"); !reflect.DeepEqual(got, []string{"jevi"}) {
+		t.Errorf("providerChain without a chain = %v", got)
+	}
+}
+
+func TestProviderBreakerCycle(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewProviderBreaker(2, time.Minute)
+	b.now = fixedClock(&now)
+
+	// Closed: failures below the threshold keep the provider available
+	b.RecordFailure("jevi")
+	if !b.Allow("jevi") {
+		t.Fatal("circuit open after 1 of 2 failures")
+	}
+	b.RecordFailure("jevi")
+	if b.Allow("jevi") {
+		t.Fatal("circuit closed after 2 of 2 failures")
+	}
+	if !b.Allow("n4s") {
+		t.Fatal("another provider's circuit opened")
+	}
+
+	// Open until the cooldown passes
+	now = now.Add(59 * time.Second)
+	if b.Allow("jevi") {
+		t.Fatal("circuit closed before the cooldown")
+	}
+
+	// Half-open: one probe goes through, concurrent calls wait
+	now = now.Add(2 * time.Second)
+	if !b.Allow("jevi") {
+		t.Fatal("no probe after the cooldown")
+	}
+	if b.Allow("jevi") {
+		t.Fatal("second call let through while the probe runs")
+	}
+
+	// A failed probe reopens the circuit for a full cooldown
+	b.RecordFailure("jevi")
+	now = now.Add(30 * time.Second)
+	if b.Allow("jevi") {
+		t.Fatal("circuit closed after a failed probe")
+	}
+
+	// A successful probe closes it and resets the failure count
+	now = now.Add(time.Minute)
+	if !b.Allow("jevi") {
+		t.Fatal("no probe after the second cooldown")
+	}
+	b.RecordSuccess("jevi")
+	b.RecordFailure("jevi")
+	if !b.Allow("jevi") || !b.Allow("jevi") {
+		t.Fatal("circuit not closed after a successful probe")
+	}
+
+	var none *ProviderBreaker
+	none.RecordFailure("jevi")
+	if !none.Allow("jevi") {
+		t.Fatal("nil breaker rejected a provider")
+	}
+}
+
+func TestCanFallBack(t *testing.T) {
+	providerErr := NewErrorWithProvider(PhaseProviderCall, "sbsd generation", "jevi", errors.New("503"))
+	for _, c := range []struct {
+		name             string
+		err              error
+		fallBack, breaks bool
+	}{
+		{"provider failure", providerErr, true, true},
+		{"wrapped provider failure", fmt.Errorf("solve: %w", providerErr), true, true},
+		{"non-retryable provider failure", NewErrorWithProvider(PhaseProviderCall, "x", "jevi", errors.New("bad key")).WithRetryable(false), false, false},
+		{"provider budget", NewErrorWithProvider(PhaseQuota, stepProviderQuota, "jevi", errors.New("daily")), true, false},
+		{"tenant budget", NewErrorWithProvider(PhaseQuota, "tenant quota exceeded", "jevi", errors.New("daily")), false, false},
+		{"domain budget", NewErrorWithProvider(PhaseQuota, "domain quota exceeded", "jevi", errors.New("daily")), false, false},
+		{"TLS-API", NewError(PhaseTLSAPI, "execute request", errors.New("refused")), false, false},
+		{"plain error", errors.New("boom"), false, false},
+		{"nil", nil, false, false},
+	} {
+		if got := canFallBack(c.err); got != c.fallBack {
+			t.Errorf("%s: canFallBack = %v; want %v", c.name, got, c.fallBack)
+		}
+		if got := isProviderFailure(c.err); got != c.breaks {
+			t.Errorf("%s: isProviderFailure = %v; want %v", c.name, got, c.breaks)
+		}
+	}
+
+	// The usage tracker names provider budgets with stepProviderQuota
+	ut := NewUsageTracker("", UsageBudgets{Providers: map[string]UsageLimit{"jevi": {Daily: 1}}})
+	reserveN(t, ut, 1, "acme", "jevi", "a.com")
+	if err := ut.Reserve("acme", "jevi", "a.com"); !canFallBack(err) {
+		t.Errorf("provider budget error %v does not fall back", err)
+	}
+}
+
+// providerStub is a TLS-API that answers provider calls by host and accepts
+// every SBSD post
+type providerStub struct {
+	mu     sync.Mutex
+	status map[string]int // provider host -> status, 200 by default
+	calls  map[string]int
+}
+
+func newProviderStub(t *testing.T, status map[string]int) (*providerStub, *TLSAPIClient) {
+	t.Helper()
+	ps := &providerStub{status: status, calls: make(map[string]int)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TLSRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		host := strings.Split(strings.TrimPrefix(req.URL, "https://"), "/")[0]
+		ps.mu.Lock()
+		ps.calls[host]++
+		status, ok := ps.status[host]
+		ps.mu.Unlock()
+		if !ok {
+			status = http.StatusOK
+		}
+		body := `{"body":"sbsd-payload"}`
+		if status != http.StatusOK {
+			body = "upstream error"
+		}
+		json.NewEncoder(w).Encode(TLSResponse{Success: true, Data: &TLSResponseData{Status: status, Body: body}})
+	}))
+	t.Cleanup(srv.Close)
+	client := NewTLSAPIClientWithConfig(srv.URL, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}, nil)
+	return ps, client
+}
+
+func (ps *providerStub) callsTo(host string) int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.calls[host]
+}
+
+// withSolverDefaults gives the test its own breaker, stats and usage tracker
+func withSolverDefaults(t *testing.T, breaker *ProviderBreaker, usage *UsageTracker) {
+	t.Helper()
+	prevBreaker, prevStats, prevUsage := DefaultProviderBreaker(), DefaultProviderStats(), DefaultUsageTracker()
+	SetDefaultProviderBreaker(breaker)
+	SetDefaultProviderStats(NewProviderStats(""))
+	SetDefaultUsageTracker(usage)
+	t.Cleanup(func() {
+		SetDefaultProviderBreaker(prevBreaker)
+		SetDefaultProviderStats(prevStats)
+		SetDefaultUsageTracker(prevUsage)
+	})
+}
+
+func newChainSolver(client *TLSAPIClient, chain ...string) *SBSDSolver {
+	cfg := &Config{
+		Domain:        "www.example.com",
+		SensorUrl:     "/abc/def?v=0a1b2c3d",
+		Tenant:        "acme",
+		JeviAPIKey:    "key-1",
+		ProviderChain: chain,
+	}
+	s := NewSBSDSolver(cfg, client, NewCookieJar(), nil, "Mozilla/5.0", "chrome_144", "")
+	s.retry = RetryPolicy{MaxAttempts: 1}
+	return s
+}
+
+const (
+	jeviHost = "new.jevi.dev"
+	n4sHost  = "n4s.xyz"
+)
+
+func TestSBSDSolverFallsBackThroughChain(t *testing.T) {
+	t.Setenv("N4S_API_KEY", "n4s-key")
+
+	t.Run("provider failure", func(t *testing.T) {
+		withSolverDefaults(t, NewProviderBreaker(1, time.Minute), nil)
+		stub, client := newProviderStub(t, map[string]int{jeviHost: http.StatusServiceUnavailable})
+
+		res, err := newChainSolver(client, "jevi", "n4s").Solve("script", "bm_so")
+		if err != nil || !res.Success || res.Session.Provider != "n4s" {
+			t.Fatalf("Solve = %+v, %v; want n4s to answer after jevi failed", res, err)
+		}
+		if stub.callsTo(jeviHost) != 1 || stub.callsTo(n4sHost) != 1 || stub.callsTo("www.example.com") != 1 {
+			t.Errorf("calls = %v", stub.calls)
+		}
+		if DefaultProviderBreaker().Allow("jevi") {
+			t.Error("jevi failure not recorded in the breaker")
+		}
+
+		// With jevi's circuit open the next solve goes straight to n4s
+		if _, err := newChainSolver(client, "jevi", "n4s").Solve("script", "bm_so"); err != nil {
+			t.Fatal(err)
+		}
+		if stub.callsTo(jeviHost) != 1 || stub.callsTo(n4sHost) != 2 {
+			t.Errorf("calls with jevi's circuit open = %v", stub.calls)
+		}
+	})
+
+	t.Run("provider budget", func(t *testing.T) {
+		usage := NewUsageTracker("", UsageBudgets{Providers: map[string]UsageLimit{"jevi": {Daily: 1}}})
+		reserveN(t, usage, 1, "acme", "jevi", "www.example.com")
+		withSolverDefaults(t, NewProviderBreaker(1, time.Minute), usage)
+		stub, client := newProviderStub(t, nil)
+
+		res, err := newChainSolver(client, "jevi", "n4s").Solve("script", "bm_so")
+		if err != nil || res.Session.Provider != "n4s" {
+			t.Fatalf("Solve = %+v, %v; want n4s after jevi's budget ran out", res, err)
+		}
+		if stub.callsTo(jeviHost) != 0 {
+			t.Error("jevi called over its budget")
+		}
+		if !DefaultProviderBreaker().Allow("jevi") {
+			t.Error("an exhausted budget opened jevi's circuit")
+		}
+	})
+
+	t.Run("tenant budget", func(t *testing.T) {
+		usage := NewUsageTracker("", UsageBudgets{Tenants: map[string]UsageLimit{"acme": {Daily: 1}}})
+		reserveN(t, usage, 1, "acme", "jevi", "www.example.com")
+		withSolverDefaults(t, NewProviderBreaker(1, time.Minute), usage)
+		stub, client := newProviderStub(t, nil)
+
+		res, err := newChainSolver(client, "jevi", "n4s").Solve("script", "bm_so")
+		var se *SolverError
+		if !errors.As(err, &se) || se.Phase != PhaseQuota || res.Session.Provider != "jevi" {
+			t.Fatalf("Solve = %+v, %v; want a QUOTA error without fallback", res, err)
+		}
+		if stub.callsTo(n4sHost) != 0 {
+			t.Error("fell back although the tenant budget covers every provider")
+		}
+	})
+
+	t.Run("every provider fails", func(t *testing.T) {
+		withSolverDefaults(t, NewProviderBreaker(5, time.Minute), nil)
+		_, client := newProviderStub(t, map[string]int{jeviHost: http.StatusBadGateway, n4sHost: http.StatusBadGateway})
+
+		res, err := newChainSolver(client, "jevi", "n4s").Solve("script", "bm_so")
+		if err == nil || res.Success || res.Error == nil || res.Error.Phase != PhaseProviderCall || res.Session.Provider != "n4s" {
+			t.Fatalf("Solve = %+v, %v; want the last provider's error", res, err)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// statsAlpha is the EWMA weight given to the newest sample
+	statsAlpha = 0.2
+
+	// DefaultExplorationRate is the share of solves that ignore the ranking
+	DefaultExplorationRate = 0.1
+)
+
+// ProviderStat holds rolling statistics for one (domain, provider, mode)
+type ProviderStat struct {
+	Domain        string    `json:"domain"`
+	Provider      string    `json:"provider"`
+	Mode          string    `json:"mode"`
+	Solves        int64     `json:"solves"`
+	Successes     int64     `json:"successes"`
+	SuccessRate   float64   `json:"success_rate"`    // EWMA of 0/1 outcomes
+	MeanAttempts  float64   `json:"mean_attempts"`   // EWMA of sensor/challenge posts per solve
+	MeanLatencyMs float64   `json:"mean_latency_ms"` // EWMA of solve latency
+	MeanCalls     float64   `json:"mean_calls"`      // EWMA of provider API calls per solve
+	TotalCalls    int64     `json:"total_calls"`     // Provider API calls since tracking started
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SolveOutcome is a single solve sample fed into ProviderStats
+type SolveOutcome struct {
+	Success  bool
+	Attempts int
+	Latency  time.Duration
+	Calls    int // Provider API calls made during the solve
+}
+
+// ProviderStats keeps per-(domain, provider, mode) statistics and ranks
+// providers for adaptive routing
+type ProviderStats struct {
+	mu          sync.Mutex
+	file        *debouncedFile
+	stats       map[string]*ProviderStat
+	adaptive    bool
+	exploration float64
+	rnd         *rand.Rand
+}
+
+var (
+	defaultProviderStatsMu sync.RWMutex
+	defaultProviderStats   = NewProviderStats("")
+)
+
+// NewProviderStats creates an in-memory store persisted to path (if not empty).
+// Writes are debounced by DefaultSaveDelay; call Flush before exiting.
+// Adaptive routing is disabled until SetRouting is called.
+func NewProviderStats(path string) *ProviderStats {
+	ps := &ProviderStats{
+		stats:       make(map[string]*ProviderStat),
+		exploration: DefaultExplorationRate,
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	ps.file = newDebouncedFile(path, ps.marshal)
+	return ps
+}
+
+func defaultProviderStatsPath() string {
+	return filepath.Join(DefaultProviderCacheDir(), "provider-stats.json")
+}
+
+// LoadProviderStats loads persisted statistics from path. A file that cannot
+// be read or parsed is an error, so it is not overwritten with empty stats.
+func LoadProviderStats(path string) (*ProviderStats, error) {
+	ps := NewProviderStats(path)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ps, nil
+		}
+		return nil, fmt.Errorf("read provider stats: %w", err)
+	}
+	if len(b) == 0 {
+		return ps, nil
+	}
+	var raw map[string]*ProviderStat
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse provider stats %s: %w", path, err)
+	}
+	if raw != nil {
+		ps.stats = raw
+	}
+	return ps, nil
+}
+
+// LoadProviderStatsDefault loads statistics stored next to the provider cache
+func LoadProviderStatsDefault() (*ProviderStats, error) {
+	if !ProviderCacheEnabled() {
+		return NewProviderStats(""), nil
+	}
+	return LoadProviderStats(defaultProviderStatsPath())
+}
+
+// Detach stops persisting ps: later solves update it in memory only. The
+// file is a whole snapshot, so a short-lived process (cookiegen) that wrote
+// it would erase what the server recorded in the meantime.
+func (ps *ProviderStats) Detach() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.file = newDebouncedFile("", ps.marshal)
+}
+
+// DefaultProviderStats returns the statistics store shared by all solvers
+func DefaultProviderStats() *ProviderStats {
+	defaultProviderStatsMu.RLock()
+	defer defaultProviderStatsMu.RUnlock()
+	return defaultProviderStats
+}
+
+// SetDefaultProviderStats replaces the statistics store shared by all solvers
+func SetDefaultProviderStats(ps *ProviderStats) {
+	if ps == nil {
+		ps = NewProviderStats("")
+	}
+	defaultProviderStatsMu.Lock()
+	defer defaultProviderStatsMu.Unlock()
+	defaultProviderStats = ps
+}
+
+// SetRouting enables or disables adaptive ordering of provider chains
+func (ps *ProviderStats) SetRouting(adaptive bool, explorationRate float64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.adaptive = adaptive
+	if explorationRate < 0 {
+		explorationRate = 0
+	}
+	if explorationRate > 1 {
+		explorationRate = 1
+	}
+	ps.exploration = explorationRate
+}
+
+// Record adds a solve sample and schedules a write of the statistics
+func (ps *ProviderStats) Record(domain, provider, mode string, o SolveOutcome) {
+	if ps == nil || provider == "" {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	k := cacheKey(domain, provider, mode)
+	st, ok := ps.stats[k]
+	if !ok {
+		st = &ProviderStat{Domain: domain, Provider: provider, Mode: mode}
+		ps.stats[k] = st
+	}
+
+	success := 0.0
+	if o.Success {
+		success = 1
+		st.Successes++
+	}
+	latencyMs := float64(o.Latency.Milliseconds())
+	if st.Solves == 0 {
+		st.SuccessRate = success
+		st.MeanAttempts = float64(o.Attempts)
+		st.MeanLatencyMs = latencyMs
+		st.MeanCalls = float64(o.Calls)
+	} else {
+		st.SuccessRate = ewma(st.SuccessRate, success)
+		st.MeanAttempts = ewma(st.MeanAttempts, float64(o.Attempts))
+		st.MeanLatencyMs = ewma(st.MeanLatencyMs, latencyMs)
+		st.MeanCalls = ewma(st.MeanCalls, float64(o.Calls))
+	}
+	st.Solves++
+	st.TotalCalls += int64(o.Calls)
+	st.UpdatedAt = time.Now()
+	ps.file.schedule()
+}
+
+// Flush writes pending changes to disk now
+func (ps *ProviderStats) Flush() error {
+	if ps == nil {
+		return nil
+	}
+	return ps.file.flush()
+}
+
+// Snapshot returns a copy of all statistics sorted by domain, mode and provider
+func (ps *ProviderStats) Snapshot() []ProviderStat {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make([]ProviderStat, 0, len(ps.stats))
+	for _, st := range ps.stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Domain != out[j].Domain {
+			return out[i].Domain < out[j].Domain
+		}
+		if out[i].Mode != out[j].Mode {
+			return out[i].Mode < out[j].Mode
+		}
+		return out[i].Provider < out[j].Provider
+	})
+	return out
+}
+
+// Order returns chain ranked by current performance for (domain, mode).
+// With probability equal to the exploration rate the chain is shuffled so
+// providers that are behind keep getting samples. When adaptive routing is
+// disabled the chain is returned unchanged.
+func (ps *ProviderStats) Order(domain, mode string, chain []string) []string {
+	if ps == nil || len(chain) < 2 {
+		return chain
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if !ps.adaptive {
+		return chain
+	}
+
+	out := make([]string, len(chain))
+	copy(out, chain)
+
+	if ps.rnd.Float64() < ps.exploration {
+		ps.rnd.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		return out
+	}
+
+	score := make(map[string]float64, len(out))
+	latency := make(map[string]float64, len(out))
+	for _, p := range out {
+		st, ok := ps.stats[cacheKey(domain, p, mode)]
+		if !ok {
+			// Unknown providers get a neutral prior so they are not starved
+			score[p] = 0.5
+			continue
+		}
+		// Blend the EWMA with a neutral prior while there are few samples
+		n := float64(st.Solves)
+		score[p] = (st.SuccessRate*n + 0.5) / (n + 1)
+		latency[p] = st.MeanLatencyMs
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if score[out[i]] != score[out[j]] {
+			return score[out[i]] > score[out[j]]
+		}
+		return latency[out[i]] < latency[out[j]]
+	})
+	return out
+}
+
+// marshal snapshots the statistics for the debounced write
+func (ps *ProviderStats) marshal() ([]byte, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return json.MarshalIndent(ps.stats, "", "  ")
+}
+
+func ewma(prev, sample float64) float64 {
+	return statsAlpha*sample + (1-statsAlpha)*prev
+}
@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestProviderStatsEWMA(t *testing.T) {
+	ps := NewProviderStats("")
+	ps.Record("a.com", "hyper", "abck", SolveOutcome{Success: true, Attempts: 2, Latency: 1000 * time.Millisecond, Calls: 3})
+
+	// The first sample is taken as is
+	st := ps.Snapshot()[0]
+	if st.SuccessRate != 1 || st.MeanAttempts != 2 || st.MeanLatencyMs != 1000 || st.MeanCalls != 3 {
+		t.Fatalf("after one solve: %+v", st)
+	}
+
+	// Later samples weigh statsAlpha
+	ps.Record("a.com", "hyper", "abck", SolveOutcome{Success: false, Attempts: 4, Latency: 2000 * time.Millisecond, Calls: 1})
+	ps.Record("a.com", "hyper", "abck", SolveOutcome{Success: true, Attempts: 1, Latency: 500 * time.Millisecond, Calls: 2})
+	st = ps.Snapshot()[0]
+	want := ProviderStat{
+		SuccessRate:   0.2*1 + 0.8*(0.2*0+0.8*1),
+		MeanAttempts:  0.2*1 + 0.8*(0.2*4+0.8*2),
+		MeanLatencyMs: 0.2*500 + 0.8*(0.2*2000+0.8*1000),
+		MeanCalls:     0.2*2 + 0.8*(0.2*1+0.8*3),
+	}
+	if !approx(st.SuccessRate, want.SuccessRate) || !approx(st.MeanAttempts, want.MeanAttempts) ||
+		!approx(st.MeanLatencyMs, want.MeanLatencyMs) || !approx(st.MeanCalls, want.MeanCalls) {
+		t.Errorf("EWMA = %+v; want %+v", st, want)
+	}
+	if st.Solves != 3 || st.Successes != 2 || st.TotalCalls != 6 || st.UpdatedAt.IsZero() {
+		t.Errorf("counters = %+v", st)
+	}
+
+	// Each (domain, provider, mode) has its own statistics
+	ps.Record("a.com", "hyper", "sbsd", SolveOutcome{Success: false, Calls: 1})
+	ps.Record("b.com", "hyper", "abck", SolveOutcome{Success: false, Calls: 1})
+	if got := ps.Snapshot(); len(got) != 3 || got[0].Mode != "abck" || got[1].Mode != "sbsd" || got[2].Domain != "b.com" {
+		t.Errorf("Snapshot = %+v", got)
+	}
+}
+
+// recordN feeds n solves with the given success ratio and latency
+func recordN(ps *ProviderStats, domain, provider string, n, successes int, latency time.Duration) {
+	for i := 0; i < n; i++ {
+		ps.Record(domain, provider, "abck", SolveOutcome{Success: i < successes, Attempts: 1, Latency: latency, Calls: 1})
+	}
+}
+
+func TestProviderStatsOrder(t *testing.T) {
+	chain := []string{"jevi", "n4s", "roolink", "hyper"}
+	ps := NewProviderStats("")
+	ps.rnd = rand.New(rand.NewSource(1))
+	recordN(ps, "a.com", "jevi", 10, 0, time.Second)              // always failing
+	recordN(ps, "a.com", "n4s", 10, 10, 2*time.Second)            // always succeeding, slow
+	recordN(ps, "a.com", "roolink", 10, 10, 500*time.Millisecond) // always succeeding, fast
+	// hyper has no samples: neutral prior between jevi and the others
+
+	if got := ps.Order("a.com", "abck", chain); !reflect.DeepEqual(got, chain) {
+		t.Errorf("adaptive routing off: %v; want the chain unchanged", got)
+	}
+
+	ps.SetRouting(true, 0)
+	want := []string{"roolink", "n4s", "hyper", "jevi"}
+	if got := ps.Order("a.com", "abck", chain); !reflect.DeepEqual(got, want) {
+		t.Errorf("Order = %v; want %v", got, want)
+	}
+	if !reflect.DeepEqual(chain, []string{"jevi", "n4s", "roolink", "hyper"}) {
+		t.Errorf("Order modified the caller's chain: %v", chain)
+	}
+	// Other domains and modes keep the configured order
+	if got := ps.Order("b.com", "abck", chain); !reflect.DeepEqual(got, chain) {
+		t.Errorf("unknown domain: %v", got)
+	}
+	if got := ps.Order("a.com", "sbsd", chain); !reflect.DeepEqual(got, chain) {
+		t.Errorf("unknown mode: %v", got)
+	}
+
+	// The prior keeps a single failure from burying a provider as deep as a
+	// long run of failures
+	fresh := NewProviderStats("")
+	fresh.SetRouting(true, 0)
+	recordN(fresh, "a.com", "jevi", 1, 0, time.Second)
+	recordN(fresh, "a.com", "n4s", 20, 0, time.Second)
+	if got := fresh.Order("a.com", "abck", []string{"n4s", "jevi", "hyper"}); !reflect.DeepEqual(got, []string{"hyper", "jevi", "n4s"}) {
+		t.Errorf("Order = %v; want hyper, jevi, n4s", got)
+	}
+}
+
+// TestProviderStatsExploration checks that about the exploration rate of the
+// solves ignore the ranking, and that exploring still tries every provider
+func TestProviderStatsExploration(t *testing.T) {
+	chain := []string{"jevi", "n4s", "roolink"}
+	ps := NewProviderStats("")
+	ps.rnd = rand.New(rand.NewSource(42))
+	recordN(ps, "a.com", "roolink", 10, 10, time.Second)
+	recordN(ps, "a.com", "n4s", 10, 5, time.Second)
+	recordN(ps, "a.com", "jevi", 10, 0, time.Second)
+	ps.SetRouting(true, 0.3)
+
+	const runs = 4000
+	notBest := 0
+	firsts := map[string]int{}
+	for i := 0; i < runs; i++ {
+		got := ps.Order("a.com", "abck", chain)
+		if len(got) != len(chain) {
+			t.Fatalf("Order = %v; want a permutation of %v", got, chain)
+		}
+		firsts[got[0]]++
+		if got[0] != "roolink" {
+			notBest++
+		}
+	}
+	// Exploring shuffles the chain, so roolink still comes first a third of the time
+	share := float64(notBest) / runs
+	if want := 0.3 * 2 / 3; math.Abs(share-want) > 0.03 {
+		t.Errorf("%.3f of the solves skipped the best provider; want about %.3f", share, want)
+	}
+	if firsts["jevi"] == 0 || firsts["n4s"] == 0 {
+		t.Errorf("exploration never tried the others first: %v", firsts)
+	}
+
+	// Rates are clamped to [0, 1]
+	ps.SetRouting(true, 5)
+	if ps.exploration != 1 {
+		t.Errorf("exploration = %v; want 1", ps.exploration)
+	}
+	ps.SetRouting(true, -1)
+	if ps.exploration != 0 {
+		t.Errorf("exploration = %v; want 0", ps.exploration)
+	}
+}
+
+func TestProviderStatsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-stats.json")
+	ps, err := LoadProviderStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordN(ps, "a.com", "hyper", 3, 2, time.Second)
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"success_rate"`, `"mean_calls"`, `"total_calls"`, `"updated_at"`} {
+		if !strings.Contains(string(b), key) {
+			t.Errorf("file has no %s key:\n%s", key, b)
+		}
+	}
+
+	loaded, err := LoadProviderStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Snapshot(), ps.Snapshot(); len(got) != 1 || got[0].Solves != 3 || got[0].TotalCalls != want[0].TotalCalls {
+		t.Errorf("loaded = %+v; want %+v", got, want)
+	}
+
+	// A detached store keeps counting in memory but never writes the file
+	loaded.Detach()
+	recordN(loaded, "a.com", "hyper", 5, 5, time.Second)
+	if err := loaded.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(b) {
+		t.Error("detached store rewrote the file")
+	}
+	if got := loaded.Snapshot()[0].Solves; got != 8 {
+		t.Errorf("detached Solves = %d; want 8", got)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProviderStats(path); err == nil {
+		t.Error("corrupt file loaded without error")
+	}
+}
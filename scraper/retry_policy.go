@@ -0,0 +1,351 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultSensorPostLimit is used when neither the request nor the site
+// profile sets SensorPostLimit
+const DefaultSensorPostLimit = 3
+
+// DefaultRetryPhases are the phases whose errors are retried when a policy
+// sets no Phases. Init, script extraction, validation and quota errors are
+// never transient.
+var DefaultRetryPhases = []ErrorPhase{PhaseTLSAPI, PhaseProviderCall, PhaseSensorPost, PhaseSBSDPost}
+
+// DefaultRetryStatuses are the HTTP statuses treated as transient when a
+// policy sets no Statuses
+var DefaultRetryStatuses = []int{408, 429, 500, 502, 503, 504}
+
+// RetryPolicy controls how TLS-API requests, provider calls and SBSD posts
+// are retried and how long sensor post iterations wait between each other.
+// Zero fields inherit from the policy they are merged into.
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts per call, the first one included (1 disables retries)
+	InitialBackoff time.Duration // Wait before the first retry
+	MaxBackoff     time.Duration // Upper bound of a single wait
+	Multiplier     float64       // Growth factor between consecutive waits
+	Jitter         float64       // Random fraction (0-1) added to or removed from each wait
+	Phases         []ErrorPhase  // Phases whose errors may be retried
+	Statuses       []int         // HTTP statuses treated as transient
+}
+
+var (
+	defaultRetryPolicyMu sync.RWMutex
+	defaultRetryPolicy   = RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+)
+
+// DefaultRetryPolicy returns the policy used when neither the request nor
+// the site profile overrides it
+func DefaultRetryPolicy() RetryPolicy {
+	defaultRetryPolicyMu.RLock()
+	defer defaultRetryPolicyMu.RUnlock()
+	return defaultRetryPolicy
+}
+
+// SetDefaultRetryPolicy replaces the default policy. Zero fields keep the
+// current values.
+func SetDefaultRetryPolicy(p RetryPolicy) {
+	defaultRetryPolicyMu.Lock()
+	defer defaultRetryPolicyMu.Unlock()
+	defaultRetryPolicy = defaultRetryPolicy.Merge(&p)
+}
+
+// Merge returns p with the non-zero fields of o applied on top
+func (p RetryPolicy) Merge(o *RetryPolicy) RetryPolicy {
+	if o == nil {
+		return p
+	}
+	if o.MaxAttempts > 0 {
+		p.MaxAttempts = o.MaxAttempts
+	}
+	if o.InitialBackoff > 0 {
+		p.InitialBackoff = o.InitialBackoff
+	}
+	if o.MaxBackoff > 0 {
+		p.MaxBackoff = o.MaxBackoff
+	}
+	if o.Multiplier > 0 {
+		p.Multiplier = o.Multiplier
+	}
+	if o.Jitter > 0 {
+		p.Jitter = o.Jitter
+	}
+	if len(o.Phases) > 0 {
+		p.Phases = o.Phases
+	}
+	if len(o.Statuses) > 0 {
+		p.Statuses = o.Statuses
+	}
+	return p
+}
+
+// Backoff returns the wait before retry n (1 for the first retry)
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	if n < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(n-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		j := math.Min(p.Jitter, 1)
+		d += d * j * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// ShouldRetry reports whether err is transient under p: it must be a
+// SolverError of a retried phase whose status is transient or, when no
+// status is known, that is flagged Retryable
+func (p RetryPolicy) ShouldRetry(err error) bool {
+	var nr *noRetryError
+	if errors.As(err, &nr) {
+		return false
+	}
+	var se *SolverError
+	if !errors.As(err, &se) {
+		return false
+	}
+	phases := p.Phases
+	if len(phases) == 0 {
+		phases = DefaultRetryPhases
+	}
+	if !containsPhase(phases, se.Phase) {
+		return false
+	}
+	if se.StatusCode > 0 {
+		return p.IsRetryableStatus(se.StatusCode)
+	}
+	return se.Retryable
+}
+
+// IsRetryableStatus reports whether status is transient under p
+func (p RetryPolicy) IsRetryableStatus(status int) bool {
+	statuses := p.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Do calls fn until it succeeds, returns an error p does not retry, or
+// MaxAttempts is reached, sleeping Backoff between attempts. It returns the
+// number of attempts made; when ctx is done during a wait it stops with the
+// last error.
+func (p RetryPolicy) Do(ctx context.Context, what string, fn func(attempt int) error) (int, error) {
+	max := p.MaxAttempts
+	if max < 1 {
+		max = 1
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(attempt)
+		if err == nil || attempt >= max || !p.ShouldRetry(err) {
+			return attempt, unwrapNoRetry(err)
+		}
+		wait := p.Backoff(attempt)
+		log.Printf("→ %s failed (attempt %d/%d), retrying in %s: %v", what, attempt, max, wait.Round(time.Millisecond), err)
+		if sleepCtx(ctx, wait) != nil {
+			return attempt, err
+		}
+	}
+}
+
+// sleepCtx waits for d or until ctx is done, returning ctx.Err() in that case
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// noRetryError marks an error that is never retried, whatever its phase or
+// status. Do returns the wrapped error.
+type noRetryError struct{ err error }
+
+func noRetry(err error) error { return &noRetryError{err: err} }
+
+func (e *noRetryError) Error() string { return e.err.Error() }
+func (e *noRetryError) Unwrap() error { return e.err }
+
+func unwrapNoRetry(err error) error {
+	if nr, ok := err.(*noRetryError); ok {
+		return nr.err
+	}
+	return err
+}
+
+func containsPhase(phases []ErrorPhase, phase ErrorPhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// RetrySpec is the JSON form of RetryPolicy used by site profiles and API
+// requests. Durations use Go syntax ("250ms", "2s").
+type RetrySpec struct {
+	MaxAttempts    int      `json:"maxAttempts,omitempty"`
+	InitialBackoff string   `json:"initialBackoff,omitempty"`
+	MaxBackoff     string   `json:"maxBackoff,omitempty"`
+	Multiplier     float64  `json:"multiplier,omitempty"`
+	Jitter         float64  `json:"jitter,omitempty"`
+	Phases         []string `json:"phases,omitempty"`
+	Statuses       []int    `json:"statuses,omitempty"`
+}
+
+// Policy validates the spec and converts it to a RetryPolicy
+func (s *RetrySpec) Policy() (*RetryPolicy, error) {
+	if s == nil {
+		return nil, nil
+	}
+	p := &RetryPolicy{
+		MaxAttempts: s.MaxAttempts,
+		Multiplier:  s.Multiplier,
+		Jitter:      s.Jitter,
+		Statuses:    s.Statuses,
+	}
+	if s.MaxAttempts < 0 || s.MaxAttempts > 10 {
+		return nil, fmt.Errorf("maxAttempts must be between 1 and 10, got %d", s.MaxAttempts)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		out   *time.Duration
+	}{
+		{"initialBackoff", s.InitialBackoff, &p.InitialBackoff},
+		{"maxBackoff", s.MaxBackoff, &p.MaxBackoff},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v <= 0 || v > time.Minute {
+			return nil, fmt.Errorf("%s must be a duration between 1ms and 1m, got %q", d.name, d.value)
+		}
+		*d.out = v
+	}
+	if s.Multiplier < 0 {
+		return nil, fmt.Errorf("multiplier must not be negative, got %g", s.Multiplier)
+	}
+	if s.Jitter < 0 || s.Jitter > 1 {
+		return nil, fmt.Errorf("jitter must be between 0 and 1, got %g", s.Jitter)
+	}
+	for _, name := range s.Phases {
+		phase := ErrorPhase(name)
+		if !containsPhase(DefaultRetryPhases, phase) {
+			return nil, fmt.Errorf("phase %q cannot be retried (allowed: %s, %s, %s, %s)", name, PhaseTLSAPI, PhaseProviderCall, PhaseSensorPost, PhaseSBSDPost)
+		}
+		p.Phases = append(p.Phases, phase)
+	}
+	for _, st := range s.Statuses {
+		if st < 100 || st > 599 {
+			return nil, fmt.Errorf("invalid status %d", st)
+		}
+	}
+	return p, nil
+}
+
+// AttemptStats counts the attempts made by one scraper. Retries are the
+// attempts beyond the first of each call.
+type AttemptStats struct {
+	TLSAPIRequests  int // TLS-API requests sent, retries included
+	TLSAPIRetries   int
+	ProviderCalls   int // Provider API calls, retries included
+	ProviderRetries int
+	SensorPosts     int // Sensor posts (one per SensorPostLimit iteration)
+	SBSDPosts       int // SBSD challenge posts, retries included
+	SBSDPostRetries int
+}
+
+// Retries returns the total number of retries
+func (a AttemptStats) Retries() int {
+	return a.TLSAPIRetries + a.ProviderRetries + a.SBSDPostRetries
+}
+
+// forPhase restricts p to errors of phase. Retries are disabled when p does
+// not retry that phase, so nested calls never multiply their attempts.
+func (p RetryPolicy) forPhase(phase ErrorPhase) RetryPolicy {
+	phases := p.Phases
+	if len(phases) == 0 {
+		phases = DefaultRetryPhases
+	}
+	if !containsPhase(phases, phase) {
+		p.MaxAttempts = 1
+	}
+	p.Phases = []ErrorPhase{phase}
+	return p
+}
+
+// countProviderCall counts a provider call once it was dispatched (after its
+// budget was reserved); attempts beyond the first are retries
+func (a *AttemptStats) countProviderCall(attempt int) {
+	if a == nil {
+		return
+	}
+	a.ProviderCalls++
+	if attempt > 1 {
+		a.ProviderRetries++
+	}
+}
+
+// retryProviderCall runs call under p, retrying when the provider answers
+// with a transient status. call counts the attempt in the stats itself, once
+// the call is dispatched. TLS-API errors were already retried by the client
+// and are returned as-is; once retries run out the last response is
+// returned so the caller reports the status as before.
+func retryProviderCall(ctx context.Context, p RetryPolicy, provider string, call func(attempt int) (*TLSResponse, error)) (*TLSResponse, error) {
+	p = p.forPhase(PhaseProviderCall)
+	var resp *TLSResponse
+	var callErr error
+	_, err := p.Do(ctx, provider+" API call", func(attempt int) error {
+		resp, callErr = call(attempt)
+		if callErr != nil {
+			return callErr
+		}
+		if status := resp.GetStatus(); !resp.IsSuccess() && p.IsRetryableStatus(status) {
+			return NewErrorWithStatus(PhaseProviderCall, "provider transient status", status, fmt.Errorf("%s returned status %d", provider, status))
+		}
+		return nil
+	})
+	if err != nil && callErr == nil {
+		return resp, nil
+	}
+	return resp, err
+}
@@ -0,0 +1,231 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for _, c := range []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 0},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second}, // capped by MaxBackoff
+		{10, time.Second},
+	} {
+		if got := p.Backoff(c.n); got != c.want {
+			t.Errorf("Backoff(%d) = %s; want %s", c.n, got, c.want)
+		}
+	}
+
+	// Multiplier below 1 keeps the wait constant
+	flat := RetryPolicy{InitialBackoff: 50 * time.Millisecond, Multiplier: 0.5}
+	if got := flat.Backoff(3); got != 50*time.Millisecond {
+		t.Errorf("Backoff with multiplier 0.5 = %s; want 50ms", got)
+	}
+	if got := (RetryPolicy{}).Backoff(1); got != 0 {
+		t.Errorf("Backoff without InitialBackoff = %s; want 0", got)
+	}
+
+	// Jitter stays within ±Jitter of the base wait
+	jittered := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := jittered.Backoff(1); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("jittered Backoff = %s; want within 80ms-120ms", got)
+		}
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	def := RetryPolicy{}
+	for _, c := range []struct {
+		name   string
+		policy RetryPolicy
+		err    error
+		want   bool
+	}{
+		{"plain error", def, errors.New("boom"), false},
+		{"retryable tls-api", def, NewError(PhaseTLSAPI, "x", nil).WithRetryable(true), true},
+		{"not retryable", def, NewError(PhaseTLSAPI, "x", nil), false},
+		{"phase not retried", def, NewError(PhaseScriptExtract, "x", nil).WithRetryable(true), false},
+		{"transient status", def, NewErrorWithStatus(PhaseSBSDPost, "x", 503, nil), true},
+		{"permanent status", def, NewErrorWithStatus(PhaseSBSDPost, "x", 403, nil), false},
+		{"status wins over flag", def, NewErrorWithStatus(PhaseSBSDPost, "x", 400, nil).WithRetryable(true), false},
+		{"custom statuses", RetryPolicy{Statuses: []int{403}}, NewErrorWithStatus(PhaseSBSDPost, "x", 403, nil), true},
+		{"custom phases", RetryPolicy{Phases: []ErrorPhase{PhaseProviderCall}}, NewError(PhaseTLSAPI, "x", nil).WithRetryable(true), false},
+		{"noRetry", def, noRetry(NewErrorWithStatus(PhaseTLSAPI, "x", 503, nil)), false},
+	} {
+		if got := c.policy.ShouldRetry(c.err); got != c.want {
+			t.Errorf("%s: ShouldRetry = %v; want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRetryPolicyMerge(t *testing.T) {
+	base := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Phases:         []ErrorPhase{PhaseTLSAPI},
+		Statuses:       []int{503},
+	}
+	if got := base.Merge(nil); got.MaxAttempts != 3 || got.Multiplier != 2 {
+		t.Errorf("Merge(nil) = %+v; want base", got)
+	}
+	got := base.Merge(&RetryPolicy{MaxAttempts: 5, Jitter: 0.5, Statuses: []int{429}})
+	want := base
+	want.MaxAttempts, want.Jitter, want.Statuses = 5, 0.5, []int{429}
+	if got.MaxAttempts != want.MaxAttempts || got.InitialBackoff != want.InitialBackoff ||
+		got.MaxBackoff != want.MaxBackoff || got.Multiplier != want.Multiplier || got.Jitter != want.Jitter ||
+		len(got.Phases) != 1 || got.Phases[0] != PhaseTLSAPI || len(got.Statuses) != 1 || got.Statuses[0] != 429 {
+		t.Errorf("Merge = %+v; want %+v", got, want)
+	}
+}
+
+func TestRetrySpecPolicy(t *testing.T) {
+	if p, err := (*RetrySpec)(nil).Policy(); p != nil || err != nil {
+		t.Fatalf("nil spec = %v, %v; want nil, nil", p, err)
+	}
+	p, err := (&RetrySpec{
+		MaxAttempts:    4,
+		InitialBackoff: "100ms",
+		MaxBackoff:     "2s",
+		Multiplier:     1.5,
+		Jitter:         0.1,
+		Phases:         []string{"TLS_API", "SBSD_POST"},
+		Statuses:       []int{429, 503},
+	}).Policy()
+	if err != nil {
+		t.Fatalf("valid spec: %v", err)
+	}
+	if p.MaxAttempts != 4 || p.InitialBackoff != 100*time.Millisecond || p.MaxBackoff != 2*time.Second ||
+		p.Multiplier != 1.5 || p.Jitter != 0.1 || len(p.Phases) != 2 || p.Phases[1] != PhaseSBSDPost || len(p.Statuses) != 2 {
+		t.Errorf("Policy = %+v", p)
+	}
+
+	for _, c := range []struct {
+		name string
+		spec RetrySpec
+		want string
+	}{
+		{"negative attempts", RetrySpec{MaxAttempts: -1}, "maxAttempts"},
+		{"too many attempts", RetrySpec{MaxAttempts: 11}, "maxAttempts"},
+		{"bad duration", RetrySpec{InitialBackoff: "soon"}, "initialBackoff"},
+		{"zero duration", RetrySpec{MaxBackoff: "0s"}, "maxBackoff"},
+		{"long duration", RetrySpec{MaxBackoff: "2m"}, "maxBackoff"},
+		{"negative multiplier", RetrySpec{Multiplier: -1}, "multiplier"},
+		{"jitter above 1", RetrySpec{Jitter: 1.5}, "jitter"},
+		{"phase not retried", RetrySpec{Phases: []string{"SCRIPT_EXTRACT"}}, "cannot be retried"},
+		{"bad status", RetrySpec{Statuses: []int{42}}, "invalid status"},
+	} {
+		_, err := c.spec.Policy()
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v; want it to mention %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestRetryPolicyDoStopsWhenContextDone(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	retryable := NewError(PhaseTLSAPI, "x", nil).WithRetryable(true)
+
+	done := make(chan struct{})
+	var attempts int
+	var err error
+	go func() {
+		attempts, err = p.Do(ctx, "test", func(int) error { return retryable })
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Do kept sleeping after the context was canceled")
+	}
+	if attempts != 1 || err != retryable {
+		t.Fatalf("Do = %d, %v; want 1 attempt and the last error", attempts, err)
+	}
+}
+
+func TestRetryPolicyDoUnwrapsNoRetry(t *testing.T) {
+	inner := NewErrorWithStatus(PhaseTLSAPI, "x", 503, nil)
+	n, err := RetryPolicy{MaxAttempts: 3}.Do(context.Background(), "test", func(int) error { return noRetry(inner) })
+	if n != 1 || err != inner {
+		t.Fatalf("Do = %d, %v; want 1 attempt and the unwrapped error", n, err)
+	}
+}
+
+// TestTLSAPIClientRetriesOnlyIdempotentRequests checks that a POST the
+// TLS-API may have forwarded is not sent again, while a GET is retried
+func TestTLSAPIClientRetriesOnlyIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method string
+		want   int32
+	}{
+		{"GET", 3},
+		{"POST", 1},
+	} {
+		calls.Store(0)
+		client := NewTLSAPIClientWithConfig(srv.URL, "", time.Second)
+		client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, nil)
+		_, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: c.method})
+		var se *SolverError
+		if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("%s: err = %v; want a SolverError with status 503", c.method, err)
+		}
+		if got := calls.Load(); got != c.want {
+			t.Errorf("%s: TLS-API called %d times; want %d", c.method, got, c.want)
+		}
+	}
+}
+
+func TestTLSAPIClientSiteErrorsRetryOnlyIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(TLSResponse{Error: &TLSAPIError{Type: "timeout", Category: "SITE", Message: "read timeout"}})
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method string
+		want   int32
+	}{
+		{"", 2},
+		{"POST", 1},
+	} {
+		calls.Store(0)
+		client := NewTLSAPIClientWithConfig(srv.URL, "", time.Second)
+		client.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}, nil)
+		if _, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: c.method}); err == nil {
+			t.Fatalf("%q: want an error", c.method)
+		}
+		if got := calls.Load(); got != c.want {
+			t.Errorf("%q: TLS-API called %d times; want %d", c.method, got, c.want)
+		}
+	}
+}
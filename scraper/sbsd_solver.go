@@ -3,8 +3,10 @@ package scraper
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -21,6 +23,15 @@ type SBSDSolver struct {
 	userAgent     string
 	browser       string
 	proxy         string
+	provider      string        // Provider currently being tried
+	providerCalls int           // Provider API calls made by the current attempt
+	quotaErr      error         // Budget error raised by the last provider call
+	retry         RetryPolicy   // Provider call and challenge post retries
+	stats         *AttemptStats // Attempt counters shared with the scraper
+	lastBody      string        // Last SBSD body posted
+	timeline      *Timeline     // Timeline shared with the scraper
+
+	ctx context.Context // Cancels retry waits
 }
 
 // NewSBSDSolver creates a new SBSD solver
@@ -44,13 +55,17 @@ func NewSBSDSolver(
 		userAgent:     userAgent,
 		browser:       browser,
 		proxy:         proxy,
+		retry:         DefaultRetryPolicy(),
+		ctx:           context.Background(),
 	}
 }
 
-// Solve executes the complete SBSD challenge flow
+// Solve executes the complete SBSD challenge flow. Challenge generation falls
+// back to the next provider in the chain on retryable provider failures.
 func (s *SBSDSolver) Solve(script string, bmSo string) (*SBSDResult, error) {
 	provider := GetEffectiveSbSdProvider(s.config.SbSdProvider, s.config.AkamaiProvider)
-	log.Printf("→ Starting SBSD solve flow (provider=%s)", provider)
+	chain := s.config.providerChain(provider)
+	log.Printf("→ Starting SBSD solve flow (providers=%s)", strings.Join(chain, ","))
 
 	result := &SBSDResult{
 		Session: SessionInfo{
@@ -63,34 +78,83 @@ func (s *SBSDSolver) Solve(script string, bmSo string) (*SBSDResult, error) {
 		},
 	}
 
+	stats := DefaultProviderStats()
+	chain = stats.Order(s.config.Domain, "sbsd", chain)
+
+	breaker := DefaultProviderBreaker()
 	var sbsdBody string
 	var err error
+	var started time.Time
+	attempted := false
 
-	switch provider {
-	case "jevi":
-		sbsdBody, err = s.generateWithJevi(script, bmSo)
-	case "n4s":
-		sbsdBody, err = s.generateWithN4S(script, bmSo)
-	case "roolink":
-		sbsdBody, err = s.generateWithRoolink(bmSo)
-	default:
-		err = NewError(PhaseInit, "invalid provider", fmt.Errorf("unknown SBSD provider: %s", provider))
+	for i, p := range chain {
+		if !breaker.Allow(p) {
+			log.Printf("→ Skipping provider %s (circuit open)", p)
+			continue
+		}
+		attempted = true
+		provider = p
+		result.Session.Provider = p
+
+		s.provider = p
+		s.providerCalls = 0
+		s.quotaErr = nil
+		started = time.Now()
+		sbsdBody, err = s.generateWith(p, script, bmSo)
+		if s.quotaErr != nil {
+			err = s.quotaErr
+		}
+		if err == nil {
+			breaker.RecordSuccess(p)
+			break
+		}
+		// Generation errors wrap the TLS-API error ("request failed: ..."):
+		// only an unwrapped SolverError keeps its own phase
+		if _, ok := err.(*SolverError); !ok {
+			err = NewErrorWithProvider(PhaseProviderCall, "sbsd generation", p, err)
+		}
+		s.recordStats(p, false, 0, started)
+		if !canFallBack(err) {
+			break
+		}
+		if isProviderFailure(err) {
+			breaker.RecordFailure(p)
+		}
+		if i < len(chain)-1 {
+			log.Printf("→ Provider %s failed, falling back: %v", p, err)
+		}
+	}
+
+	if !attempted {
+		err = NewError(PhaseProviderCall, "no provider available", fmt.Errorf("all providers skipped (circuit open): %s", strings.Join(chain, ",")))
 	}
 
 	if err != nil {
 		result.Success = false
-		if solverErr, ok := err.(*SolverError); ok {
+		var solverErr *SolverError
+		if errors.As(err, &solverErr) {
 			result.Error = solverErr
 		} else {
-			result.Error = NewErrorWithProvider(PhaseProviderCall, "sbsd generation", provider, err)
+			result.Error = NewError(PhaseProviderCall, "solve failed", err)
 		}
 		return result, err
 	}
 
-	// Post the SBSD challenge to Akamai
-	if err := s.postChallenge(sbsdBody); err != nil {
+	// Post the SBSD challenge to Akamai, retrying transient statuses
+	posts, err := s.retry.forPhase(PhaseSBSDPost).Do(s.ctx, "SBSD post", func(attempt int) error {
+		if s.stats != nil {
+			s.stats.SBSDPosts++
+			if attempt > 1 {
+				s.stats.SBSDPostRetries++
+			}
+		}
+		return s.postChallenge(sbsdBody)
+	})
+	if err != nil {
+		s.recordStats(provider, false, posts, started)
 		result.Success = false
-		if solverErr, ok := err.(*SolverError); ok {
+		var solverErr *SolverError
+		if errors.As(err, &solverErr) {
 			result.Error = solverErr
 		} else {
 			result.Error = NewError(PhaseSBSDPost, "post challenge", err)
@@ -98,6 +162,7 @@ func (s *SBSDSolver) Solve(script string, bmSo string) (*SBSDResult, error) {
 		return result, err
 	}
 
+	s.recordStats(provider, true, posts, started)
 	result.Success = true
 	result.Cookies = s.cookieJar.GetCookies(s.config.Domain)
 	result.CookieString = s.cookieJar.GetCookieString(s.config.Domain)
@@ -106,6 +171,20 @@ func (s *SBSDSolver) Solve(script string, bmSo string) (*SBSDResult, error) {
 	return result, nil
 }
 
+// generateWith dispatches challenge generation to a single provider
+func (s *SBSDSolver) generateWith(provider, script, bmSo string) (string, error) {
+	switch provider {
+	case "jevi":
+		return s.generateWithJevi(script, bmSo)
+	case "n4s":
+		return s.generateWithN4S(script, bmSo)
+	case "roolink":
+		return s.generateWithRoolink(bmSo)
+	default:
+		return "", NewError(PhaseInit, "invalid provider", fmt.Errorf("unknown SBSD provider: %s", provider))
+	}
+}
+
 // postChallenge sends the SBSD body to Akamai
 func (s *SBSDSolver) postChallenge(sbsdBody string) error {
 	log.Printf("→ Posting SBSD challenge to Akamai")
@@ -113,6 +192,7 @@ func (s *SBSDSolver) postChallenge(sbsdBody string) error {
 	challengeURL := fmt.Sprintf("https://%s%s", s.config.Domain, s.config.SensorUrl)
 
 	// SBSD uses JSON body format
+	s.lastBody = sbsdBody
 	payload, _ := json.Marshal(map[string]string{"body": sbsdBody})
 
 	headers := s.buildHeaders()
@@ -128,14 +208,17 @@ func (s *SBSDSolver) postChallenge(sbsdBody string) error {
 		Cookies:       s.cookieJar.ToTLSAPICookies(s.config.Domain),
 		Proxy:         s.proxy,
 		ReturnCookies: true,
+		step:          TimelineSbsdPost,
 	})
 
 	if err != nil {
-		return NewError(PhaseSBSDPost, "send challenge", err)
+		// The TLS-API client already retried the transport
+		return NewError(PhaseSBSDPost, "send challenge", err).WithRetryable(false)
 	}
 
 	// Validate response: must be 200 or 202
 	status := resp.GetStatus()
+	s.timeline.PostResult(TimelineSbsdPost, status, status == 200 || status == 202)
 	if status != 200 && status != 202 {
 		return NewErrorWithStatus(PhaseSBSDPost, "unexpected status", status, fmt.Errorf("expected 200 or 202, got %d: %s", status, resp.GetBody()))
 	}
@@ -177,21 +260,24 @@ func (s *SBSDSolver) generateWithJevi(script string, bmSo string) (string, error
 		return "", fmt.Errorf("could not extract vid from sensor URL")
 	}
 
-	// First attempt without script
+	// Jevi answers from its script cache when it knows the script; only
+	// when it asks for the script is the call repeated with it
 	body, err := s.callJeviSBSD("", bmSo, uuid)
-	if err != nil {
-		// Retry with base64-encoded script
-		log.Printf("→ Jevi SBSD retry with script")
+	if errors.Is(err, errJeviScriptRequired) {
+		log.Printf("→ Jevi SBSD requires script; sending it")
 		base64Script := base64.StdEncoding.EncodeToString([]byte(script))
 		body, err = s.callJeviSBSD(base64Script, bmSo, uuid)
-		if err != nil {
-			return "", err
-		}
+	}
+	if err != nil {
+		return "", err
 	}
 
 	return body, nil
 }
 
+// errJeviScriptRequired is returned when Jevi does not know the script yet
+var errJeviScriptRequired = errors.New("jevi requires script")
+
 func (s *SBSDSolver) callJeviSBSD(script string, bmSo string, uuid string) (string, error) {
 	req := sbsdJeviRequest{
 		Mode: 3,
@@ -222,7 +308,7 @@ func (s *SBSDSolver) callJeviSBSD(script string, bmSo string, uuid string) (stri
 	}
 	userAgentPrefix := strings.Split(apiKey, "-")[0]
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://new.jevi.dev/Solver/solve",
 		Method:  "POST",
 		Browser: s.browser,
@@ -252,7 +338,7 @@ func (s *SBSDSolver) callJeviSBSD(script string, bmSo string, uuid string) (stri
 
 	// Check for errors
 	if resp.GetStatus() == 400 || strings.Contains(body, "Script hash or script content must be provided") || strings.Contains(body, "Error processing SBSD request") {
-		return "", fmt.Errorf("jevi requires script: %s", body)
+		return "", fmt.Errorf("%w: %s", errJeviScriptRequired, body)
 	}
 
 	if !resp.IsSuccess() {
@@ -298,7 +384,7 @@ func (s *SBSDSolver) generateWithN4S(script string, bmSo string) (string, error)
 		return "", fmt.Errorf("N4S_API_KEY not configured")
 	}
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://n4s.xyz/sbsd",
 		Method:  "POST",
 		Browser: s.browser,
@@ -370,7 +456,7 @@ func (s *SBSDSolver) generateWithRoolink(bmSo string) (string, error) {
 
 	jsonData, _ := json.Marshal(req)
 
-	resp, err := s.tlsClient.Request(TLSRequest{
+	resp, err := s.providerRequest(TLSRequest{
 		URL:     "https://www.roolink.io/api/v1/sbsd",
 		Method:  "POST",
 		Browser: s.browser,
@@ -420,6 +506,36 @@ func (s *SBSDSolver) roolinkAPIKey() string {
 // Helper Functions
 // ============================================================================
 
+// providerRequest sends a request to a provider API, counting it as one provider call.
+// Usage budgets are checked before the request is dispatched.
+func (s *SBSDSolver) providerRequest(req TLSRequest) (*TLSResponse, error) {
+	return retryProviderCall(s.ctx, s.retry, s.provider, func(attempt int) (*TLSResponse, error) {
+		if err := DefaultUsageTracker().Reserve(s.config.Tenant, s.provider, s.config.Domain); err != nil {
+			s.quotaErr = err
+			return nil, err
+		}
+		s.stats.countProviderCall(attempt)
+		s.providerCalls++
+		req.step, req.provider = TimelineProviderCall, s.provider
+		s.timeline.emit(&ProviderRequestEvent{Provider: s.provider, Mode: SolveSBSD, Attempt: s.providerCalls, Request: req, Time: time.Now()})
+		started := time.Now()
+		resp, err := s.tlsClient.Request(req)
+		s.timeline.emit(&ProviderResponseEvent{Provider: s.provider, Mode: SolveSBSD, Attempt: s.providerCalls, Response: resp, Duration: time.Since(started), Err: err, Time: time.Now()})
+		return resp, err
+	})
+}
+
+// recordStats feeds the outcome of the current provider attempt into the
+// statistics; posts is the number of challenge posts made (0 when generation failed)
+func (s *SBSDSolver) recordStats(provider string, success bool, posts int, started time.Time) {
+	DefaultProviderStats().Record(s.config.Domain, provider, "sbsd", SolveOutcome{
+		Success:  success,
+		Attempts: posts,
+		Latency:  time.Since(started),
+		Calls:    s.providerCalls,
+	})
+}
+
 func (s *SBSDSolver) extractVidFromSensorURL() string {
 	if strings.Contains(s.config.SensorUrl, "v=") {
 		parts := strings.Split(s.config.SensorUrl, "v=")
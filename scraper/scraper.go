@@ -1,12 +1,10 @@
 package scraper
 
 import (
-	"bytes"
-	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -15,9 +13,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/PuerkitoBio/goquery"
-	"github.com/andybalholm/brotli"
 )
 
 type Config struct {
@@ -31,11 +26,16 @@ type Config struct {
 	EncodedData         string
 	AkamaiProvider      string
 	SbSdProvider        string
+	ProviderChain       []string // Ordered providers tried on retryable provider failures
 	SbSd                bool
 	UserAgent           string
 	SecChUa             string
 	ProfileType         string
 	GenerateReport      bool
+	Tenant              string           // Tenant charged for provider calls (usage accounting)
+	Retry               *RetryPolicy     // Overrides the site profile and default retry policies (zero fields inherit)
+	EventHandler        EventHandler     // Receives the events of the scraper and its solvers (optional)
+	Interceptors        []TLSInterceptor // Wrap every TLS-API request of this scraper, inside the global ones
 	// TLS-API specific fields
 	TLSAPIBrowser string // Browser profile for TLS-API (e.g., "chrome_133")
 	Proxy         string // Proxy URL for TLS-API requests
@@ -104,6 +104,12 @@ type Scraper struct {
 	sbsdSolver   *SBSDSolver
 	browser      string
 	proxy        string
+	profile      *SiteProfile
+	retry        RetryPolicy
+	attempts     AttemptStats
+	timeline     Timeline
+	// Candidates ranked by the last script discovery
+	scriptDiscovery *ScriptDiscovery
 }
 
 func (s *Scraper) HasCachedProviderDynamic() bool {
@@ -138,13 +144,13 @@ func (s *Scraper) providerKeyMode() (provider string, mode string) {
 	return provider, mode
 }
 
-func (s *Scraper) cacheGet() (providerCacheEntry, bool) {
+func (s *Scraper) cacheGet() (ProviderCacheEntry, bool) {
 	if s == nil || s.providerCache == nil || s.config == nil {
-		return providerCacheEntry{}, false
+		return ProviderCacheEntry{}, false
 	}
 	provider, mode := s.providerKeyMode()
 	if provider == "" {
-		return providerCacheEntry{}, false
+		return ProviderCacheEntry{}, false
 	}
 	return s.providerCache.Get(s.config.Domain, provider, mode)
 }
@@ -233,6 +239,18 @@ var defaultUserAgent = UserAgent{
 func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 	log.Printf("→ Using TLS-API client")
 
+	var profile *SiteProfile
+	if config != nil {
+		if profile = LookupSiteProfile(config.Domain); profile != nil {
+			profile.ApplyTo(config)
+			log.Printf("→ Site profile applied: %s", profile.Domain)
+		}
+	}
+
+	if config != nil && config.SensorPostLimit == 0 {
+		config.SensorPostLimit = DefaultSensorPostLimit
+	}
+
 	if config != nil {
 		if lang, ok := languageFromProxy(proxyURL); ok {
 			prev := config.Language
@@ -278,6 +296,9 @@ func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 	if config != nil && config.TLSAPIBrowser != "" {
 		browser = config.TLSAPIBrowser
 	}
+	if err := DefaultTLSAPIProfiles().Check(browser); err != nil {
+		return nil, NewError(PhaseInit, "unsupported browser profile", err).WithRetryable(false)
+	}
 	proxy := ""
 	if config != nil && config.Proxy != "" {
 		proxy = config.Proxy
@@ -289,8 +310,8 @@ func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 	tlsAPIClient := NewTLSAPIClient()
 	cookieJar := NewCookieJar()
 
-	// Load provider cache
-	pc, _ := LoadProviderCacheDefault()
+	// Provider cache shared by every scraper of the process
+	pc := DefaultProviderCache()
 
 	scraper := &Scraper{
 		simpleClient:  simpleClient,
@@ -301,6 +322,19 @@ func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 		cookieJar:     cookieJar,
 		browser:       browser,
 		proxy:         proxy,
+		profile:       profile,
+	}
+
+	// Retry policy: defaults, then the site profile, then the request
+	scraper.retry = DefaultRetryPolicy().Merge(profile.RetryPolicy())
+	if config != nil {
+		scraper.retry = scraper.retry.Merge(config.Retry)
+	}
+	tlsAPIClient.SetRetryPolicy(scraper.retry, &scraper.attempts)
+	tlsAPIClient.SetTimeline(&scraper.timeline)
+	if config != nil {
+		scraper.timeline.AddEventHandler(config.EventHandler)
+		tlsAPIClient.Use(config.Interceptors...)
 	}
 
 	// Initialize report if enabled
@@ -322,6 +356,7 @@ func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 		browser,
 		proxy,
 	)
+	scraper.siteClient.profile = profile
 
 	// Initialize ABCK and SBSD solvers
 	scraper.abckSolver = NewABCKSolver(
@@ -343,9 +378,66 @@ func NewScraper(proxyURL string, config *Config) (*Scraper, error) {
 		proxy,
 	)
 
+	scraper.abckSolver.retry, scraper.abckSolver.stats = scraper.retry, &scraper.attempts
+	scraper.sbsdSolver.retry, scraper.sbsdSolver.stats = scraper.retry, &scraper.attempts
+	scraper.abckSolver.timeline, scraper.sbsdSolver.timeline = &scraper.timeline, &scraper.timeline
+
 	return scraper, nil
 }
 
+// RetryPolicy returns the effective retry policy of this scraper
+func (s *Scraper) RetryPolicy() RetryPolicy {
+	return s.retry
+}
+
+// Attempts returns the attempts and retries made so far
+func (s *Scraper) Attempts() AttemptStats {
+	return s.attempts
+}
+
+// Use adds interceptors to the TLS-API requests of this scraper and its
+// solvers (see TLSInterceptor)
+func (s *Scraper) Use(interceptors ...TLSInterceptor) {
+	s.tlsAPIClient.Use(interceptors...)
+}
+
+// AddEventHandler registers h for the events of this scraper and its solvers
+func (s *Scraper) AddEventHandler(h EventHandler) {
+	s.timeline.AddEventHandler(h)
+}
+
+// SetContext makes TLS-API requests, retry waits and the waits between
+// sensor posts stop when ctx is done
+func (s *Scraper) SetContext(ctx context.Context) {
+	s.tlsAPIClient.SetContext(ctx)
+	if ctx != nil {
+		s.abckSolver.ctx, s.sbsdSolver.ctx = ctx, ctx
+	}
+}
+
+// SetProgress sets the function notified as the steps of this scraper start
+// and finish (fn may be nil)
+func (s *Scraper) SetProgress(fn ProgressFunc) {
+	s.timeline.SetProgress(fn)
+}
+
+// Timeline returns the timed steps recorded so far
+func (s *Scraper) Timeline() []TimelineEntry {
+	return s.timeline.Entries()
+}
+
+// SensorData returns the last sensor data posted, or the last SBSD body when
+// the ABCK flow did not run
+func (s *Scraper) SensorData() string {
+	if s.abckSolver != nil && s.abckSolver.lastSensor != "" {
+		return s.abckSolver.lastSensor
+	}
+	if s.sbsdSolver != nil {
+		return s.sbsdSolver.lastBody
+	}
+	return ""
+}
+
 // GetHomepage fetches the homepage via TLS-API
 func (s *Scraper) GetHomepage() (*SiteResponse, error) {
 	return s.siteClient.GetHomepage("")
@@ -369,87 +461,123 @@ func (s *Scraper) GetAntiBotScript() (string, error) {
 
 	if s.config != nil && !s.config.SbSd && s.HasCachedProviderDynamic() {
 		log.Printf("→ Using cached provider dynamic; _abck script fetch reduced to cookie seed only")
-		if err := s.SeedAbckScriptCookies(); err != nil {
+		resp, err := s.siteClient.SeedScript(urlStr)
+		if err != nil {
 			return "", err
 		}
-		return "", nil
+		// The seed only returns the script head; a changed head means the
+		// cached dynamic belongs to an older script, so fetch it in full
+		if resp.Status < 200 || resp.Status > 299 || s.trackScript(ScriptVersion{URL: s.config.SensorUrl, HeadHash: HashScriptHead(resp.Body)}) == 0 {
+			return "", nil
+		}
+		log.Printf("→ Cached dynamic was stale; fetching full script")
 	}
 
 	// Fetch the script via TLS-API
 	resp, err := s.siteClient.GetScript(urlStr)
 	if err != nil {
-		return "", fmt.Errorf("error fetching script: %v", err)
+		return "", fmt.Errorf("error fetching script: %w", err)
 	}
 
 	log.Printf("→ Script downloaded: status=%d size=%d", resp.Status, len(resp.Body))
 
+	if s.config != nil && !s.config.SbSd && resp.Status >= 200 && resp.Status <= 299 {
+		s.trackScript(NewScriptVersion(s.config.SensorUrl, resp.Body))
+	}
+
 	encoded := base64.StdEncoding.EncodeToString(resp.Body)
 	return encoded, nil
 }
 
+// trackScript invalidates cached dynamics derived from another version of the
+// sensor script and hands v to the ABCK solver for the dynamics it stores
+func (s *Scraper) trackScript(v ScriptVersion) int {
+	if s == nil || s.config == nil || s.config.SbSd {
+		return 0
+	}
+	if s.abckSolver != nil {
+		v.applyToVersion(&s.abckSolver.script)
+	}
+	if s.providerCache == nil {
+		return 0
+	}
+	return s.providerCache.TrackScript(s.config.Domain, "sensor", v)
+}
+
 // GetAntiBotScriptURL fetches the homepage and extracts the anti-bot script URL
 func (s *Scraper) GetAntiBotScriptURL(providedUrl string) (string, error) {
 	log.Printf("→ Getting home page via TLS-API")
 
 	resp, err := s.siteClient.GetHomepage(providedUrl)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch homepage: %v", err)
+		return "", fmt.Errorf("failed to fetch homepage: %w", err)
 	}
 
 	log.Printf("→ Home page fetched: status=%d", resp.Status)
 
 	if resp.Status < 200 || resp.Status > 299 {
-		return "", fmt.Errorf("homepage blocked: status=%d body_preview=%s", resp.Status, string(resp.Body[:min(len(resp.Body), 2048)]))
+		block := ClassifyBlock(resp)
+		log.Printf("→ Homepage blocked: status=%d reason=%s action=%s (%s)", block.Status, block.Reason, block.Action, block.Evidence)
+		return "", &HomepageBlockedError{
+			Domain:         s.config.Domain,
+			Classification: block,
+			BodyPreview:    string(resp.Body[:min(len(resp.Body), 2048)]),
+		}
+	}
+
+	s.timeline.Start(TimelineScriptURL, "")
+	started := time.Now()
+	scriptURL, err := s.resolveScriptURL(resp, providedUrl)
+	s.timeline.RecordLocal(TimelineScriptURL, started, scriptURL, err)
+	return scriptURL, err
+}
+
+// resolveScriptURL picks the anti-bot script URL from a fetched homepage:
+// site profile sensor URL, discovery, then the cached URL
+func (s *Scraper) resolveScriptURL(resp *SiteResponse, providedUrl string) (string, error) {
+	// Site profile with a fixed sensor endpoint skips discovery (ABCK only;
+	// SBSD URLs carry a per-session ?v= parameter)
+	if s.config != nil && !s.config.SbSd && s.profile != nil && s.profile.SensorURL != "" {
+		log.Printf("→ Using site profile sensor URL: %s", s.profile.SensorURL)
+		return s.profile.SensorURL, nil
 	}
 
-	// Check cache for existing script URL
+	// Cached script URL, used when discovery finds nothing. Discovery still
+	// runs so a rotated script is noticed and its dynamics invalidated.
+	cachedURL := ""
 	if s.config != nil && !s.config.SbSd {
 		if entry, ok := s.cacheGet(); ok && entry.ScriptURL != "" {
-			log.Printf("→ Found cached sensor URL: %s", entry.ScriptURL)
-			return entry.ScriptURL, nil
+			cachedURL = entry.ScriptURL
 		}
 	}
 
-	bodyString := string(resp.Body)
-
-	// Parse the response body with goquery
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(bodyString))
+	base, _ := url.Parse(s.profile.HomepageURL(s.config.Domain))
+	if providedUrl != "" {
+		if u, err := url.Parse(providedUrl); err == nil {
+			base = u
+		}
+	}
+	discovery, err := DiscoverScripts(resp.Body, base, s.profile, s.config.SbSd)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse response body with goquery: %v", err)
-	}
-
-	akamaiUrl := ""
-	var candidateUrls []string
-
-	doc.Find("script").Each(func(index int, item *goquery.Selection) {
-		src, exists := item.Attr("src")
-		_, existsDefer := item.Attr("defer")
-
-		if exists {
-			parts := strings.Split(src, "/")
-
-			if s.config.SbSd && strings.Contains(src, "?v=") && len(parts) > 3 {
-				baseUrl := src
-				if strings.Contains(src, "?") {
-					baseUrl = strings.Split(src, "?")[0]
-				}
-				hasExtension := strings.Contains(baseUrl, ".js") || strings.Contains(baseUrl, ".css")
-				if !hasExtension {
-					candidateUrls = append(candidateUrls, src)
-				}
-			} else if !s.config.SbSd && !existsDefer && !strings.Contains(src, "?v=") && len(parts) > 3 {
-				hasExtension := strings.Contains(src, ".js") || strings.Contains(src, ".css")
-				if !hasExtension {
-					candidateUrls = append(candidateUrls, src)
-				}
-			}
+		return "", err
+	}
+	s.scriptDiscovery = discovery
+	for i, c := range discovery.Candidates {
+		if i == 5 {
+			log.Printf("→   ... %d more candidates", len(discovery.Candidates)-i)
+			break
 		}
-	})
+		log.Printf("→   candidate %s score=%d eligible=%v source=%s (%s)", c.URL, c.Score, c.Eligible, c.Source, strings.Join(c.Reasons, ", "))
+	}
 
-	// Select the last matching URL (the dynamic one)
-	if len(candidateUrls) > 0 {
-		akamaiUrl = candidateUrls[len(candidateUrls)-1]
+	if discovery.Chosen == nil {
+		if cachedURL != "" {
+			log.Printf("→ Found cached sensor URL: %s", cachedURL)
+			return cachedURL, nil
+		}
+		return "", &ScriptDiscoveryError{Domain: s.config.Domain, SbSd: s.config.SbSd, Candidates: discovery.Candidates}
 	}
+	akamaiUrl := discovery.Chosen.URL
 
 	if s.config.SbSd {
 		log.Printf("→ Found SbSd URL: %s", akamaiUrl)
@@ -457,13 +585,20 @@ func (s *Scraper) GetAntiBotScriptURL(providedUrl string) (string, error) {
 		log.Printf("→ Found sensor URL: %s", akamaiUrl)
 	}
 
-	if akamaiUrl != "" && s.config != nil && !s.config.SbSd {
+	if !s.config.SbSd {
+		s.trackScript(ScriptVersion{URL: akamaiUrl})
 		s.cacheUpsert(&akamaiUrl, nil)
 	}
 
 	return akamaiUrl, nil
 }
 
+// ScriptDiscovery returns the candidates ranked by the last
+// GetAntiBotScriptURL call, or nil
+func (s *Scraper) ScriptDiscovery() *ScriptDiscovery {
+	return s.scriptDiscovery
+}
+
 // GenerateSession generates the _abck cookie (legacy API, uses TLS-API internally)
 // Deprecated: Use GenerateABCK() instead for full result details
 func (s *Scraper) GenerateSession(script string) (bool, error) {
@@ -578,28 +713,9 @@ func (s *Scraper) formatStdRequestBlock(req *http.Request) string {
 	return b.String()
 }
 
-// ReadBody reads and decompresses response body
+// ReadBody decodes a response body per its Content-Encoding (see DecodeBody)
 func ReadBody(body []byte, contentEncoding string) ([]byte, error) {
-	if len(body) == 0 {
-		return body, nil
-	}
-
-	switch contentEncoding {
-	case "gzip":
-		reader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return body, nil // Already decompressed
-		}
-		defer reader.Close()
-		return io.ReadAll(reader)
-
-	case "br":
-		reader := brotli.NewReader(bytes.NewReader(body))
-		return io.ReadAll(reader)
-
-	default:
-		return body, nil
-	}
+	return DecodeBody(body, contentEncoding)
 }
 
 func min(a, b int) int {
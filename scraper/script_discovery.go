@@ -0,0 +1,308 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ScriptRef is a raw script reference found by a ScriptSource
+type ScriptRef struct {
+	Src   string // src/href as written in the page
+	Defer bool   // Loaded with the defer attribute
+}
+
+// ScriptSource finds script references in a homepage. Sources are tried in
+// registration order; register new ones with RegisterScriptSource.
+type ScriptSource interface {
+	Name() string // Recorded as the candidate source ("script", "preload"...)
+	Find(doc *goquery.Document, profile *SiteProfile) []ScriptRef
+}
+
+// ScriptCandidate is a possible anti-bot script with the score and the
+// reasons that produced it
+type ScriptCandidate struct {
+	URL      string   `json:"url"`    // Same-origin path and query as used by the solvers
+	Source   string   `json:"source"` // Name of the ScriptSource(s) that found it
+	Score    int      `json:"score"`
+	Eligible bool     `json:"eligible"`
+	Reasons  []string `json:"reasons"`
+
+	position int // Order of discovery; later wins ties, as the dynamic script comes last
+}
+
+// ScriptDiscovery is the outcome of DiscoverScripts
+type ScriptDiscovery struct {
+	Chosen     *ScriptCandidate  // Best eligible candidate, nil when none
+	Candidates []ScriptCandidate // All candidates, eligible first, best first
+}
+
+// ScriptDiscoveryError is returned when no candidate is eligible. It carries
+// the ranked candidates so the caller can see what was rejected and why.
+type ScriptDiscoveryError struct {
+	Domain     string
+	SbSd       bool
+	Candidates []ScriptCandidate
+}
+
+func (e *ScriptDiscoveryError) Error() string {
+	kind := "sensor"
+	if e.SbSd {
+		kind = "sbsd"
+	}
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("no %s script candidate found on %s", kind, e.Domain)
+	}
+	best := e.Candidates[0]
+	return fmt.Sprintf("no %s script candidate matched on %s (%d rejected; best %s: %s)",
+		kind, e.Domain, len(e.Candidates), best.URL, strings.Join(best.Reasons, ", "))
+}
+
+var (
+	scriptSourcesMu sync.RWMutex
+	scriptSources   = []ScriptSource{scriptTagSource{}, preloadLinkSource{}, inlineInjectionSource{}}
+)
+
+// RegisterScriptSource adds a source used by every later discovery
+func RegisterScriptSource(src ScriptSource) {
+	scriptSourcesMu.Lock()
+	defer scriptSourcesMu.Unlock()
+	scriptSources = append(scriptSources, src)
+}
+
+// sourceBonus favors references the browser loads directly
+var sourceBonus = map[string]int{"script": 3, "preload": 2, "inline": 1}
+
+// DiscoverScripts collects every script candidate of a homepage and ranks
+// them for the SBSD (sbsd=true) or ABCK flow. base resolves relative URLs and
+// candidates on other hosts are rejected. A site profile scriptPattern alone
+// decides eligibility; scriptSelector narrows the script tags inspected.
+func DiscoverScripts(body []byte, base *url.URL, profile *SiteProfile, sbsd bool) (*ScriptDiscovery, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response body with goquery: %v", err)
+	}
+
+	scriptSourcesMu.RLock()
+	sources := append([]ScriptSource(nil), scriptSources...)
+	scriptSourcesMu.RUnlock()
+
+	byURL := make(map[string]*ScriptCandidate)
+	var order []string
+	position := 0
+	for _, src := range sources {
+		for _, ref := range src.Find(doc, profile) {
+			position++
+			u, reason := resolveScriptURL(ref.Src, base)
+			if u == "" && reason == "" {
+				continue
+			}
+			key := u
+			if key == "" {
+				key = ref.Src
+			}
+			if c, ok := byURL[key]; ok {
+				// Same script from another source: record it and keep the later position
+				if !strings.Contains(c.Source, src.Name()) {
+					c.Source += "+" + src.Name()
+				}
+				c.position = position
+				continue
+			}
+			c := &ScriptCandidate{URL: key, Source: src.Name(), position: position}
+			scoreScript(c, ref, u, reason, profile.ScriptRegexp(), sbsd)
+			byURL[key] = c
+			order = append(order, key)
+		}
+	}
+
+	out := &ScriptDiscovery{Candidates: make([]ScriptCandidate, 0, len(order))}
+	for _, k := range order {
+		out.Candidates = append(out.Candidates, *byURL[k])
+	}
+	sort.SliceStable(out.Candidates, func(i, j int) bool {
+		a, b := out.Candidates[i], out.Candidates[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.position > b.position
+	})
+	if len(out.Candidates) > 0 && out.Candidates[0].Eligible {
+		out.Chosen = &out.Candidates[0]
+	}
+	return out, nil
+}
+
+// resolveScriptURL turns src into a same-origin path with query. It returns
+// a rejection reason for other hosts and ("", "") for refs that are not URLs.
+func resolveScriptURL(src string, base *url.URL) (string, string) {
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "javascript:") || strings.HasPrefix(src, "blob:") {
+		return "", ""
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Host != "" && base != nil && !strings.EqualFold(u.Hostname(), base.Hostname()) {
+		return "", "third-party host " + u.Hostname()
+	}
+	p := u.EscapedPath()
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	return p, ""
+}
+
+// scoreScript applies the eligibility rules and ranking bonuses to c
+func scoreScript(c *ScriptCandidate, ref ScriptRef, resolved, rejected string, pattern *regexp.Regexp, sbsd bool) {
+	reject := func(reason string) {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, reason)
+	}
+	c.Eligible = true
+	if rejected != "" {
+		reject(rejected)
+		return
+	}
+
+	if pattern != nil {
+		if pattern.MatchString(ref.Src) || pattern.MatchString(resolved) {
+			c.Score += 10
+			c.Reasons = append(c.Reasons, "matches site scriptPattern")
+		} else {
+			reject("does not match site scriptPattern")
+		}
+		return
+	}
+
+	p, query, _ := strings.Cut(resolved, "?")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if ext := path.Ext(p); ext != "" {
+		reject("has file extension " + ext)
+	} else {
+		c.Score += 3
+		c.Reasons = append(c.Reasons, "no file extension")
+	}
+	if len(segments) < 3 {
+		reject(fmt.Sprintf("too few path segments (%d)", len(segments)))
+	} else {
+		c.Score += 2
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%d path segments", len(segments)))
+	}
+
+	hasVersion := strings.HasPrefix(query, "v=") || strings.Contains(query, "&v=")
+	switch {
+	case sbsd && !hasVersion:
+		reject("no ?v= parameter (SBSD scripts carry one)")
+	case sbsd:
+		c.Score += 4
+		c.Reasons = append(c.Reasons, "has ?v= parameter")
+	case hasVersion:
+		reject("has ?v= parameter (SBSD script)")
+	}
+	if !sbsd && ref.Defer {
+		reject("deferred (the sensor script is not)")
+	}
+
+	if generatedPath(segments) {
+		c.Score += 2
+		c.Reasons = append(c.Reasons, "generated-looking path")
+	}
+	c.Score += sourceBonus[c.Source]
+}
+
+// generatedPath reports whether some segment mixes case or digits the way
+// Akamai's generated script paths do
+func generatedPath(segments []string) bool {
+	for _, s := range segments {
+		if len(s) < 5 {
+			continue
+		}
+		var upper, lower, digit bool
+		for _, r := range s {
+			switch {
+			case r >= 'A' && r <= 'Z':
+				upper = true
+			case r >= 'a' && r <= 'z':
+				lower = true
+			case r >= '0' && r <= '9':
+				digit = true
+			}
+		}
+		if lower && (upper || digit) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptTagSource finds <script src> elements (scriptSelector overrides "script")
+type scriptTagSource struct{}
+
+func (scriptTagSource) Name() string { return "script" }
+
+func (scriptTagSource) Find(doc *goquery.Document, profile *SiteProfile) []ScriptRef {
+	selector := "script"
+	if profile != nil && profile.ScriptSelector != "" {
+		selector = profile.ScriptSelector
+	}
+	var refs []ScriptRef
+	doc.Find(selector).Each(func(_ int, item *goquery.Selection) {
+		if src, ok := item.Attr("src"); ok {
+			_, deferred := item.Attr("defer")
+			refs = append(refs, ScriptRef{Src: src, Defer: deferred})
+		}
+	})
+	return refs
+}
+
+// preloadLinkSource finds scripts preloaded with <link rel="preload" as="script">
+// or rel="modulepreload"
+type preloadLinkSource struct{}
+
+func (preloadLinkSource) Name() string { return "preload" }
+
+func (preloadLinkSource) Find(doc *goquery.Document, _ *SiteProfile) []ScriptRef {
+	var refs []ScriptRef
+	doc.Find("link[href]").Each(func(_ int, item *goquery.Selection) {
+		rel := strings.ToLower(item.AttrOr("rel", ""))
+		as := strings.ToLower(item.AttrOr("as", ""))
+		if strings.Contains(rel, "modulepreload") || (strings.Contains(rel, "preload") && as == "script") {
+			refs = append(refs, ScriptRef{Src: item.AttrOr("href", "")})
+		}
+	})
+	return refs
+}
+
+// injectedSrcRe matches src assignments of scripts injected by inline code:
+// s.src = "/a/b/c" and s.setAttribute("src", "/a/b/c")
+var injectedSrcRe = regexp.MustCompile(`(?:\.src\s*=\s*|setAttribute\(\s*["']src["']\s*,\s*)["']([^"']+)["']`)
+
+// inlineInjectionSource finds scripts injected by inline <script> code
+type inlineInjectionSource struct{}
+
+func (inlineInjectionSource) Name() string { return "inline" }
+
+func (inlineInjectionSource) Find(doc *goquery.Document, _ *SiteProfile) []ScriptRef {
+	var refs []ScriptRef
+	doc.Find("script:not([src])").Each(func(_ int, item *goquery.Selection) {
+		for _, m := range injectedSrcRe.FindAllStringSubmatch(item.Text(), -1) {
+			refs = append(refs, ScriptRef{Src: m[1]})
+		}
+	})
+	return refs
+}
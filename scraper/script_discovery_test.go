@@ -0,0 +1,242 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// legacyScriptChoice is the selection used before DiscoverScripts: script
+// tags only, more than three "/" parts, no .js/.css, and the last match wins
+func legacyScriptChoice(t *testing.T, body string, sbsd bool) string {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chosen string
+	doc.Find("script").Each(func(_ int, item *goquery.Selection) {
+		src, ok := item.Attr("src")
+		if !ok || len(strings.Split(src, "/")) <= 3 || strings.Contains(src, ".js") || strings.Contains(src, ".css") {
+			return
+		}
+		_, deferred := item.Attr("defer")
+		hasVersion := strings.Contains(src, "?v=")
+		if sbsd == hasVersion && (sbsd || !deferred) {
+			chosen = src
+		}
+	})
+	return chosen
+}
+
+func patternProfile(pattern string) *SiteProfile {
+	return &SiteProfile{ScriptPattern: pattern, scriptRe: regexp.MustCompile(pattern)}
+}
+
+func TestDiscoverScripts(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		base    string // Defaults to https://www.example.com/
+		html    string
+		sbsd    bool
+		profile *SiteProfile
+
+		want       string // Chosen URL, "" when none is eligible
+		wantSource string
+		candidates int
+		rejected   map[string]string // Candidate URL -> expected rejection reason
+		legacy     bool              // The old last-match choice was right and must be kept
+	}{
+		{
+			name: "several extension-less scripts, last wins",
+			html: `<script src="/assets/app.js"></script>
+				<script src="/static/vendor/bundle"></script>
+				<script src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 3,
+			rejected: map[string]string{"/assets/app.js": "has file extension .js"},
+			legacy:   true,
+		},
+		{
+			name: "equal scores keep the later script",
+			html: `<script src="/abc/def/ghi"></script><script src="/jkl/mno/pqr"></script>`,
+			want: "/jkl/mno/pqr", wantSource: "script", candidates: 2, legacy: true,
+		},
+		{
+			name: "absolute same-host url",
+			html: `<script src="https://www.example.com/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 1, legacy: true,
+		},
+		{
+			name: "relative url resolved against the page",
+			base: "https://www.example.com/shop/",
+			html: `<script src="Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/shop/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 1,
+		},
+		{
+			name: "relative and absolute forms are one candidate",
+			html: `<script src="/Ab12cd/Ef34gh/Ij56kl"></script>
+				<script src="https://www.example.com/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 1, legacy: true,
+		},
+		{
+			name: "third-party host rejected",
+			html: `<script src="/Ab12cd/Ef34gh/Ij56kl"></script>
+				<script src="https://cdn.other.com/Xy12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 2,
+			rejected: map[string]string{"https://cdn.other.com/Xy12cd/Ef34gh/Ij56kl": "third-party host cdn.other.com"},
+		},
+		{
+			name: "link rel=preload as=script",
+			html: `<link rel="preload" as="style" href="/Zz12cd/Ef34gh/Ij56kl">
+				<link rel="preload" as="script" href="/Ab12cd/Ef34gh/Ij56kl">`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "preload", candidates: 1,
+		},
+		{
+			name: "preloaded script tag merges sources",
+			html: `<link rel="preload" as="script" href="/Ab12cd/Ef34gh/Ij56kl">
+				<script src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script+preload", candidates: 1, legacy: true,
+		},
+		{
+			name: "script injected with s.src",
+			html: `<script>(function(){var s=document.createElement("script");
+				s.src = "/Ab12cd/Ef34gh/Ij56kl";document.head.appendChild(s);})();</script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "inline", candidates: 1,
+		},
+		{
+			name: "script tag preferred over injected script",
+			html: `<script src="/Qq12cd/Ef34gh/Ij56kl"></script>
+				<script>s.setAttribute("src", "/Ab12cd/Ef34gh/Ij56kl")</script>`,
+			want: "/Qq12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 2, legacy: true,
+		},
+		{
+			name: "deferred script rejected for abck",
+			html: `<script src="/Qq12cd/Ef34gh/Ij56kl"></script>
+				<script defer src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			want: "/Qq12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 2,
+			rejected: map[string]string{"/Ab12cd/Ef34gh/Ij56kl": "deferred (the sensor script is not)"},
+			legacy:   true,
+		},
+		{
+			name: "sbsd requires ?v=",
+			html: `<script src="/Qq12cd/Ef34gh/Ij56kl?v=1a2b-3c4d"></script>
+				<script src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			sbsd: true,
+			want: "/Qq12cd/Ef34gh/Ij56kl?v=1a2b-3c4d", wantSource: "script", candidates: 2,
+			rejected: map[string]string{"/Ab12cd/Ef34gh/Ij56kl": "no ?v= parameter (SBSD scripts carry one)"},
+			legacy:   true,
+		},
+		{
+			name: "abck rejects ?v=",
+			html: `<script src="/Ab12cd/Ef34gh/Ij56kl"></script>
+				<script src="/Qq12cd/Ef34gh/Ij56kl?v=1a2b-3c4d"></script>`,
+			want: "/Ab12cd/Ef34gh/Ij56kl", wantSource: "script", candidates: 2,
+			rejected: map[string]string{"/Qq12cd/Ef34gh/Ij56kl?v=1a2b-3c4d": "has ?v= parameter (SBSD script)"},
+			legacy:   true,
+		},
+		{
+			name: "scriptPattern overrides the heuristics",
+			html: `<script src="/custom/sensor.js"></script>
+				<script src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			profile: patternProfile(`^/custom/sensor\.js$`),
+			want:    "/custom/sensor.js", wantSource: "script", candidates: 2,
+			rejected: map[string]string{"/Ab12cd/Ef34gh/Ij56kl": "does not match site scriptPattern"},
+		},
+		{
+			name: "scriptSelector narrows the script tags",
+			html: `<script data-akamai src="/abc/def/ghi"></script>
+				<script src="/Ab12cd/Ef34gh/Ij56kl"></script>`,
+			profile: &SiteProfile{ScriptSelector: "script[data-akamai]"},
+			want:    "/abc/def/ghi", wantSource: "script", candidates: 1,
+		},
+		{
+			name:       "no eligible candidate",
+			html:       `<script src="/assets/app.js"></script><script src="/a/b"></script>`,
+			candidates: 2,
+			rejected: map[string]string{
+				"/assets/app.js": "has file extension .js",
+				"/a/b":           "too few path segments (2)",
+			},
+			legacy: true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			base := c.base
+			if base == "" {
+				base = "https://www.example.com/"
+			}
+			baseURL, _ := url.Parse(base)
+			got, err := DiscoverScripts([]byte(c.html), baseURL, c.profile, c.sbsd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.want == "" {
+				if got.Chosen != nil {
+					t.Errorf("chose %s; want no eligible candidate", got.Chosen.URL)
+				}
+			} else if got.Chosen == nil {
+				t.Errorf("no candidate chosen; want %s (candidates %+v)", c.want, got.Candidates)
+			} else if got.Chosen.URL != c.want || got.Chosen.Source != c.wantSource {
+				t.Errorf("chose %s from %s; want %s from %s", got.Chosen.URL, got.Chosen.Source, c.want, c.wantSource)
+			}
+			if len(got.Candidates) != c.candidates {
+				t.Errorf("%d candidates; want %d (%+v)", len(got.Candidates), c.candidates, got.Candidates)
+			}
+			for u, reason := range c.rejected {
+				found := false
+				for _, cand := range got.Candidates {
+					if cand.URL != u {
+						continue
+					}
+					found = true
+					if cand.Eligible || !slices.Contains(cand.Reasons, reason) {
+						t.Errorf("%s: eligible=%v reasons %v; want rejected with %q", u, cand.Eligible, cand.Reasons, reason)
+					}
+				}
+				if !found {
+					t.Errorf("%s not among the candidates", u)
+				}
+			}
+
+			if c.legacy {
+				old := legacyScriptChoice(t, c.html, c.sbsd)
+				if old != "" {
+					old, _ = resolveScriptURL(old, baseURL)
+				}
+				if old != c.want {
+					t.Errorf("legacy choice %q differs from %q", old, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreScriptRanking(t *testing.T) {
+	score := func(src string, source string) ScriptCandidate {
+		c := ScriptCandidate{URL: src, Source: source}
+		scoreScript(&c, ScriptRef{Src: src}, src, "", nil, false)
+		return c
+	}
+	generated := score("/Ab12cd/Ef34gh/Ij56kl", "script")
+	plain := score("/abc/def/ghi", "script")
+	if !generated.Eligible || !plain.Eligible || generated.Score <= plain.Score {
+		t.Errorf("generated %+v, plain %+v; want the generated path ranked higher", generated, plain)
+	}
+	for _, lower := range []string{"preload", "inline"} {
+		if c := score("/Ab12cd/Ef34gh/Ij56kl", lower); c.Score >= generated.Score {
+			t.Errorf("%s score %d; want below the script tag's %d", lower, c.Score, generated.Score)
+		}
+	}
+
+	// A third-party rejection skips every other rule
+	c := ScriptCandidate{Source: "script"}
+	scoreScript(&c, ScriptRef{}, "", "third-party host cdn.other.com", nil, false)
+	if c.Eligible || c.Score != 0 || len(c.Reasons) != 1 {
+		t.Errorf("third-party candidate %+v; want rejected with a single reason", c)
+	}
+}
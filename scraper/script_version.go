@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ScriptHeadSize is how much of the script the cookie seed request downloads
+// (Range: bytes=0-1023). Its hash is what can be checked when the full
+// script fetch is skipped because a cached dynamic exists.
+const ScriptHeadSize = 1024
+
+// ScriptVersion identifies the anti-bot script a cached dynamic was derived
+// from. Empty fields are unknown and never compared.
+type ScriptVersion struct {
+	URL      string
+	Hash     string // sha256 of the full script
+	HeadHash string // sha256 of the first ScriptHeadSize bytes
+}
+
+// NewScriptVersion fingerprints a fully downloaded script
+func NewScriptVersion(url string, body []byte) ScriptVersion {
+	return ScriptVersion{URL: url, Hash: hashBytes(body), HeadHash: HashScriptHead(body)}
+}
+
+// HashScriptHead hashes the first ScriptHeadSize bytes of body
+func HashScriptHead(body []byte) string {
+	if len(body) > ScriptHeadSize {
+		body = body[:ScriptHeadSize]
+	}
+	return hashBytes(body)
+}
+
+// changeReason describes why an entry recorded for another script no longer
+// matches v, or returns "" if it still does
+func (v ScriptVersion) changeReason(e ProviderCacheEntry) string {
+	switch {
+	case v.URL != "" && e.ScriptURL != "" && v.URL != e.ScriptURL:
+		return fmt.Sprintf("script URL changed (%s -> %s)", e.ScriptURL, v.URL)
+	case v.Hash != "" && e.ScriptHash != "" && v.Hash != e.ScriptHash:
+		return fmt.Sprintf("script hash changed (%s -> %s)", shortHash(e.ScriptHash), shortHash(v.Hash))
+	case v.HeadHash != "" && e.ScriptHeadHash != "" && v.HeadHash != e.ScriptHeadHash:
+		return fmt.Sprintf("script head hash changed (%s -> %s)", shortHash(e.ScriptHeadHash), shortHash(v.HeadHash))
+	}
+	return ""
+}
+
+// applyTo records the known fields of v on e
+func (v ScriptVersion) applyTo(e *ProviderCacheEntry) {
+	if v.URL != "" {
+		e.ScriptURL = v.URL
+	}
+	if v.Hash != "" {
+		e.ScriptHash = v.Hash
+	}
+	if v.HeadHash != "" {
+		e.ScriptHeadHash = v.HeadHash
+	}
+}
+
+// applyToVersion copies the known fields of v into dst
+func (v ScriptVersion) applyToVersion(dst *ScriptVersion) {
+	if v.URL != "" {
+		dst.URL = v.URL
+	}
+	if v.Hash != "" {
+		dst.Hash = v.Hash
+	}
+	if v.HeadHash != "" {
+		dst.HeadHash = v.HeadHash
+	}
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
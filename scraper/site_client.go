@@ -1,14 +1,9 @@
 package scraper
 
 import (
-	"bytes"
-	"compress/gzip"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-
-	"github.com/andybalholm/brotli"
 )
 
 // SiteClient handles HTTP requests to target sites via TLS-API
@@ -20,6 +15,7 @@ type SiteClient struct {
 	userAgent UserAgent
 	browser   string
 	proxy     string
+	profile   *SiteProfile
 }
 
 // NewSiteClient creates a new site client for making requests to target sites
@@ -55,6 +51,11 @@ type SiteResponse struct {
 
 // Request makes a request to a target site via TLS-API
 func (c *SiteClient) Request(method, url string, body string, customHeaders map[string]string, customHeadersOrder []string) (*SiteResponse, error) {
+	return c.request(TimelineSiteRequest, method, url, body, customHeaders, customHeadersOrder)
+}
+
+// request is Request with the timeline step the request is recorded under
+func (c *SiteClient) request(step TimelineStep, method, url string, body string, customHeaders map[string]string, customHeadersOrder []string) (*SiteResponse, error) {
 	headers, headersOrder := c.buildHeaders(customHeaders, customHeadersOrder)
 
 	req := TLSRequest{
@@ -68,6 +69,7 @@ func (c *SiteClient) Request(method, url string, body string, customHeaders map[
 		Proxy:         c.proxy,
 		ReturnCookies: true,
 		Timeout:       10,
+		step:          step,
 	}
 
 	resp, err := c.tlsClient.Request(req)
@@ -80,8 +82,15 @@ func (c *SiteClient) Request(method, url string, body string, customHeaders map[
 		c.cookieJar.FromTLSAPICookies(c.config.Domain, resp.GetCookies())
 	}
 
-	// Decompress body if needed
-	bodyBytes := c.decompressBody([]byte(resp.GetBody()), resp.GetHeaders())
+	// Decode body per Content-Encoding (cookies above are kept either way)
+	bodyBytes, err := DecodeBody([]byte(resp.GetBody()), headerValue(resp.GetHeaders(), "Content-Encoding"))
+	if err != nil {
+		phase, ok := stepPhases[step]
+		if !ok {
+			phase = PhaseTLSAPI
+		}
+		return nil, NewErrorWithStatus(phase, "decode response body", resp.GetStatus(), err).WithRetryable(false)
+	}
 
 	return &SiteResponse{
 		Status:     resp.GetStatus(),
@@ -97,16 +106,14 @@ func (c *SiteClient) GetHomepage(customURL string) (*SiteResponse, error) {
 	var homeURL string
 	if customURL != "" {
 		homeURL = customURL
-	} else if c.config.Domain == "www.voeazul.com.br" {
-		homeURL = "https://www.voeazul.com.br/br/pt/home"
 	} else {
-		homeURL = fmt.Sprintf("https://%s", c.config.Domain)
+		homeURL = c.profile.HomepageURL(c.config.Domain)
 	}
 
 	log.Printf("→ Fetching homepage via TLS-API: %s", homeURL)
 
 	headers, headersOrder := c.buildHomepageHeaders()
-	return c.Request("GET", homeURL, "", headers, headersOrder)
+	return c.request(TimelineHomepage, "GET", homeURL, "", headers, headersOrder)
 }
 
 // GetScript fetches the anti-bot script from the target site
@@ -131,18 +138,25 @@ func (c *SiteClient) GetScript(scriptURL string) (*SiteResponse, error) {
 		"User-Agent",
 	}
 
-	return c.Request("GET", scriptURL, "", headers, headersOrder)
+	return c.request(TimelineScriptFetch, "GET", scriptURL, "", headers, headersOrder)
 }
 
 // SeedCookies makes a minimal request to seed cookies
 func (c *SiteClient) SeedCookies(url string) error {
+	_, err := c.SeedScript(url)
+	return err
+}
+
+// SeedScript requests the first ScriptHeadSize bytes of the script, which is
+// enough to seed its cookies and fingerprint the script head
+func (c *SiteClient) SeedScript(url string) (*SiteResponse, error) {
 	log.Printf("→ Seeding cookies via TLS-API: %s", url)
 
 	headers := map[string]string{
 		"Accept":          "*/*",
 		"Accept-Encoding": "identity",
 		"Accept-Language": c.config.Language,
-		"Range":           "bytes=0-1023",
+		"Range":           fmt.Sprintf("bytes=0-%d", ScriptHeadSize-1),
 		"Referer":         fmt.Sprintf("https://%s/", c.config.Domain),
 		"User-Agent":      c.userAgent.Full,
 	}
@@ -156,31 +170,30 @@ func (c *SiteClient) SeedCookies(url string) error {
 		"User-Agent",
 	}
 
-	_, err := c.Request("GET", url, "", headers, headersOrder)
-	return err
+	return c.request(TimelineScriptSeed, "GET", url, "", headers, headersOrder)
 }
 
-// buildHeaders creates the headers map and order based on profile type
+// buildHeaders creates the headers map and order for the profile's browser family
 func (c *SiteClient) buildHeaders(custom map[string]string, customOrder []string) (map[string]string, []string) {
 	if custom != nil && len(custom) > 0 {
 		return custom, customOrder
 	}
 
 	// Default sensor POST headers based on profile type
-	switch c.config.ProfileType {
-	case "safari_ios_18_5":
+	switch ProfileFamily(c.config.ProfileType) {
+	case FamilySafari:
 		return c.buildSafariHeaders()
-	case "firefox_135":
+	case FamilyFirefox:
 		return c.buildFirefoxHeaders()
 	default:
 		return c.buildChromeHeaders()
 	}
 }
 
-// buildHomepageHeaders creates headers for homepage requests based on profile type
+// buildHomepageHeaders creates headers for homepage requests for the profile's browser family
 func (c *SiteClient) buildHomepageHeaders() (map[string]string, []string) {
-	switch c.config.ProfileType {
-	case "safari_ios_18_5":
+	switch ProfileFamily(c.config.ProfileType) {
+	case FamilySafari:
 		headers := map[string]string{
 			"sec-fetch-dest":  "document",
 			"user-agent":      "Mozilla/5.0 (iPhone; CPU iPhone OS 18_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Mobile/15E148 Safari/604.1",
@@ -203,7 +216,7 @@ func (c *SiteClient) buildHomepageHeaders() (map[string]string, []string) {
 		}
 		return headers, order
 
-	case "firefox_135":
+	case FamilyFirefox:
 		headers := map[string]string{
 			"user-agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:145.0) Gecko/20100101 Firefox/145.0",
 			"accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
@@ -375,46 +388,6 @@ func (c *SiteClient) buildChromeHeaders() (map[string]string, []string) {
 	return headers, order
 }
 
-// decompressBody decompresses the response body based on Content-Encoding
-func (c *SiteClient) decompressBody(body []byte, headers map[string]string) []byte {
-	if len(body) == 0 {
-		return body
-	}
-
-	encoding := ""
-	if headers != nil {
-		encoding = headers["Content-Encoding"]
-		if encoding == "" {
-			encoding = headers["content-encoding"]
-		}
-	}
-
-	switch encoding {
-	case "gzip":
-		reader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return body // Already decompressed or invalid
-		}
-		defer reader.Close()
-		decompressed, err := io.ReadAll(reader)
-		if err != nil {
-			return body
-		}
-		return decompressed
-
-	case "br":
-		reader := brotli.NewReader(bytes.NewReader(body))
-		decompressed, err := io.ReadAll(reader)
-		if err != nil {
-			return body
-		}
-		return decompressed
-
-	default:
-		return body
-	}
-}
-
 // GetCookieString returns cookies as a string for the configured domain
 func (c *SiteClient) GetCookieString() string {
 	return c.cookieJar.GetCookieString(c.config.Domain)
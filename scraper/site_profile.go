@@ -0,0 +1,288 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SiteProfile holds per-domain overrides that used to be hard-coded in the
+// scraper (homepage path, script heuristics, provider and sensor settings)
+type SiteProfile struct {
+	Domain          string     `json:"domain,omitempty"`
+	HomepagePath    string     `json:"homepagePath,omitempty"`    // Path fetched as homepage (e.g. "/br/pt/home")
+	ScriptSelector  string     `json:"scriptSelector,omitempty"`  // CSS selector for script candidates (default: "script")
+	ScriptPattern   string     `json:"scriptPattern,omitempty"`   // Regex a candidate src must match
+	Provider        string     `json:"provider,omitempty"`        // Preferred provider (jevi, n4s, roolink)
+	SensorPostLimit int        `json:"sensorPostLimit,omitempty"` // Sensor post attempts
+	LowSecurity     *bool      `json:"lowSecurity,omitempty"`     // Relaxed _abck validation (nil: keep the Config value)
+	Language        string     `json:"language,omitempty"`        // Accept-Language
+	SensorURL       string     `json:"sensorUrl,omitempty"`       // Sensor endpoint path
+	CacheTTL        string     `json:"cacheTtl,omitempty"`        // Provider cache TTL (e.g. "6h")
+	Retry           *RetrySpec `json:"retry,omitempty"`           // Retry policy overrides
+
+	scriptRe *regexp.Regexp
+	cacheTTL time.Duration
+	retry    *RetryPolicy
+}
+
+// ScriptRegexp returns the compiled ScriptPattern, or nil if none is set
+func (p *SiteProfile) ScriptRegexp() *regexp.Regexp {
+	if p == nil {
+		return nil
+	}
+	return p.scriptRe
+}
+
+// CacheTTLDuration returns the parsed CacheTTL, or 0 if none is set
+func (p *SiteProfile) CacheTTLDuration() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return p.cacheTTL
+}
+
+// RetryPolicy returns the parsed Retry overrides, or nil if none are set
+func (p *SiteProfile) RetryPolicy() *RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return p.retry
+}
+
+// HomepageURL returns the homepage URL for the profile's domain
+func (p *SiteProfile) HomepageURL(domain string) string {
+	path := ""
+	if p != nil {
+		path = p.HomepagePath
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("https://%s%s", domain, path)
+}
+
+// ApplyTo fills zero-valued Config fields with the profile values.
+// Values explicitly set on the Config always win, except LowSecurity: it
+// describes the site, so a profile that sets it decides either way.
+func (p *SiteProfile) ApplyTo(config *Config) {
+	if p == nil || config == nil {
+		return
+	}
+	if config.AkamaiProvider == "" && p.Provider != "" {
+		config.AkamaiProvider = p.Provider
+	}
+	if config.SensorPostLimit == 0 && p.SensorPostLimit > 0 {
+		config.SensorPostLimit = p.SensorPostLimit
+	}
+	if p.LowSecurity != nil {
+		config.LowSecurity = *p.LowSecurity
+	}
+	if config.Language == "" && p.Language != "" {
+		config.Language = p.Language
+	}
+	if config.SensorUrl == "" && p.SensorURL != "" {
+		config.SensorUrl = p.SensorURL
+	}
+}
+
+// builtinSiteProfiles keeps the behavior that existed before profiles were
+// configurable. Entries loaded from a file override these.
+var builtinSiteProfiles = map[string]SiteProfile{
+	"www.voeazul.com.br": {HomepagePath: "/br/pt/home"},
+}
+
+// SiteProfileStore holds the profiles loaded from a JSON file keyed by domain
+// and reloads them when the file changes
+type SiteProfileStore struct {
+	mu       sync.RWMutex
+	path     string
+	modTime  time.Time
+	profiles map[string]*SiteProfile
+}
+
+var (
+	defaultSiteProfilesMu sync.RWMutex
+	defaultSiteProfiles   = NewSiteProfileStore("")
+)
+
+// NewSiteProfileStore creates a store backed by path. An empty path only
+// serves the built-in profiles.
+func NewSiteProfileStore(path string) *SiteProfileStore {
+	st := &SiteProfileStore{path: path}
+	st.profiles = buildSiteProfiles(nil)
+	return st
+}
+
+// LoadSiteProfiles creates a store and loads path immediately
+func LoadSiteProfiles(path string) (*SiteProfileStore, error) {
+	st := NewSiteProfileStore(path)
+	if path == "" {
+		return st, nil
+	}
+	if err := st.Reload(); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// DefaultSiteProfiles returns the store used by NewScraper
+func DefaultSiteProfiles() *SiteProfileStore {
+	defaultSiteProfilesMu.RLock()
+	defer defaultSiteProfilesMu.RUnlock()
+	return defaultSiteProfiles
+}
+
+// SetDefaultSiteProfiles replaces the store used by NewScraper
+func SetDefaultSiteProfiles(st *SiteProfileStore) {
+	if st == nil {
+		st = NewSiteProfileStore("")
+	}
+	defaultSiteProfilesMu.Lock()
+	defer defaultSiteProfilesMu.Unlock()
+	defaultSiteProfiles = st
+}
+
+// LookupSiteProfile returns the profile for domain from the default store
+func LookupSiteProfile(domain string) *SiteProfile {
+	return DefaultSiteProfiles().Get(domain)
+}
+
+// Get returns the profile for domain, or nil if there is none
+func (st *SiteProfileStore) Get(domain string) *SiteProfile {
+	if st == nil {
+		return nil
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	p, ok := st.profiles[strings.ToLower(domain)]
+	if !ok {
+		return nil
+	}
+	cp := *p
+	return &cp
+}
+
+// Domains returns the domains that currently have a profile
+func (st *SiteProfileStore) Domains() []string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	out := make([]string, 0, len(st.profiles))
+	for d := range st.profiles {
+		out = append(out, d)
+	}
+	return out
+}
+
+// Reload reads the profile file again. On error the previous profiles are kept.
+func (st *SiteProfileStore) Reload() error {
+	if st.path == "" {
+		return nil
+	}
+	info, err := os.Stat(st.path)
+	if err != nil {
+		return fmt.Errorf("stat site profiles: %w", err)
+	}
+	b, err := os.ReadFile(st.path)
+	if err != nil {
+		return fmt.Errorf("read site profiles: %w", err)
+	}
+	var raw map[string]SiteProfile
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse site profiles %s: %w", st.path, err)
+	}
+	for domain, p := range raw {
+		if p.ScriptPattern != "" {
+			if _, err := regexp.Compile(p.ScriptPattern); err != nil {
+				return fmt.Errorf("site profile %s: invalid scriptPattern: %w", domain, err)
+			}
+		}
+		if p.CacheTTL != "" {
+			if d, err := time.ParseDuration(p.CacheTTL); err != nil || d <= 0 {
+				return fmt.Errorf("site profile %s: invalid cacheTtl %q", domain, p.CacheTTL)
+			}
+		}
+		if _, err := p.Retry.Policy(); err != nil {
+			return fmt.Errorf("site profile %s: invalid retry: %w", domain, err)
+		}
+	}
+
+	profiles := buildSiteProfiles(raw)
+
+	st.mu.Lock()
+	st.profiles = profiles
+	st.modTime = info.ModTime()
+	st.mu.Unlock()
+
+	log.Printf("→ Site profiles loaded: %d domains from %s", len(raw), st.path)
+	return nil
+}
+
+// Watch polls the profile file every interval and reloads it when its
+// modification time changes. It returns when ctx is done.
+func (st *SiteProfileStore) Watch(ctx context.Context, interval time.Duration) {
+	if st.path == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			st.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged reloads the file when its modification time differs from
+// the last one seen. A failed reload still records the time, so a broken
+// file is reported once rather than on every poll.
+func (st *SiteProfileStore) reloadIfChanged() {
+	info, err := os.Stat(st.path)
+	if err != nil {
+		return
+	}
+	st.mu.RLock()
+	changed := !info.ModTime().Equal(st.modTime)
+	st.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := st.Reload(); err != nil {
+		log.Printf("site profiles reload failed (keeping previous): %v", err)
+		st.mu.Lock()
+		st.modTime = info.ModTime()
+		st.mu.Unlock()
+	}
+}
+
+func buildSiteProfiles(raw map[string]SiteProfile) map[string]*SiteProfile {
+	out := make(map[string]*SiteProfile, len(builtinSiteProfiles)+len(raw))
+	add := func(domain string, p SiteProfile) {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		p.Domain = domain
+		if p.ScriptPattern != "" {
+			p.scriptRe = regexp.MustCompile(p.ScriptPattern)
+		}
+		if p.CacheTTL != "" {
+			p.cacheTTL, _ = time.ParseDuration(p.CacheTTL)
+		}
+		p.retry, _ = p.Retry.Policy()
+		out[domain] = &p
+	}
+	for d, p := range builtinSiteProfiles {
+		add(d, p)
+	}
+	for d, p := range raw {
+		add(d, p)
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeProfiles(t *testing.T, path, body string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSiteProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site-profiles.json")
+	writeProfiles(t, path, `{
+		" WWW.Example.COM ": {
+			"homepagePath": "br/home",
+			"scriptPattern": "^/[a-z]+/ips\\.js$",
+			"provider": "n4s",
+			"lowSecurity": false,
+			"cacheTtl": "6h",
+			"retry": {"maxAttempts": 5, "initialBackoff": "1s"}
+		},
+		"www.voeazul.com.br": {"homepagePath": "/br/en/home"}
+	}`, time.Now())
+
+	st, err := LoadSiteProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domains := st.Domains()
+	sort.Strings(domains)
+	if strings.Join(domains, ",") != "www.example.com,www.voeazul.com.br" {
+		t.Fatalf("Domains = %v", domains)
+	}
+
+	// Lookup ignores case; the file overrides the built-in profile
+	p := st.Get("WWW.EXAMPLE.COM")
+	if p == nil || p.Domain != "www.example.com" || p.Provider != "n4s" {
+		t.Fatalf("Get = %+v", p)
+	}
+	if got := p.HomepageURL("www.example.com"); got != "https://www.example.com/br/home" {
+		t.Errorf("HomepageURL = %q", got)
+	}
+	if re := p.ScriptRegexp(); re == nil || !re.MatchString("/abc/ips.js") || re.MatchString("/abc/other.js") {
+		t.Errorf("ScriptRegexp = %v", re)
+	}
+	if p.CacheTTLDuration() != 6*time.Hour {
+		t.Errorf("CacheTTLDuration = %v", p.CacheTTLDuration())
+	}
+	if rp := p.RetryPolicy(); rp == nil || rp.MaxAttempts != 5 {
+		t.Errorf("RetryPolicy = %+v", rp)
+	}
+	if p.LowSecurity == nil || *p.LowSecurity {
+		t.Errorf("LowSecurity = %v; want an explicit false", p.LowSecurity)
+	}
+	if got := st.Get("www.voeazul.com.br").HomepagePath; got != "/br/en/home" {
+		t.Errorf("voeazul homepage = %q; want the file's value", got)
+	}
+
+	// Get returns a copy
+	p.Provider = "jevi"
+	if st.Get("www.example.com").Provider != "n4s" {
+		t.Error("changing a returned profile changed the store")
+	}
+
+	// Unknown domains and empty stores
+	if st.Get("other.com") != nil {
+		t.Error("profile for an unknown domain")
+	}
+	var none *SiteProfile
+	if none.ScriptRegexp() != nil || none.CacheTTLDuration() != 0 || none.RetryPolicy() != nil ||
+		none.HomepageURL("a.com") != "https://a.com" {
+		t.Error("nil profile accessors")
+	}
+	builtin := NewSiteProfileStore("")
+	if p := builtin.Get("www.voeazul.com.br"); p == nil || p.HomepagePath != "/br/pt/home" {
+		t.Errorf("built-in profile = %+v", p)
+	}
+}
+
+func TestSiteProfilesValidation(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name, body, err string
+	}{
+		{"not JSON", `{"a.com": `, "parse site profiles"},
+		{"scriptPattern", `{"a.com": {"scriptPattern": "(["}}`, "invalid scriptPattern"},
+		{"cacheTtl", `{"a.com": {"cacheTtl": "soon"}}`, "invalid cacheTtl"},
+		{"negative cacheTtl", `{"a.com": {"cacheTtl": "-1h"}}`, "invalid cacheTtl"},
+		{"retry", `{"a.com": {"retry": {"initialBackoff": "fast"}}}`, "invalid retry"},
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(c.name, " ", "-")+".json")
+		writeProfiles(t, path, c.body, time.Now())
+		if _, err := LoadSiteProfiles(path); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: err = %v; want %q", c.name, err, c.err)
+		}
+	}
+	if _, err := LoadSiteProfiles(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file loaded without error")
+	}
+}
+
+func TestSiteProfileApplyTo(t *testing.T) {
+	on, off := true, false
+	p := &SiteProfile{Provider: "n4s", SensorPostLimit: 5, Language: "pt-BR", SensorURL: "/on/abck", LowSecurity: &on}
+
+	// Zero-valued fields take the profile values
+	cfg := &Config{}
+	p.ApplyTo(cfg)
+	if cfg.AkamaiProvider != "n4s" || cfg.SensorPostLimit != 5 || cfg.Language != "pt-BR" ||
+		cfg.SensorUrl != "/on/abck" || !cfg.LowSecurity {
+		t.Errorf("empty config = %+v", cfg)
+	}
+
+	// Explicit values win
+	cfg = &Config{AkamaiProvider: "jevi", SensorPostLimit: 2, Language: "en-US", SensorUrl: "/x"}
+	p.ApplyTo(cfg)
+	if cfg.AkamaiProvider != "jevi" || cfg.SensorPostLimit != 2 || cfg.Language != "en-US" || cfg.SensorUrl != "/x" {
+		t.Errorf("explicit config = %+v", cfg)
+	}
+
+	// LowSecurity follows the profile whenever it is set
+	cfg = &Config{LowSecurity: true}
+	(&SiteProfile{LowSecurity: &off}).ApplyTo(cfg)
+	if cfg.LowSecurity {
+		t.Error("profile could not turn LowSecurity off")
+	}
+	cfg = &Config{LowSecurity: true}
+	(&SiteProfile{}).ApplyTo(cfg)
+	if !cfg.LowSecurity {
+		t.Error("profile without lowSecurity changed the config")
+	}
+
+	var none *SiteProfile
+	none.ApplyTo(cfg)
+	p.ApplyTo(nil)
+}
+
+func TestSiteProfilesHotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site-profiles.json")
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeProfiles(t, path, `{"a.com": {"provider": "jevi"}}`, t0)
+	st, err := LoadSiteProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged modification time: nothing to reload
+	st.reloadIfChanged()
+	if st.Get("a.com").Provider != "jevi" {
+		t.Fatal("profile lost after a poll without changes")
+	}
+
+	// A modified file replaces the profiles
+	writeProfiles(t, path, `{"a.com": {"provider": "n4s"}, "b.com": {}}`, t0.Add(time.Minute))
+	st.reloadIfChanged()
+	if st.Get("a.com").Provider != "n4s" || st.Get("b.com") == nil {
+		t.Fatalf("after reload: a.com = %+v, b.com = %+v", st.Get("a.com"), st.Get("b.com"))
+	}
+
+	// A broken file keeps the previous profiles and is tried once per change
+	writeProfiles(t, path, `{"a.com": {"cacheTtl": "soon"}}`, t0.Add(2*time.Minute))
+	st.reloadIfChanged()
+	if st.Get("a.com").Provider != "n4s" {
+		t.Fatal("broken file replaced the profiles")
+	}
+	st.mu.RLock()
+	seen := st.modTime
+	st.mu.RUnlock()
+	if !seen.Equal(t0.Add(2 * time.Minute)) {
+		t.Errorf("modTime after a failed reload = %v; want the broken file's", seen)
+	}
+
+	// Fixing the file is picked up by Watch
+	writeProfiles(t, path, `{"a.com": {"provider": "roolink"}}`, t0.Add(3*time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		st.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for st.Get("a.com").Provider != "roolink" {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not reload the fixed file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if st.Get("b.com") != nil {
+		t.Error("b.com kept after it left the file")
+	}
+}
@@ -0,0 +1,370 @@
+package scraper
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SolveMode selects which challenges Solve runs
+type SolveMode string
+
+const (
+	SolveSBSD SolveMode = "sbsd" // SBSD challenge only
+	SolveABCK SolveMode = "abck" // _abck sensor only
+	SolveBoth SolveMode = "both" // SBSD first, then ABCK on the same session
+)
+
+// SolveStep identifies a step of the solve flow. The values match the
+// step codes returned by the HTTP API.
+type SolveStep string
+
+const (
+	StepScraperInit      SolveStep = "scraper_init"
+	StepScriptURLExtract SolveStep = "script_url_extraction"
+	StepScriptFetch      SolveStep = "script_fetch"
+	StepScriptDecode     SolveStep = "script_decode"
+	StepBmSoExtraction   SolveStep = "bm_so_extraction"
+	StepProviderCall     SolveStep = "provider_call"
+	StepSensorPost       SolveStep = "sensor_post"
+	StepSbsdGeneration   SolveStep = "sbsd_generation"
+	StepSbsdPost         SolveStep = "sbsd_post"
+	StepCookieValidation SolveStep = "cookie_validation"
+	StepTLSAPIError      SolveStep = "tls_api_error"
+	StepQuotaExceeded    SolveStep = "quota_exceeded"
+)
+
+// SolveRequest describes a full solve. Config carries the domain, providers,
+// browser profile and API keys; SbSd and SensorUrl are set by Solve itself.
+type SolveRequest struct {
+	Mode       SolveMode    // Defaults to SolveSBSD
+	Proxy      string       // Proxy URL used for every site request
+	ScriptPath string       // Anti-bot script path overriding discovery; ignored by SolveBoth
+	Progress   ProgressFunc // Notified as steps start and finish (optional)
+	Config     Config
+}
+
+// StepResult records one executed step
+type StepResult struct {
+	Mode     SolveMode     // SolveSBSD or SolveABCK
+	Step     SolveStep     // Step that ran
+	Success  bool          // Whether the step succeeded
+	Detail   string        // Step output worth logging (script URL, script size, provider)
+	Duration time.Duration // Time spent in the step
+}
+
+// SolveResult is the outcome of Solve. On failure Cookies holds whatever the
+// session collected before the failing step.
+type SolveResult struct {
+	Success      bool
+	Mode         SolveMode
+	Steps        []StepResult
+	Cookies      []*http.Cookie // Cookies of the domain at the end of the flow
+	CookieString string
+	SBSD         *SBSDResult     // Set when the SBSD solver ran
+	ABCK         *ABCKResult     // Set when the ABCK solver ran
+	Provider     string          // Provider that served the last solver call
+	Attempts     AttemptStats    // Attempts and retries per operation
+	Timeline     []TimelineEntry // Every upstream request and timed step, in order
+	SensorData   string          // Last sensor data or SBSD body posted
+	ReportPath   string          // Request report, when Config.GenerateReport is set
+}
+
+// SolveError is returned by Solve; Step tells where the flow stopped
+type SolveError struct {
+	Mode        SolveMode
+	Step        SolveStep
+	Provider    string
+	Domain      string
+	Attempt     int   // Attempts made by the failing operation (posts or calls), when known
+	MaxAttempts int   // Limit of the failing operation, when known
+	Err         error // Underlying error (often a *SolverError)
+}
+
+func (e *SolveError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Mode, e.Step, e.Err)
+}
+
+func (e *SolveError) Unwrap() error { return e.Err }
+
+// Solve runs the whole flow: homepage, script URL, script download and
+// decode, bm_so/sbsd_o extraction (SBSD) and the provider solve. ctx is
+// checked between steps and also cancels TLS-API requests and retry waits.
+func Solve(ctx context.Context, req SolveRequest) (*SolveResult, error) {
+	if req.Mode == "" {
+		req.Mode = SolveSBSD
+	}
+	cfg := req.Config
+	cfg.SbSd = req.Mode != SolveABCK
+	if cfg.Proxy == "" {
+		cfg.Proxy = req.Proxy
+	}
+
+	f := &solveFlow{ctx: ctx, req: req, cfg: &cfg, result: &SolveResult{Mode: req.Mode}}
+
+	started := time.Now()
+	sc, err := NewScraper(req.Proxy, f.cfg)
+	f.record(modeFor(f.cfg), StepScraperInit, started, err == nil, "")
+	if err != nil {
+		return f.result, f.fail(modeFor(f.cfg), StepScraperInit, err)
+	}
+	f.sc = sc
+	sc.SetContext(ctx)
+	sc.SetProgress(req.Progress)
+	defer sc.CloseReport()
+	f.result.ReportPath = sc.ReportPath()
+
+	if req.Mode != SolveABCK {
+		err = f.solveSBSD()
+	}
+	if err == nil && req.Mode != SolveSBSD {
+		err = f.solveABCK()
+	}
+
+	f.result.Cookies = sc.GetCookies()
+	f.result.CookieString = sc.GetCookieString("")
+	f.result.Attempts = sc.Attempts()
+	f.result.Timeline = sc.Timeline()
+	f.result.SensorData = sc.SensorData()
+	if err != nil {
+		return f.result, err
+	}
+	f.result.Success = true
+	return f.result, nil
+}
+
+// solveFlow holds the state shared by the steps of one Solve call
+type solveFlow struct {
+	ctx    context.Context
+	req    SolveRequest
+	cfg    *Config
+	sc     *Scraper
+	result *SolveResult
+}
+
+func modeFor(cfg *Config) SolveMode {
+	if cfg.SbSd {
+		return SolveSBSD
+	}
+	return SolveABCK
+}
+
+func (f *solveFlow) record(mode SolveMode, step SolveStep, started time.Time, ok bool, detail string) {
+	f.result.Steps = append(f.result.Steps, StepResult{
+		Mode:     mode,
+		Step:     step,
+		Success:  ok,
+		Detail:   detail,
+		Duration: time.Since(started),
+	})
+}
+
+func (f *solveFlow) fail(mode SolveMode, step SolveStep, err error) *SolveError {
+	se := &SolveError{Mode: mode, Step: step, Provider: f.result.Provider, Domain: f.cfg.Domain, Err: err}
+	if f.sc == nil {
+		return se
+	}
+	a := f.sc.Attempts()
+	switch step {
+	case StepSensorPost, StepCookieValidation:
+		se.Attempt, se.MaxAttempts = a.SensorPosts, f.cfg.SensorPostLimit
+	case StepSbsdPost:
+		se.Attempt, se.MaxAttempts = a.SBSDPosts, f.sc.RetryPolicy().MaxAttempts
+	case StepProviderCall, StepSbsdGeneration:
+		se.Attempt = a.ProviderCalls
+	}
+	return se
+}
+
+// checkCtx stops the flow before step when ctx is done
+func (f *solveFlow) checkCtx(mode SolveMode, step SolveStep) error {
+	if err := f.ctx.Err(); err != nil {
+		f.record(mode, step, time.Now(), false, "canceled")
+		return f.fail(mode, step, err)
+	}
+	return nil
+}
+
+func (f *solveFlow) solveSBSD() error {
+	f.cfg.SbSd = true
+	script, err := f.fetchScript(SolveSBSD)
+	if err != nil {
+		return err
+	}
+
+	if err := f.checkCtx(SolveSBSD, StepBmSoExtraction); err != nil {
+		return err
+	}
+	started := time.Now()
+	var bmSo string
+	if c := ParseAkamaiCookies(f.sc.GetCookies()).SbsdO; c != nil {
+		bmSo = c.Raw
+	}
+	f.record(SolveSBSD, StepBmSoExtraction, started, bmSo != "", "")
+	if bmSo == "" {
+		return f.fail(SolveSBSD, StepBmSoExtraction, fmt.Errorf("bm_so/sbsd_o cookie not found"))
+	}
+
+	if err := f.checkCtx(SolveSBSD, StepSbsdGeneration); err != nil {
+		return err
+	}
+	started = time.Now()
+	res, err := f.sc.GenerateSBSD(script, bmSo)
+	f.result.SBSD = res
+	if res != nil {
+		f.result.Provider = res.Session.Provider
+	}
+	if err != nil {
+		step := sbsdFailureStep(err)
+		f.record(SolveSBSD, step, started, false, f.result.Provider)
+		return f.fail(SolveSBSD, step, err)
+	}
+	if !res.Success {
+		f.record(SolveSBSD, StepSbsdPost, started, false, f.result.Provider)
+		return f.fail(SolveSBSD, StepSbsdPost, resultError(res.Error, "sbsd challenge rejected"))
+	}
+	f.record(SolveSBSD, StepSbsdPost, started, true, f.result.Provider)
+	return nil
+}
+
+func (f *solveFlow) solveABCK() error {
+	f.cfg.SbSd = false
+	f.cfg.SensorUrl = ""
+	script, err := f.fetchScript(SolveABCK)
+	if err != nil {
+		return err
+	}
+
+	if err := f.checkCtx(SolveABCK, StepProviderCall); err != nil {
+		return err
+	}
+	started := time.Now()
+	res, err := f.sc.GenerateABCK(script)
+	f.result.ABCK = res
+	if res != nil {
+		f.result.Provider = res.Session.Provider
+	}
+	if err != nil {
+		step := abckFailureStep(err)
+		f.record(SolveABCK, step, started, false, f.result.Provider)
+		return f.fail(SolveABCK, step, err)
+	}
+	if !res.Success {
+		f.record(SolveABCK, StepCookieValidation, started, false, f.result.Provider)
+		return f.fail(SolveABCK, StepCookieValidation, resultError(res.Error, "_abck cookie validation failed"))
+	}
+	f.record(SolveABCK, StepCookieValidation, started, true, f.result.Provider)
+	return nil
+}
+
+// fetchScript loads the homepage, resolves the script URL for the current
+// mode (ScriptPath wins over discovery), downloads and decodes the script
+func (f *solveFlow) fetchScript(mode SolveMode) (string, error) {
+	if err := f.checkCtx(mode, StepScriptURLExtract); err != nil {
+		return "", err
+	}
+	started := time.Now()
+	scriptURL, err := f.sc.GetAntiBotScriptURL("")
+	if f.req.ScriptPath != "" && f.req.Mode != SolveBoth {
+		// The homepage still has to load; only discovery is overridden
+		var de *ScriptDiscoveryError
+		if err == nil || errors.As(err, &de) {
+			scriptURL, err = f.req.ScriptPath, nil
+		}
+	}
+	if err == nil && scriptURL == "" {
+		err = fmt.Errorf("script URL not found in page")
+	}
+	f.record(mode, StepScriptURLExtract, started, err == nil, scriptURL)
+	if err != nil {
+		return "", f.fail(mode, StepScriptURLExtract, err)
+	}
+	f.cfg.SensorUrl = scriptURL
+
+	if err := f.checkCtx(mode, StepScriptFetch); err != nil {
+		return "", err
+	}
+	started = time.Now()
+	encoded, err := f.sc.GetAntiBotScript()
+	f.record(mode, StepScriptFetch, started, err == nil, "")
+	if err != nil {
+		return "", f.fail(mode, StepScriptFetch, err)
+	}
+
+	started = time.Now()
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	f.record(mode, StepScriptDecode, started, err == nil, fmt.Sprintf("%d bytes", len(decoded)))
+	if err != nil {
+		return "", f.fail(mode, StepScriptDecode, err)
+	}
+	if len(decoded) == 0 {
+		log.Printf("→ Script body skipped (cached provider dynamic)")
+	}
+	return string(decoded), nil
+}
+
+// resultError keeps a nil *SolverError from becoming a non-nil error
+func resultError(se *SolverError, fallback string) error {
+	if se == nil {
+		return fmt.Errorf("%s", fallback)
+	}
+	return se
+}
+
+// sbsdFailureStep maps the phase of an SBSD solver error to its step
+func sbsdFailureStep(err error) SolveStep {
+	var se *SolverError
+	if !errors.As(err, &se) {
+		return StepSbsdGeneration
+	}
+	if step, ok := siteFailureStep(se.Phase); ok {
+		return step
+	}
+	switch se.Phase {
+	case PhaseQuota:
+		return StepQuotaExceeded
+	case PhaseSBSDPost:
+		return StepSbsdPost
+	default:
+		return StepSbsdGeneration
+	}
+}
+
+// abckFailureStep maps the phase of an ABCK solver error to its step
+func abckFailureStep(err error) SolveStep {
+	var se *SolverError
+	if !errors.As(err, &se) {
+		return StepProviderCall
+	}
+	if step, ok := siteFailureStep(se.Phase); ok {
+		return step
+	}
+	switch se.Phase {
+	case PhaseQuota:
+		return StepQuotaExceeded
+	case PhaseSensorPost:
+		return StepSensorPost
+	case PhaseCookieValidation:
+		return StepCookieValidation
+	default:
+		return StepProviderCall
+	}
+}
+
+// siteFailureStep maps the phases of site and TLS-API requests (a body that
+// fails to decode, for instance) to their step
+func siteFailureStep(phase ErrorPhase) (SolveStep, bool) {
+	switch phase {
+	case PhaseHomepage, PhaseScriptExtract:
+		return StepScriptURLExtract, true
+	case PhaseScriptFetch:
+		return StepScriptFetch, true
+	case PhaseTLSAPI:
+		return StepTLSAPIError, true
+	}
+	return "", false
+}
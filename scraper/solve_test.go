@@ -0,0 +1,252 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailureSteps(t *testing.T) {
+	for _, c := range []struct {
+		phase      ErrorPhase
+		sbsd, abck SolveStep
+	}{
+		{PhaseHomepage, StepScriptURLExtract, StepScriptURLExtract},
+		{PhaseScriptExtract, StepScriptURLExtract, StepScriptURLExtract},
+		{PhaseScriptFetch, StepScriptFetch, StepScriptFetch},
+		{PhaseTLSAPI, StepTLSAPIError, StepTLSAPIError},
+		{PhaseQuota, StepQuotaExceeded, StepQuotaExceeded},
+		{PhaseProviderCall, StepSbsdGeneration, StepProviderCall},
+		{PhaseSBSDPost, StepSbsdPost, StepProviderCall},
+		{PhaseSensorPost, StepSbsdGeneration, StepSensorPost},
+		{PhaseCookieValidation, StepSbsdGeneration, StepCookieValidation},
+		{PhaseInit, StepSbsdGeneration, StepProviderCall},
+	} {
+		err := fmt.Errorf("solve: %w", NewError(c.phase, "step", errors.New("boom")))
+		if got := sbsdFailureStep(err); got != c.sbsd {
+			t.Errorf("sbsdFailureStep(%s) = %s; want %s", c.phase, got, c.sbsd)
+		}
+		if got := abckFailureStep(err); got != c.abck {
+			t.Errorf("abckFailureStep(%s) = %s; want %s", c.phase, got, c.abck)
+		}
+	}
+
+	// Errors without a phase fall on the provider step of each mode
+	plain := errors.New("boom")
+	if got := sbsdFailureStep(plain); got != StepSbsdGeneration {
+		t.Errorf("sbsdFailureStep(plain) = %s", got)
+	}
+	if got := abckFailureStep(plain); got != StepProviderCall {
+		t.Errorf("abckFailureStep(plain) = %s", got)
+	}
+}
+
+const (
+	siteHost      = "www.example.com"
+	sbsdScriptURL = "/Qq12cd/Ef34gh/Ij56kl?v=1a2b-3c4d"
+	abckScriptURL = "/Ab12cd/Ef34gh/Ij56kl"
+)
+
+// siteStub is a TLS-API that plays an Akamai-protected site and the jevi
+// provider: the homepage links an SBSD and a sensor script and sets the
+// challenge cookies, SBSD posts are accepted and sensor posts return a valid
+// _abck. Every answer carries the request ID "req-<n>", n counting from 1.
+type siteStub struct {
+	mu               sync.Mutex
+	providerStatus   int      // Status of jevi's answers, 200 by default
+	sensorRejections int      // Sensor posts rejected before one is accepted
+	requests         []string // "METHOD url" of every request, in order
+}
+
+// newSiteStub starts the stub and makes it the TLS-API of every scraper
+// created by the test
+func newSiteStub(t *testing.T) *siteStub {
+	t.Helper()
+	st := &siteStub{providerStatus: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TLSRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, id := st.answer(req)
+		json.NewEncoder(w).Encode(TLSResponse{Success: true, Data: data, Metadata: &TLSMetadata{RequestID: id}})
+	}))
+	t.Cleanup(srv.Close)
+
+	prev := DefaultTLSAPIPool()
+	SetDefaultTLSAPIPool(NewTLSAPIPool([]string{srv.URL}, 0))
+	SetDefaultProviderCache(NewProviderCache(NewFileCacheStore("")))
+	t.Cleanup(func() {
+		SetDefaultTLSAPIPool(prev)
+		SetDefaultProviderCache(nil) // Reopened from the defaults on next use
+	})
+	return st
+}
+
+func (st *siteStub) answer(req TLSRequest) (*TLSResponseData, string) {
+	st.mu.Lock()
+	st.requests = append(st.requests, req.Method+" "+req.URL)
+	id := fmt.Sprintf("req-%d", len(st.requests))
+	providerStatus := st.providerStatus
+	st.mu.Unlock()
+	return st.page(req, providerStatus), id
+}
+
+func (st *siteStub) page(req TLSRequest, providerStatus int) *TLSResponseData {
+	switch {
+	case strings.HasPrefix(req.URL, "https://"+jeviHost+"/"):
+		if providerStatus != http.StatusOK {
+			return &TLSResponseData{Status: providerStatus, Body: "upstream error"}
+		}
+		return &TLSResponseData{Status: http.StatusOK, Body: `{"body":"sbsd-payload","sensor_data":"sensor-payload"}`}
+	case req.Method == "GET" && strings.TrimSuffix(req.URL, "/") == "https://"+siteHost:
+		return &TLSResponseData{
+			Status: http.StatusOK,
+			Body:   `<html><script src="` + sbsdScriptURL + `"></script><script src="` + abckScriptURL + `"></script></html>`,
+			Cookies: []Cookie{
+				{Name: "sbsd_o", Value: "0A1B2C3D~sbsd-o-payload"},
+				{Name: "bm_sz", Value: "0A1B2C3D~YAAQbmszpayload~4342581~3556930"},
+				{Name: "_abck", Value: abckPending},
+			},
+		}
+	case req.Method == "GET":
+		return &TLSResponseData{Status: http.StatusOK, Body: "var akamai = 1;"}
+	case strings.Contains(req.URL, "v="):
+		return &TLSResponseData{Status: http.StatusOK, Body: "{}"}
+	default:
+		st.mu.Lock()
+		reject := st.sensorRejections > 0
+		if reject {
+			st.sensorRejections--
+		}
+		st.mu.Unlock()
+		if reject {
+			return &TLSResponseData{Status: http.StatusCreated, Body: "{\n}", Cookies: []Cookie{{Name: "_abck", Value: abckPending}}}
+		}
+		return &TLSResponseData{Status: http.StatusCreated, Body: `{"success":true}`, Cookies: []Cookie{{Name: "_abck", Value: abckAccepted}}}
+	}
+}
+
+func (st *siteStub) rejectSensors(n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sensorRejections = n
+}
+
+func (st *siteStub) setProviderStatus(status int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.providerStatus = status
+}
+
+func (st *siteStub) requestLog() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]string(nil), st.requests...)
+}
+
+func siteSolveRequest(mode SolveMode) SolveRequest {
+	return SolveRequest{
+		Mode: mode,
+		Config: Config{
+			Domain:         siteHost,
+			AkamaiProvider: "jevi",
+			JeviAPIKey:     "key-1",
+			Tenant:         "acme",
+			Retry:          &RetryPolicy{MaxAttempts: 1},
+		},
+	}
+}
+
+func stepNames(steps []StepResult) []string {
+	names := make([]string, len(steps))
+	for i, s := range steps {
+		names[i] = string(s.Mode) + ":" + string(s.Step)
+		if !s.Success {
+			names[i] += "!"
+		}
+	}
+	return names
+}
+
+func TestSolveBoth(t *testing.T) {
+	withSolverDefaults(t, NewProviderBreaker(5, time.Minute), nil)
+	stub := newSiteStub(t)
+
+	res, err := Solve(context.Background(), siteSolveRequest(SolveBoth))
+	if err != nil {
+		t.Fatalf("Solve: %v", err)
+	}
+	want := []string{
+		"sbsd:scraper_init",
+		"sbsd:script_url_extraction", "sbsd:script_fetch", "sbsd:script_decode", "sbsd:bm_so_extraction", "sbsd:sbsd_post",
+		"abck:script_url_extraction", "abck:script_fetch", "abck:script_decode", "abck:cookie_validation",
+	}
+	if got := stepNames(res.Steps); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("steps = %v; want %v", got, want)
+	}
+	if !res.Success || res.SBSD == nil || !res.SBSD.Success || res.ABCK == nil || !res.ABCK.Success || res.Provider != "jevi" {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Steps[1].Detail != sbsdScriptURL || res.Steps[6].Detail != abckScriptURL {
+		t.Errorf("script URLs = %q, %q", res.Steps[1].Detail, res.Steps[6].Detail)
+	}
+	if !strings.Contains(res.CookieString, "_abck="+abckAccepted) || res.SensorData != "sensor-payload" {
+		t.Errorf("cookies = %q, sensor = %q", res.CookieString, res.SensorData)
+	}
+	if res.Attempts.ProviderCalls != 2 || res.Attempts.SBSDPosts != 1 || res.Attempts.SensorPosts != 1 {
+		t.Errorf("attempts = %+v", res.Attempts)
+	}
+
+	// The sensor flow loads the homepage again and posts to the sensor script
+	wantRequests := []string{
+		"GET https://" + siteHost,
+		"GET https://" + siteHost + sbsdScriptURL,
+		"POST https://" + jeviHost + "/Solver/solve",
+		"POST https://" + siteHost + sbsdScriptURL,
+		"GET https://" + siteHost,
+		"GET https://" + siteHost + abckScriptURL,
+		"POST https://" + jeviHost + "/Solver/solve",
+		"POST https://" + siteHost + abckScriptURL,
+	}
+	if got := stub.requestLog(); strings.Join(got, "\n") != strings.Join(wantRequests, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantRequests, "\n"))
+	}
+}
+
+func TestSolveFailureStopsTheFlow(t *testing.T) {
+	withSolverDefaults(t, NewProviderBreaker(5, time.Minute), nil)
+	stub := newSiteStub(t)
+	stub.setProviderStatus(http.StatusServiceUnavailable)
+
+	res, err := Solve(context.Background(), siteSolveRequest(SolveBoth))
+	var se *SolveError
+	if !errors.As(err, &se) {
+		t.Fatalf("Solve = %v; want a *SolveError", err)
+	}
+	if se.Mode != SolveSBSD || se.Step != StepSbsdGeneration || se.Provider != "jevi" || se.Domain != siteHost || se.Attempt != 1 {
+		t.Errorf("SolveError = %+v", se)
+	}
+	if res.Success || res.ABCK != nil {
+		t.Errorf("result = %+v; want the ABCK flow skipped", res)
+	}
+	if got := stepNames(res.Steps); got[len(got)-1] != "sbsd:sbsd_generation!" {
+		t.Errorf("steps = %v", got)
+	}
+
+	// A canceled context stops before the first step that needs the site
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Solve(ctx, siteSolveRequest(SolveABCK))
+	if !errors.As(err, &se) || se.Step != StepScriptURLExtract || !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled Solve = %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TimelineStep names an entry of the solve timeline
+type TimelineStep string
+
+const (
+	TimelineHomepage     TimelineStep = "homepage"      // Homepage request
+	TimelineScriptURL    TimelineStep = "script_url"    // Script discovery on the homepage (no upstream request)
+	TimelineScriptFetch  TimelineStep = "script_fetch"  // Anti-bot script download
+	TimelineScriptSeed   TimelineStep = "script_seed"   // Partial script request seeding cookies
+	TimelineProviderCall TimelineStep = "provider_call" // Provider API call
+	TimelineSensorPost   TimelineStep = "sensor_post"   // Sensor post to Akamai
+	TimelineSbsdPost     TimelineStep = "sbsd_post"     // SBSD challenge post to Akamai
+	TimelineSiteRequest  TimelineStep = "site_request"  // Other SiteClient.Request calls
+)
+
+// TimelineEntry is one timed step of a solve. Upstream requests get one entry
+// per TLS-API attempt, so retries show up as separate entries.
+type TimelineEntry struct {
+	Step      TimelineStep
+	Provider  string // Provider called (provider_call only)
+	Attempt   int    // 1 for the first entry of Step (and Provider), 2 for the next...
+	Status    int    // Upstream HTTP status, 0 without a response
+	RequestID string // TLS-API request ID (TLSMetadata.RequestID)
+	StartedAt time.Time
+	Duration  time.Duration
+	Detail    string // Step output (script URL for script_url)
+	Error     string
+}
+
+// Timeline collects the timed steps of one scraper and dispatches its
+// events to the registered handlers. The zero value is ready to use and a
+// nil *Timeline records nothing.
+type Timeline struct {
+	mu       sync.Mutex
+	entries  []TimelineEntry
+	handlers []EventHandler
+	progress ProgressFunc
+
+	cookies map[string]string // Values set by earlier responses, by domain and name
+}
+
+// AddEventHandler registers h for every later event
+func (t *Timeline) AddEventHandler(h EventHandler) {
+	if h == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers = append(t.handlers, h)
+}
+
+// SetProgress sets the function notified as steps start and finish (fn may
+// be nil)
+func (t *Timeline) SetProgress(fn ProgressFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress = fn
+}
+
+// emit sends e to the handlers and the progress function, outside the lock
+func (t *Timeline) emit(e Event) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	handlers, progress := t.handlers, t.progress
+	t.mu.Unlock()
+	for _, h := range handlers {
+		h.HandleEvent(e)
+	}
+	if progress != nil {
+		if ev, ok := progressEvent(e); ok {
+			progress(ev)
+		}
+	}
+}
+
+// nextAttempt returns the attempt number the next entry of step gets.
+// Callers hold t.mu.
+func (t *Timeline) nextAttempt(step TimelineStep, provider string) int {
+	n := 1
+	for _, prev := range t.entries {
+		if prev.Step == step && prev.Provider == provider {
+			n++
+		}
+	}
+	return n
+}
+
+// Start reports that step is about to run
+func (t *Timeline) Start(step TimelineStep, provider string) {
+	if t == nil || step == "" {
+		return
+	}
+	t.mu.Lock()
+	attempt := t.nextAttempt(step, provider)
+	t.mu.Unlock()
+	t.emit(&PhaseStartEvent{Phase: stepPhases[step], Step: step, Provider: provider, Attempt: attempt, Time: time.Now()})
+}
+
+// PostResult reports whether the last recorded post of step (sensor_post or
+// sbsd_post) was accepted
+func (t *Timeline) PostResult(step TimelineStep, status int, accepted bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	attempt := t.nextAttempt(step, "") - 1
+	t.mu.Unlock()
+	t.emit(&PostResultEvent{Step: step, Attempt: attempt, Status: status, Accepted: accepted, Time: time.Now()})
+}
+
+// Entries returns a copy of the recorded entries, oldest first
+func (t *Timeline) Entries() []TimelineEntry {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TimelineEntry(nil), t.entries...)
+}
+
+// Record adds an entry for an upstream request of step started at started.
+// resp and err may be nil.
+func (t *Timeline) Record(step TimelineStep, provider string, started time.Time, resp *TLSResponse, err error) {
+	if t == nil || step == "" {
+		return
+	}
+	e := TimelineEntry{
+		Step:      step,
+		Provider:  provider,
+		StartedAt: started,
+		Duration:  time.Since(started),
+	}
+	var cookies []Cookie
+	if resp != nil {
+		e.Status = resp.GetStatus()
+		if resp.Metadata != nil {
+			e.RequestID = resp.Metadata.RequestID
+		}
+		cookies = resp.GetCookies()
+	}
+	if err != nil {
+		e.Error = err.Error()
+		var se *SolverError
+		if errors.As(err, &se) && e.Status == 0 {
+			e.Status = se.StatusCode
+		}
+	}
+	t.add(e)
+	if changed := t.changedCookies(cookies); len(changed) > 0 {
+		t.emit(&CookiesChangedEvent{Step: step, Cookies: changed, Time: time.Now()})
+	}
+}
+
+// changedCookies returns the cookies whose value differs from the one an
+// earlier response set, and remembers the new values
+func (t *Timeline) changedCookies(cookies []Cookie) []Cookie {
+	if len(cookies) == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cookies == nil {
+		t.cookies = make(map[string]string)
+	}
+	var changed []Cookie
+	for _, c := range cookies {
+		key := c.Domain + "|" + c.Name
+		if v, ok := t.cookies[key]; ok && v == c.Value {
+			continue
+		}
+		t.cookies[key] = c.Value
+		changed = append(changed, c)
+	}
+	return changed
+}
+
+// RecordLocal adds an entry for a step without upstream request
+func (t *Timeline) RecordLocal(step TimelineStep, started time.Time, detail string, err error) {
+	if t == nil {
+		return
+	}
+	e := TimelineEntry{Step: step, StartedAt: started, Duration: time.Since(started), Detail: detail}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	t.add(e)
+}
+
+func (t *Timeline) add(e TimelineEntry) {
+	t.mu.Lock()
+	e.Attempt = t.nextAttempt(e.Step, e.Provider)
+	t.entries = append(t.entries, e)
+	t.mu.Unlock()
+
+	t.emit(&PhaseEndEvent{Phase: stepPhases[e.Step], Entry: e, Time: time.Now()})
+}
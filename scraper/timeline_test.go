@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSolveTimeline runs a sensor solve whose first post is rejected and
+// checks the entries it records
+func TestSolveTimeline(t *testing.T) {
+	withSolverDefaults(t, NewProviderBreaker(5, time.Minute), nil)
+	stub := newSiteStub(t)
+	stub.rejectSensors(1)
+
+	res, err := Solve(context.Background(), siteSolveRequest(SolveABCK))
+	if err != nil {
+		t.Fatalf("Solve: %v", err)
+	}
+	var got []string
+	for _, e := range res.Timeline {
+		got = append(got, fmt.Sprintf("%s %s #%d %d %s", e.Step, e.Provider, e.Attempt, e.Status, e.RequestID))
+		if e.StartedAt.IsZero() || e.Duration < 0 || e.Error != "" {
+			t.Errorf("entry %+v", e)
+		}
+	}
+	want := []string{
+		"homepage  #1 200 req-1",
+		"script_url  #1 0 ", // Discovery sends no request
+		"script_fetch  #1 200 req-2",
+		"provider_call jevi #1 200 req-3",
+		"sensor_post  #1 201 req-4",
+		"provider_call jevi #2 200 req-5",
+		"sensor_post  #2 201 req-6",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("timeline:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if d := res.Timeline[1].Detail; d != abckScriptURL {
+		t.Errorf("script_url detail = %q; want %q", d, abckScriptURL)
+	}
+	if res.Attempts.SensorPosts != 2 || res.Attempts.ProviderCalls != 2 {
+		t.Errorf("attempts = %+v", res.Attempts)
+	}
+}
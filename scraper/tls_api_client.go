@@ -2,11 +2,14 @@ package scraper
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -19,55 +22,98 @@ const (
 
 	// TLSAPIRequestPath is the endpoint for making requests
 	TLSAPIRequestPath = "/v1/request"
+
+	// TLSAPIProfilesPath is the endpoint listing the supported browser profiles
+	TLSAPIProfilesPath = "/v1/profiles"
 )
 
 // TLSAPIClient handles communication with the TLS-API service
 type TLSAPIClient struct {
-	baseURL    string
-	authToken  string
-	httpClient *http.Client
+	pool         *TLSAPIPool
+	authToken    string
+	httpClient   *http.Client
+	retry        RetryPolicy
+	stats        *AttemptStats
+	timeline     *Timeline
+	interceptors []TLSInterceptor
+	ctx          context.Context // Bounds requests and retry waits (see SetContext)
 }
 
 // NewTLSAPIClient creates a new TLS-API client
 // Configuration is read from environment variables:
-// - TLS_API_URL: Base URL (default: http://localhost:8080)
+// - TLS_API_URL: Base URL or comma-separated replica URLs (default: http://localhost:8080)
 // - TLS_API_TOKEN: Authorization token (optional)
+// The shared DefaultTLSAPIPool, when set, takes precedence over TLS_API_URL.
 func NewTLSAPIClient() *TLSAPIClient {
-	baseURL := os.Getenv("TLS_API_URL")
-	if baseURL == "" {
-		baseURL = DefaultTLSAPIURL
+	pool := DefaultTLSAPIPool()
+	if pool == nil {
+		pool = NewTLSAPIPool(ParseTLSAPIURLs(os.Getenv("TLS_API_URL")), 0)
 	}
 
 	authToken := os.Getenv("TLS_API_TOKEN")
 
 	return &TLSAPIClient{
-		baseURL:   baseURL,
+		pool:      pool,
 		authToken: authToken,
 		httpClient: &http.Client{
 			Timeout: DefaultTLSAPITimeout,
 		},
+		retry: DefaultRetryPolicy(),
+		ctx:   context.Background(),
 	}
 }
 
-// NewTLSAPIClientWithConfig creates a TLS-API client with explicit configuration
+// NewTLSAPIClientWithConfig creates a TLS-API client with explicit configuration.
+// baseURL may list several replicas separated by commas.
 func NewTLSAPIClientWithConfig(baseURL, authToken string, timeout time.Duration) *TLSAPIClient {
-	if baseURL == "" {
-		baseURL = DefaultTLSAPIURL
-	}
 	if timeout == 0 {
 		timeout = DefaultTLSAPITimeout
 	}
 
 	return &TLSAPIClient{
-		baseURL:   baseURL,
+		pool:      NewTLSAPIPool(ParseTLSAPIURLs(baseURL), 0),
 		authToken: authToken,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
+		retry: DefaultRetryPolicy(),
+		ctx:   context.Background(),
+	}
+}
+
+// NewTLSAPIClientWithPool creates a TLS-API client that balances over pool
+func NewTLSAPIClientWithPool(pool *TLSAPIPool, authToken string, timeout time.Duration) *TLSAPIClient {
+	c := NewTLSAPIClientWithConfig("", authToken, timeout)
+	if pool != nil {
+		c.pool = pool
+	}
+	return c
+}
+
+// SetRetryPolicy sets the policy applied to retryable TLS-API errors and the
+// counters updated on every attempt (stats may be nil)
+func (c *TLSAPIClient) SetRetryPolicy(p RetryPolicy, stats *AttemptStats) {
+	c.retry = p
+	c.stats = stats
+}
+
+// SetContext makes in-flight requests and retry waits stop when ctx is done
+func (c *TLSAPIClient) SetContext(ctx context.Context) {
+	if ctx != nil {
+		c.ctx = ctx
 	}
 }
 
-// Request sends an HTTP request through the TLS-API service
+// SetTimeline sets the timeline that records every labeled request attempt
+// (t may be nil)
+func (c *TLSAPIClient) SetTimeline(t *Timeline) {
+	c.timeline = t
+}
+
+// Request sends an HTTP request through the TLS-API service. Each attempt
+// passes through the interceptor chain (see Use and SetDefaultTLSInterceptors).
+// Requests other than GET, HEAD and OPTIONS are retried only when the TLS-API
+// could not be reached, so a post is never sent to the target twice.
 func (c *TLSAPIClient) Request(req TLSRequest) (*TLSResponse, error) {
 	// Ensure ReturnCookies is set by default
 	req.ReturnCookies = true
@@ -77,17 +123,124 @@ func (c *TLSAPIClient) Request(req TLSRequest) (*TLSResponse, error) {
 		req.Timeout = 30 // 30 seconds in ms
 	}
 
-	// Marshal request body
+	roundTrip := c.chain(c.roundTrip)
+	var resp *TLSResponse
+	_, err := c.retry.forPhase(PhaseTLSAPI).Do(c.ctx, "TLS-API request", func(attempt int) error {
+		if c.stats != nil {
+			c.stats.TLSAPIRequests++
+			if attempt > 1 {
+				c.stats.TLSAPIRetries++
+			}
+		}
+		c.timeline.Start(req.step, req.provider)
+		started := time.Now()
+		var err error
+		resp, err = roundTrip(req)
+		c.timeline.Record(req.step, req.provider, started, resp, err)
+		return err
+	})
+	return resp, err
+}
+
+// roundTrip marshals req and sends it: the innermost TLSRoundTripFunc
+func (c *TLSAPIClient) roundTrip(req TLSRequest) (*TLSResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, NewError(PhaseTLSAPI, "marshal request", err)
 	}
+	return c.send(body, isIdempotent(req.Method))
+}
+
+// isIdempotent reports whether a request with method may be sent to the
+// target again (an empty method is a GET)
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
 
+// send makes a single POST to the TLS-API
+func (c *TLSAPIClient) send(body []byte, idempotent bool) (*TLSResponse, error) {
+	var resp *TLSResponse
+	err := c.onBackend(func(baseURL string) (res backendResult, err error) {
+		resp, res, err = c.sendTo(baseURL, body, idempotent)
+		return res, err
+	})
+	return resp, err
+}
+
+// onBackend runs fn against a backend of the pool. A backend that is down
+// (see backendResult) is ejected and the call moves on to the next one.
+func (c *TLSAPIClient) onBackend(fn func(baseURL string) (backendResult, error)) error {
+	tried := make(map[*tlsBackend]bool)
+	for {
+		b := c.pool.acquire(tried)
+		tried[b] = true
+		res, err := fn(b.url)
+		c.pool.release(b, res)
+		if res != backendDown || len(tried) == len(c.pool.backends) {
+			return err
+		}
+		log.Printf("→ TLS-API backend %s failed, trying another: %v", b.url, err)
+	}
+}
+
+// Profiles returns the browser profiles supported by the TLS-API
+func (c *TLSAPIClient) Profiles() ([]string, error) {
+	var profiles []string
+	err := c.onBackend(func(baseURL string) (backendResult, error) {
+		httpReq, err := http.NewRequestWithContext(c.ctx, "GET", baseURL+TLSAPIProfilesPath, nil)
+		if err != nil {
+			return backendOK, NewError(PhaseTLSAPI, "create http request", err)
+		}
+		if c.authToken != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.authToken)
+		}
+		httpResp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			res := backendOK
+			if isConnError(err) {
+				res = backendDown
+			}
+			return res, NewError(PhaseTLSAPI, "list profiles", err).WithRetryable(true)
+		}
+		defer httpResp.Body.Close()
+
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return backendOK, NewError(PhaseTLSAPI, "read response body", err).WithRetryable(true)
+		}
+		if httpResp.StatusCode != http.StatusOK {
+			res := backendOK
+			if isBackendError(httpResp.StatusCode) {
+				res = backendDown
+			}
+			return res, NewErrorWithStatus(PhaseTLSAPI, "list profiles", httpResp.StatusCode, fmt.Errorf("body: %s", string(respBody)))
+		}
+		var out TLSProfilesResponse
+		if err := json.Unmarshal(respBody, &out); err != nil {
+			return backendOK, NewError(PhaseTLSAPI, "unmarshal profiles", err)
+		}
+		if out.Data == nil || len(out.Data.Profiles) == 0 {
+			return backendOK, NewError(PhaseTLSAPI, "list profiles", fmt.Errorf("empty profile list"))
+		}
+		profiles = out.Data.Profiles
+		return backendOK, nil
+	})
+	return profiles, err
+}
+
+// sendTo posts body to the TLS-API at baseURL; res reports whether the
+// backend failed. Errors after the TLS-API was reached are retryable only for
+// idempotent requests: the target may have received it.
+func (c *TLSAPIClient) sendTo(baseURL string, body []byte, idempotent bool) (resp *TLSResponse, res backendResult, err error) {
 	// Create HTTP request
-	url := c.baseURL + TLSAPIRequestPath
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	url := baseURL + TLSAPIRequestPath
+	httpReq, err := http.NewRequestWithContext(c.ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, NewError(PhaseTLSAPI, "create http request", err)
+		return nil, backendOK, NewError(PhaseTLSAPI, "create http request", err)
 	}
 
 	// Set headers
@@ -97,44 +250,62 @@ func (c *TLSAPIClient) Request(req TLSRequest) (*TLSResponse, error) {
 	}
 
 	// Execute request
-	resp, err := c.httpClient.Do(httpReq)
+	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, NewError(PhaseTLSAPI, "execute request", err).WithRetryable(true)
+		if isConnError(err) {
+			return nil, backendDown, NewError(PhaseTLSAPI, "execute request", err).WithRetryable(true)
+		}
+		return nil, backendOK, NewError(PhaseTLSAPI, "execute request", err).WithRetryable(idempotent)
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
 	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		return nil, NewError(PhaseTLSAPI, "read response body", err)
+		return nil, backendOK, NewError(PhaseTLSAPI, "read response body", err).WithRetryable(idempotent)
 	}
 
-	// Check for HTTP errors from TLS-API itself
-	if resp.StatusCode != http.StatusOK {
-		return nil, NewErrorWithStatus(
+	// Check for HTTP errors from TLS-API itself. A 5xx ejects the backend; an
+	// idempotent request moves on to another one. The status is kept for
+	// reporting but only drives retries of idempotent requests.
+	if httpResp.StatusCode != http.StatusOK {
+		err := NewErrorWithStatus(
 			PhaseTLSAPI,
 			"tls-api returned non-200",
-			resp.StatusCode,
+			httpResp.StatusCode,
 			fmt.Errorf("body: %s", string(respBody)),
 		)
+		res := backendOK
+		if isBackendError(httpResp.StatusCode) {
+			res = backendFailed
+			if idempotent {
+				res = backendDown
+			}
+		}
+		if !idempotent {
+			return nil, res, noRetry(err)
+		}
+		return nil, res, err
 	}
 
 	// Parse response
 	var tlsResp TLSResponse
 	if err := json.Unmarshal(respBody, &tlsResp); err != nil {
-		return nil, NewError(PhaseTLSAPI, "unmarshal response", err)
+		return nil, backendOK, NewError(PhaseTLSAPI, "unmarshal response", err)
 	}
 
 	// Check for API-level errors
 	if !tlsResp.Success && tlsResp.Error != nil {
-		return &tlsResp, c.handleAPIError(tlsResp.Error)
+		return &tlsResp, backendOK, c.handleAPIError(tlsResp.Error, idempotent)
 	}
 
-	return &tlsResp, nil
+	return &tlsResp, backendOK, nil
 }
 
-// handleAPIError converts a TLS-API error to a SolverError
-func (c *TLSAPIClient) handleAPIError(apiErr *TLSAPIError) *SolverError {
+// handleAPIError converts a TLS-API error to a SolverError. TLS and proxy
+// errors happen before the target is reached; site errors may happen after
+// it received the request, so they are retried only for idempotent requests.
+func (c *TLSAPIClient) handleAPIError(apiErr *TLSAPIError, idempotent bool) *SolverError {
 	err := &SolverError{
 		Phase:    PhaseTLSAPI,
 		Step:     apiErr.Type,
@@ -148,7 +319,7 @@ func (c *TLSAPIClient) handleAPIError(apiErr *TLSAPIError) *SolverError {
 	case "PROXY":
 		err.Retryable = true
 	case "SITE":
-		err.Retryable = true
+		err.Retryable = idempotent
 	case "VALIDATION":
 		err.Retryable = false
 	default:
@@ -160,7 +331,7 @@ func (c *TLSAPIClient) handleAPIError(apiErr *TLSAPIError) *SolverError {
 
 // GetStatus returns the status of the request target (not the TLS-API)
 func (r *TLSResponse) GetStatus() int {
-	if r.Data != nil {
+	if r != nil && r.Data != nil {
 		return r.Data.Status
 	}
 	return 0
@@ -196,25 +367,21 @@ func (r *TLSResponse) IsSuccess() bool {
 	return status >= 200 && status < 300
 }
 
-// Ping checks if the TLS-API service is reachable
+// Ping checks every TLS-API backend, updating their health, and fails when
+// none is reachable
 func (c *TLSAPIClient) Ping() error {
-	// Simple GET to base URL to check connectivity
-	resp, err := c.httpClient.Get(c.baseURL + "/health")
-	if err != nil {
-		return NewError(PhaseTLSAPI, "ping failed", err).WithRetryable(true)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return NewErrorWithStatus(PhaseTLSAPI, "health check failed", resp.StatusCode, nil)
-	}
-
-	return nil
+	return c.pool.CheckHealth()
 }
 
-// GetBaseURL returns the configured base URL
+// GetBaseURL returns the first healthy backend (see TLSAPIPool.BaseURL).
+// Requests are balanced over every healthy backend, not only this one.
 func (c *TLSAPIClient) GetBaseURL() string {
-	return c.baseURL
+	return c.pool.BaseURL()
+}
+
+// Pool returns the backends the client balances over
+func (c *TLSAPIClient) Pool() *TLSAPIPool {
+	return c.pool
 }
 
 // HasAuth returns whether authentication is configured
@@ -0,0 +1,303 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTLSAPIEjectDuration is how long a backend that failed a health
+	// check or a connection is skipped
+	DefaultTLSAPIEjectDuration = 30 * time.Second
+
+	// DefaultTLSAPIHealthInterval is the interval between health checks
+	DefaultTLSAPIHealthInterval = 10 * time.Second
+
+	// tlsAPIPingTimeout bounds a single health check
+	tlsAPIPingTimeout = 5 * time.Second
+)
+
+// ParseTLSAPIURLs splits a comma-separated list of TLS-API base URLs,
+// trimming spaces and trailing slashes and dropping empty or repeated entries
+func ParseTLSAPIURLs(s string) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// tlsBackend is one TLS-API replica
+type tlsBackend struct {
+	url          string
+	outstanding  int
+	failures     int // Consecutive failed checks or connections
+	ejectedUntil time.Time
+}
+
+// TLSAPIBackendStatus is a snapshot of one backend of a TLSAPIPool
+type TLSAPIBackendStatus struct {
+	URL          string
+	Healthy      bool
+	Outstanding  int       // Requests in flight
+	Failures     int       // Consecutive failed checks or connections
+	EjectedUntil time.Time // Zero when the backend is not ejected
+}
+
+// TLSAPIPool balances TLS-API requests across replicas. Each request goes to
+// the healthy backend with the fewest requests in flight; a backend that
+// fails a health check, refuses a connection or answers with a 5xx is
+// ejected for EjectDuration.
+// When every backend is ejected the one closest to re-admission is used.
+type TLSAPIPool struct {
+	mu         sync.Mutex
+	backends   []*tlsBackend
+	next       int // Rotates the start of the scan so ties are spread
+	ejectFor   time.Duration
+	httpClient *http.Client // Health checks only
+}
+
+var (
+	defaultTLSAPIPoolMu sync.RWMutex
+	defaultTLSAPIPool   *TLSAPIPool
+)
+
+// NewTLSAPIPool creates a pool for urls; an empty list falls back to
+// DefaultTLSAPIURL and a zero ejectFor to DefaultTLSAPIEjectDuration
+func NewTLSAPIPool(urls []string, ejectFor time.Duration) *TLSAPIPool {
+	if len(urls) == 0 {
+		urls = []string{DefaultTLSAPIURL}
+	}
+	if ejectFor <= 0 {
+		ejectFor = DefaultTLSAPIEjectDuration
+	}
+	p := &TLSAPIPool{
+		ejectFor:   ejectFor,
+		httpClient: &http.Client{Timeout: tlsAPIPingTimeout},
+	}
+	for _, u := range urls {
+		p.backends = append(p.backends, &tlsBackend{url: u})
+	}
+	return p
+}
+
+// DefaultTLSAPIPool returns the pool shared by all TLS-API clients, or nil
+// when none was set and each client builds its own from TLS_API_URL
+func DefaultTLSAPIPool() *TLSAPIPool {
+	defaultTLSAPIPoolMu.RLock()
+	defer defaultTLSAPIPoolMu.RUnlock()
+	return defaultTLSAPIPool
+}
+
+// SetDefaultTLSAPIPool sets the pool shared by all TLS-API clients, so health
+// and load are tracked across scrapers
+func SetDefaultTLSAPIPool(p *TLSAPIPool) {
+	defaultTLSAPIPoolMu.Lock()
+	defer defaultTLSAPIPoolMu.Unlock()
+	defaultTLSAPIPool = p
+}
+
+// URLs returns the configured backend URLs
+func (p *TLSAPIPool) URLs() []string {
+	urls := make([]string, len(p.backends))
+	for i, b := range p.backends {
+		urls[i] = b.url
+	}
+	return urls
+}
+
+// Status returns a snapshot of every backend
+func (p *TLSAPIPool) Status() []TLSAPIBackendStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make([]TLSAPIBackendStatus, 0, len(p.backends))
+	for _, b := range p.backends {
+		st := TLSAPIBackendStatus{
+			URL:         b.url,
+			Healthy:     !b.ejected(now),
+			Outstanding: b.outstanding,
+			Failures:    b.failures,
+		}
+		if !st.Healthy {
+			st.EjectedUntil = b.ejectedUntil
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func (b *tlsBackend) ejected(now time.Time) bool {
+	return now.Before(b.ejectedUntil)
+}
+
+// acquire picks the backend for the next request among those not in tried
+// and counts the request as in flight. It returns nil when all were tried.
+func (p *TLSAPIPool) acquire(tried map[*tlsBackend]bool) *tlsBackend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best, fallback *tlsBackend
+	n := len(p.backends)
+	for i := 0; i < n; i++ {
+		b := p.backends[(p.next+i)%n]
+		if tried[b] {
+			continue
+		}
+		if b.ejected(now) {
+			if fallback == nil || b.ejectedUntil.Before(fallback.ejectedUntil) {
+				fallback = b
+			}
+			continue
+		}
+		if best == nil || b.outstanding < best.outstanding {
+			best = b
+		}
+	}
+	p.next = (p.next + 1) % n
+	if best == nil {
+		best = fallback
+	}
+	if best != nil {
+		best.outstanding++
+	}
+	return best
+}
+
+// backendResult tells the pool how a call on one backend went
+type backendResult int
+
+const (
+	backendOK     backendResult = iota // The backend answered, even if the target failed
+	backendDown                        // Not reached or failed before forwarding: eject it and try another
+	backendFailed                      // Failed after the request may have been forwarded: eject it, do not resend
+)
+
+// release ends a request on b; a failed backend is ejected
+func (p *TLSAPIPool) release(b *tlsBackend, res backendResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.outstanding--
+	if res != backendOK {
+		p.eject(b)
+	}
+}
+
+// BaseURL returns the first healthy backend in the configured order or, when
+// every backend is ejected, the one re-admitted first
+func (p *TLSAPIPool) BaseURL() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var fallback *tlsBackend
+	for _, b := range p.backends {
+		if !b.ejected(now) {
+			return b.url
+		}
+		if fallback == nil || b.ejectedUntil.Before(fallback.ejectedUntil) {
+			fallback = b
+		}
+	}
+	return fallback.url
+}
+
+// eject marks b unhealthy for ejectFor; the caller holds p.mu
+func (p *TLSAPIPool) eject(b *tlsBackend) {
+	b.failures++
+	b.ejectedUntil = time.Now().Add(p.ejectFor)
+	log.Printf("→ TLS-API backend ejected: %s (failures=%d, for %s)", b.url, b.failures, p.ejectFor)
+}
+
+// CheckHealth pings every backend in parallel, ejecting those that fail and
+// re-admitting those that answer. It returns an error when none is healthy.
+func (p *TLSAPIPool) CheckHealth() error {
+	errs := make([]error, len(p.backends))
+	var wg sync.WaitGroup
+	for i, b := range p.backends {
+		wg.Add(1)
+		go func(i int, b *tlsBackend) {
+			defer wg.Done()
+			errs[i] = p.ping(b.url)
+		}(i, b)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var failed []string
+	for i, b := range p.backends {
+		if errs[i] != nil {
+			p.eject(b)
+			failed = append(failed, fmt.Sprintf("%s: %v", b.url, errs[i]))
+			continue
+		}
+		if b.failures > 0 {
+			log.Printf("→ TLS-API backend healthy again: %s", b.url)
+		}
+		b.failures = 0
+		b.ejectedUntil = time.Time{}
+	}
+	if len(failed) == len(p.backends) {
+		return NewError(PhaseTLSAPI, "no healthy backend", errors.New(strings.Join(failed, "; "))).WithRetryable(true)
+	}
+	return nil
+}
+
+// ping checks one backend's /health endpoint
+func (p *TLSAPIPool) ping(baseURL string) error {
+	resp, err := p.httpClient.Get(baseURL + "/health")
+	if err != nil {
+		return NewError(PhaseTLSAPI, "ping failed", err).WithRetryable(true)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NewErrorWithStatus(PhaseTLSAPI, "health check failed", resp.StatusCode, nil)
+	}
+	return nil
+}
+
+// Watch runs CheckHealth every interval until ctx is done
+func (p *TLSAPIPool) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.CheckHealth(); err != nil {
+				log.Printf("TLS-API health check: %v", err)
+			}
+		}
+	}
+}
+
+// isBackendError reports whether status is an error of the TLS-API itself
+// rather than a bad request
+func isBackendError(status int) bool {
+	return status >= 500
+}
+
+// isConnError reports whether err happened while connecting, so the request
+// never reached the TLS-API and can safely go to another backend
+func isConnError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tlsAPIStub answers /v1/request with status, counting calls
+func tlsAPIStub(t *testing.T, status int, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(TLSResponse{Success: true, Data: &TLSResponseData{Status: 200}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSAPIPoolFailsOverOn5xx(t *testing.T) {
+	var badCalls, goodCalls atomic.Int32
+	bad := tlsAPIStub(t, http.StatusBadGateway, &badCalls)
+	good := tlsAPIStub(t, http.StatusOK, &goodCalls)
+
+	pool := NewTLSAPIPool([]string{bad.URL, good.URL}, time.Minute)
+	client := NewTLSAPIClientWithPool(pool, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}, nil)
+
+	resp, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: "GET"})
+	if err != nil || resp.GetStatus() != 200 {
+		t.Fatalf("GET = %v, %v; want the healthy replica's answer", resp, err)
+	}
+	if badCalls.Load() != 1 || goodCalls.Load() != 1 {
+		t.Fatalf("calls bad=%d good=%d; want 1 and 1", badCalls.Load(), goodCalls.Load())
+	}
+	if st := pool.Status(); st[0].Healthy || !st[1].Healthy {
+		t.Fatalf("status %+v; want the 502 replica ejected", st)
+	}
+	if got := client.GetBaseURL(); got != good.URL {
+		t.Fatalf("GetBaseURL = %s; want the healthy replica %s", got, good.URL)
+	}
+}
+
+func TestTLSAPIPoolDoesNotResendPostsOn5xx(t *testing.T) {
+	var badCalls, goodCalls atomic.Int32
+	bad := tlsAPIStub(t, http.StatusInternalServerError, &badCalls)
+	good := tlsAPIStub(t, http.StatusOK, &goodCalls)
+
+	pool := NewTLSAPIPool([]string{bad.URL, good.URL}, time.Minute)
+	client := NewTLSAPIClientWithPool(pool, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, nil)
+
+	if _, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: "POST"}); err == nil {
+		t.Fatal("POST succeeded; want the 500 returned")
+	}
+	if badCalls.Load() != 1 || goodCalls.Load() != 0 {
+		t.Fatalf("calls bad=%d good=%d; want the post sent once", badCalls.Load(), goodCalls.Load())
+	}
+	if st := pool.Status(); st[0].Healthy {
+		t.Fatalf("status %+v; want the 500 replica ejected", st)
+	}
+
+	// The next request avoids the ejected replica
+	if _, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: "POST"}); err != nil {
+		t.Fatalf("second POST: %v", err)
+	}
+	if goodCalls.Load() != 1 {
+		t.Fatalf("second POST went to the ejected replica")
+	}
+}
+
+func TestTLSAPIPoolKeepsBackendOn4xx(t *testing.T) {
+	var calls atomic.Int32
+	srv := tlsAPIStub(t, http.StatusUnauthorized, &calls)
+	pool := NewTLSAPIPool([]string{srv.URL}, time.Minute)
+	client := NewTLSAPIClientWithPool(pool, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}, nil)
+
+	if _, err := client.Request(TLSRequest{URL: "https://www.example.com/"}); err == nil {
+		t.Fatal("want the 401 returned")
+	}
+	if st := pool.Status(); !st[0].Healthy {
+		t.Fatalf("status %+v; a 4xx must not eject the backend", st)
+	}
+}
+
+func TestTLSAPIPoolBaseURLWhenAllEjected(t *testing.T) {
+	pool := NewTLSAPIPool([]string{"http://a", "http://b"}, time.Minute)
+	pool.mu.Lock()
+	pool.backends[0].ejectedUntil = time.Now().Add(time.Minute)
+	pool.backends[1].ejectedUntil = time.Now().Add(time.Second)
+	pool.mu.Unlock()
+	if got := pool.BaseURL(); got != "http://b" {
+		t.Fatalf("BaseURL = %s; want the backend re-admitted first", got)
+	}
+}
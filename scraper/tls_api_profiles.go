@@ -0,0 +1,136 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTLSAPIProfilesRefresh is the default interval between refreshes of
+// the TLS-API profile list
+const DefaultTLSAPIProfilesRefresh = 5 * time.Minute
+
+// ErrUnsupportedProfile is returned by TLSAPIProfiles.Check for a browser
+// profile the TLS-API does not support
+var ErrUnsupportedProfile = errors.New("browser profile not supported by the TLS-API")
+
+// TLSAPIProfiles caches the browser profiles supported by the TLS-API so
+// requests can be validated before the flow starts. Until the first
+// successful refresh every profile is accepted; a failed refresh keeps the
+// previous list.
+type TLSAPIProfiles struct {
+	client *TLSAPIClient
+
+	mu        sync.RWMutex
+	supported map[string]bool
+	list      []string
+	fetchedAt time.Time
+	lastErr   error
+}
+
+var (
+	defaultTLSAPIProfilesMu sync.RWMutex
+	defaultTLSAPIProfiles   *TLSAPIProfiles
+)
+
+// NewTLSAPIProfiles creates an empty cache that refreshes through client
+func NewTLSAPIProfiles(client *TLSAPIClient) *TLSAPIProfiles {
+	return &TLSAPIProfiles{client: client}
+}
+
+// DefaultTLSAPIProfiles returns the cache used by NewScraper, or nil when
+// profiles are not validated
+func DefaultTLSAPIProfiles() *TLSAPIProfiles {
+	defaultTLSAPIProfilesMu.RLock()
+	defer defaultTLSAPIProfilesMu.RUnlock()
+	return defaultTLSAPIProfiles
+}
+
+// SetDefaultTLSAPIProfiles sets the cache used by NewScraper
+func SetDefaultTLSAPIProfiles(p *TLSAPIProfiles) {
+	defaultTLSAPIProfilesMu.Lock()
+	defer defaultTLSAPIProfilesMu.Unlock()
+	defaultTLSAPIProfiles = p
+}
+
+// Refresh fetches the profile list from the TLS-API
+func (p *TLSAPIProfiles) Refresh() error {
+	profiles, err := p.client.Profiles()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastErr = err
+	if err != nil {
+		return err
+	}
+	supported := make(map[string]bool, len(profiles))
+	list := make([]string, 0, len(profiles))
+	for _, name := range profiles {
+		if name = strings.TrimSpace(name); name != "" && !supported[name] {
+			supported[name] = true
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	p.supported, p.list, p.fetchedAt = supported, list, time.Now()
+	return nil
+}
+
+// Watch refreshes the list every interval until ctx is done
+func (p *TLSAPIProfiles) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Refresh(); err != nil {
+				log.Printf("TLS-API profiles refresh failed (keeping previous): %v", err)
+			}
+		}
+	}
+}
+
+// Known reports whether the list was fetched at least once
+func (p *TLSAPIProfiles) Known() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.supported != nil
+}
+
+// List returns the supported profiles (sorted), when they were fetched and
+// the error of the last refresh
+func (p *TLSAPIProfiles) List() ([]string, time.Time, error) {
+	if p == nil {
+		return nil, time.Time{}, nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.list...), p.fetchedAt, p.lastErr
+}
+
+// Check returns ErrUnsupportedProfile (wrapped with the supported list) when
+// the TLS-API is known not to support profile
+func (p *TLSAPIProfiles) Check(profile string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.supported == nil || p.supported[profile] {
+		return nil
+	}
+	return fmt.Errorf("%w: %q (supported: %s)", ErrUnsupportedProfile, profile, strings.Join(p.list, ", "))
+}
@@ -0,0 +1,149 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// profilesStub answers /v1/profiles with whatever set() last configured
+type profilesStub struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	profiles []string
+}
+
+func newProfilesStub(t *testing.T, profiles ...string) *profilesStub {
+	t.Helper()
+	s := &profilesStub{status: http.StatusOK, profiles: profiles}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != TLSAPIProfilesPath {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		status, profiles := s.status, s.profiles
+		s.mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": map[string][]string{"profiles": profiles}})
+	}))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *profilesStub) set(status int, profiles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.profiles = status, profiles
+}
+
+func (s *profilesStub) profilesCache() *TLSAPIProfiles {
+	client := NewTLSAPIClientWithConfig(s.srv.URL, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}, nil)
+	return NewTLSAPIProfiles(client)
+}
+
+func TestTLSAPIProfilesRefresh(t *testing.T) {
+	stub := newProfilesStub(t, "firefox_135", " chrome_144 ", "chrome_144", "")
+	p := stub.profilesCache()
+
+	if err := p.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	list, fetchedAt, err := p.List()
+	if !reflect.DeepEqual(list, []string{"chrome_144", "firefox_135"}) || fetchedAt.IsZero() || err != nil {
+		t.Fatalf("List = %v, %v, %v; want the trimmed, sorted, deduplicated list", list, fetchedAt, err)
+	}
+	if !p.Known() || p.Check("chrome_144") != nil {
+		t.Fatal("chrome_144 rejected after refresh")
+	}
+	if err := p.Check("safari_ios_18_5"); !errors.Is(err, ErrUnsupportedProfile) {
+		t.Fatalf("Check(safari_ios_18_5) = %v; want ErrUnsupportedProfile", err)
+	}
+
+	// A new TLS-API build replaces the list
+	stub.set(http.StatusOK, "chrome_146", "safari_ios_18_5")
+	if err := p.Refresh(); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+	if p.Check("chrome_144") == nil || p.Check("safari_ios_18_5") != nil {
+		t.Fatal("list not replaced by the second refresh")
+	}
+}
+
+func TestTLSAPIProfilesFallback(t *testing.T) {
+	stub := newProfilesStub(t)
+	stub.set(http.StatusBadGateway)
+	p := stub.profilesCache()
+
+	// Until the TLS-API answers every profile is accepted
+	if err := p.Refresh(); err == nil {
+		t.Fatal("Refresh succeeded against a 502")
+	}
+	if p.Known() || p.Check("anything_1") != nil {
+		t.Fatal("profile rejected before the TLS-API ever answered")
+	}
+	if _, _, err := p.List(); err == nil {
+		t.Fatal("List does not report the failed refresh")
+	}
+
+	// An empty list is a failure too
+	stub.set(http.StatusOK)
+	if err := p.Refresh(); err == nil || p.Known() {
+		t.Fatalf("Refresh with an empty list = %v; want an error", err)
+	}
+
+	// A failed refresh keeps the previous list
+	stub.set(http.StatusOK, "chrome_144")
+	if err := p.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	stub.srv.Close()
+	if err := p.Refresh(); err == nil {
+		t.Fatal("Refresh succeeded with the TLS-API down")
+	}
+	list, _, err := p.List()
+	if !reflect.DeepEqual(list, []string{"chrome_144"}) || err == nil {
+		t.Fatalf("List = %v, %v; want the previous list and the refresh error", list, err)
+	}
+	if p.Check("chrome_144") != nil || p.Check("firefox_135") == nil {
+		t.Fatal("previous list not enforced while the TLS-API is down")
+	}
+
+	// No cache configured: nothing is checked
+	var none *TLSAPIProfiles
+	if none.Check("edge_1") != nil || none.Known() {
+		t.Fatal("nil cache rejected a profile")
+	}
+}
+
+func TestProfileFamily(t *testing.T) {
+	for profile, want := range map[string]string{
+		"chrome_133":      FamilyChrome,
+		"chrome_146":      FamilyChrome,
+		"firefox_135":     FamilyFirefox,
+		"safari_ios_18_5": FamilySafari,
+		"edge_120":        "",
+		"chromium_1":      "",
+		"":                "",
+	} {
+		if got := ProfileFamily(profile); got != want {
+			t.Errorf("ProfileFamily(%q) = %q; want %q", profile, got, want)
+		}
+	}
+	for _, p := range KnownProfileTypes {
+		if ProfileFamily(p) == "" {
+			t.Errorf("known profile %s has no header family", p)
+		}
+	}
+}
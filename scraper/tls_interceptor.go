@@ -0,0 +1,66 @@
+package scraper
+
+import "sync"
+
+// TLSRoundTripFunc sends one TLS-API request attempt
+type TLSRoundTripFunc func(req TLSRequest) (*TLSResponse, error)
+
+// TLSInterceptor wraps a round trip, like an http.RoundTripper wrapper: it
+// may inspect or change the request, call next (or not) and inspect or
+// replace the response. Interceptors run once per attempt, inside the
+// TLS-API retry loop, so retries pass through them again.
+type TLSInterceptor func(next TLSRoundTripFunc) TLSRoundTripFunc
+
+var (
+	defaultTLSInterceptorsMu sync.RWMutex
+	defaultTLSInterceptors   []TLSInterceptor
+)
+
+// DefaultTLSInterceptors returns the interceptors every TLS-API client runs,
+// outside its own
+func DefaultTLSInterceptors() []TLSInterceptor {
+	defaultTLSInterceptorsMu.RLock()
+	defer defaultTLSInterceptorsMu.RUnlock()
+	return append([]TLSInterceptor(nil), defaultTLSInterceptors...)
+}
+
+// SetDefaultTLSInterceptors replaces the global interceptors, the first
+// being the outermost. Requests already running keep the previous chain.
+func SetDefaultTLSInterceptors(interceptors ...TLSInterceptor) {
+	defaultTLSInterceptorsMu.Lock()
+	defer defaultTLSInterceptorsMu.Unlock()
+	defaultTLSInterceptors = nil
+	for _, i := range interceptors {
+		if i != nil {
+			defaultTLSInterceptors = append(defaultTLSInterceptors, i)
+		}
+	}
+}
+
+// Use adds interceptors to this client, inside the global ones. The first
+// interceptor added is the outermost.
+func (c *TLSAPIClient) Use(interceptors ...TLSInterceptor) {
+	for _, i := range interceptors {
+		if i != nil {
+			c.interceptors = append(c.interceptors, i)
+		}
+	}
+}
+
+// chain wraps send with the global interceptors, then the client's
+func (c *TLSAPIClient) chain(send TLSRoundTripFunc) TLSRoundTripFunc {
+	all := append(DefaultTLSInterceptors(), c.interceptors...)
+
+	rt := send
+	for i := len(all) - 1; i >= 0; i-- {
+		rt = all[i](rt)
+	}
+	return rt
+}
+
+// Step returns the timeline step the request belongs to ("" for requests
+// sent outside a scraper flow)
+func (r TLSRequest) Step() TimelineStep { return r.step }
+
+// Provider returns the provider a provider_call request is sent for
+func (r TLSRequest) Provider() string { return r.provider }
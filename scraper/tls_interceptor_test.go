@@ -0,0 +1,153 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echoTLSAPI answers every request with the X-Test header it was sent as body
+func echoTLSAPI(t *testing.T, calls *atomic.Int32) *TLSAPIClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req TLSRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(TLSResponse{Success: true, Data: &TLSResponseData{Status: 200, Body: req.Headers["X-Test"]}})
+	}))
+	t.Cleanup(srv.Close)
+	client := NewTLSAPIClientWithConfig(srv.URL, "", time.Second)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1}, nil)
+	return client
+}
+
+// withGlobalInterceptors sets the global interceptors for the test
+func withGlobalInterceptors(t *testing.T, interceptors ...TLSInterceptor) {
+	t.Helper()
+	prev := DefaultTLSInterceptors()
+	SetDefaultTLSInterceptors(interceptors...)
+	t.Cleanup(func() { SetDefaultTLSInterceptors(prev...) })
+}
+
+// tracing records when it is entered and left
+func tracing(name string, trace *[]string) TLSInterceptor {
+	return func(next TLSRoundTripFunc) TLSRoundTripFunc {
+		return func(req TLSRequest) (*TLSResponse, error) {
+			*trace = append(*trace, ">"+name)
+			resp, err := next(req)
+			*trace = append(*trace, "<"+name)
+			return resp, err
+		}
+	}
+}
+
+func TestTLSInterceptorOrder(t *testing.T) {
+	var trace []string
+	withGlobalInterceptors(t, tracing("global1", &trace), nil, tracing("global2", &trace))
+	var calls atomic.Int32
+	client := echoTLSAPI(t, &calls)
+	client.Use(tracing("client1", &trace), nil)
+	client.Use(tracing("client2", &trace))
+
+	if _, err := client.Request(TLSRequest{URL: "https://www.example.com/", Method: "GET"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{">global1", ">global2", ">client1", ">client2", "<client2", "<client1", "<global2", "<global1"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %v; want %v", trace, want)
+	}
+	if len(DefaultTLSInterceptors()) != 2 {
+		t.Errorf("nil global interceptor kept: %d", len(DefaultTLSInterceptors()))
+	}
+
+	// Other clients only run the global interceptors
+	trace = nil
+	if _, err := echoTLSAPI(t, &calls).Request(TLSRequest{URL: "https://www.example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{">global1", ">global2", "<global2", "<global1"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("other client trace = %v; want %v", trace, want)
+	}
+}
+
+func TestTLSInterceptorShortCircuit(t *testing.T) {
+	var trace []string
+	canned := &TLSResponse{Success: true, Data: &TLSResponseData{Status: 204, Body: "from cache"}}
+	withGlobalInterceptors(t, func(next TLSRoundTripFunc) TLSRoundTripFunc {
+		return func(req TLSRequest) (*TLSResponse, error) {
+			if req.Step() == TimelineScriptFetch {
+				return canned, nil
+			}
+			return next(req)
+		}
+	})
+	var calls atomic.Int32
+	client := echoTLSAPI(t, &calls)
+	client.Use(tracing("client", &trace))
+
+	resp, err := client.Request(TLSRequest{URL: "https://www.example.com/s.js", step: TimelineScriptFetch})
+	if err != nil || resp != canned {
+		t.Fatalf("Request = %+v, %v; want the canned response", resp, err)
+	}
+	if calls.Load() != 0 || trace != nil {
+		t.Errorf("short-circuited request reached calls=%d trace=%v", calls.Load(), trace)
+	}
+
+	// Other steps go through
+	if resp, err := client.Request(TLSRequest{URL: "https://www.example.com/", step: TimelineHomepage}); err != nil || resp == canned || calls.Load() != 1 {
+		t.Errorf("homepage = %+v, %v, calls=%d", resp, err, calls.Load())
+	}
+}
+
+func TestTLSInterceptorRewrites(t *testing.T) {
+	var calls atomic.Int32
+	client := echoTLSAPI(t, &calls)
+	var seen []string
+	client.Use(
+		// Outer: sees the response the inner interceptor rewrote
+		func(next TLSRoundTripFunc) TLSRoundTripFunc {
+			return func(req TLSRequest) (*TLSResponse, error) {
+				resp, err := next(req)
+				if err == nil {
+					seen = append(seen, resp.GetBody())
+					resp.Data.Body = "outer(" + resp.Data.Body + ")"
+				}
+				return resp, err
+			}
+		},
+		// Inner: adds a header to the request and wraps the body
+		func(next TLSRoundTripFunc) TLSRoundTripFunc {
+			return func(req TLSRequest) (*TLSResponse, error) {
+				headers := map[string]string{"X-Test": "signed"}
+				for k, v := range req.Headers {
+					headers[k] = v
+				}
+				req.Headers = headers
+				resp, err := next(req)
+				if err == nil {
+					resp.Data.Body = "inner(" + resp.Data.Body + ")"
+				}
+				return resp, err
+			}
+		},
+	)
+
+	orig := TLSRequest{URL: "https://www.example.com/", Headers: map[string]string{"Accept": "*/*"}}
+	resp, err := client.Request(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.GetBody(); got != "outer(inner(signed))" {
+		t.Errorf("body = %q; want the server's echo rewritten inside out", got)
+	}
+	if !reflect.DeepEqual(seen, []string{"inner(signed)"}) {
+		t.Errorf("outer saw %v", seen)
+	}
+	if _, ok := orig.Headers["X-Test"]; ok {
+		t.Error("interceptor changed the caller's headers")
+	}
+}
@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,31 @@ import (
 // DefaultBrowser is the default TLS profile when none specified
 const DefaultBrowser = "chrome_133"
 
+// Browser families with a header set in SiteClient. The TLS fingerprint comes
+// from the TLS-API profile; the headers only depend on the family.
+const (
+	FamilyChrome  = "chrome"
+	FamilyFirefox = "firefox"
+	FamilySafari  = "safari"
+)
+
+// KnownProfileTypes lists the profiles the header sets were captured from. The
+// accepted profiles are the ones the TLS-API reports (TLSAPIProfiles); this
+// list only serves as an example until the TLS-API answers.
+var KnownProfileTypes = []string{"chrome_133", "chrome_144", "firefox_135", "safari_ios_18_5"}
+
+// ProfileFamily returns the header family of a TLS-API profile
+// (chrome_144 → chrome, safari_ios_18_5 → safari), or "" when no header set
+// matches it
+func ProfileFamily(profile string) string {
+	family, _, _ := strings.Cut(profile, "_")
+	switch family {
+	case FamilyChrome, FamilyFirefox, FamilySafari:
+		return family
+	}
+	return ""
+}
+
 // GetBrowser returns the configured browser or default from Config
 // This will be a method on Config after Phase 6 refactoring
 func GetBrowser(browser string) string {
@@ -82,6 +108,10 @@ type TLSRequest struct {
 	Proxy         string            `json:"proxy,omitempty"`
 	Timeout       int               `json:"timeout,omitempty"`
 	ReturnCookies bool              `json:"returnCookies"`
+
+	// Timeline labels, not sent to the TLS-API
+	step     TimelineStep
+	provider string
 }
 
 // TLSResponse represents a response from the TLS-API
@@ -92,6 +122,14 @@ type TLSResponse struct {
 	Metadata *TLSMetadata     `json:"metadata,omitempty"`
 }
 
+// TLSProfilesResponse is the body of GET /v1/profiles on the TLS-API
+type TLSProfilesResponse struct {
+	Success bool `json:"success"`
+	Data    *struct {
+		Profiles []string `json:"profiles"`
+	} `json:"data,omitempty"`
+}
+
 // TLSResponseData contains the actual response data
 type TLSResponseData struct {
 	Status  int               `json:"status"`
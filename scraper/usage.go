@@ -0,0 +1,444 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTenant is used when a solve carries no tenant
+	DefaultTenant = "default"
+
+	// usageRetentionDays is how long daily counters are kept
+	usageRetentionDays = 400
+
+	usageDayLayout = "2006-01-02"
+)
+
+// UsageLimit is a daily/monthly budget of provider calls. Zero means unlimited.
+type UsageLimit struct {
+	Daily   int64 `json:"daily,omitempty"`
+	Monthly int64 `json:"monthly,omitempty"`
+}
+
+// UsageBudgets holds the budgets enforced before each provider call.
+// The "*" key in Tenants or Domains applies to every tenant/domain without
+// an explicit entry; Providers budgets are global per provider.
+type UsageBudgets struct {
+	Providers map[string]UsageLimit `json:"providers,omitempty"`
+	Tenants   map[string]UsageLimit `json:"tenants,omitempty"`
+	Domains   map[string]UsageLimit `json:"domains,omitempty"`
+}
+
+// LoadUsageBudgets reads budgets from a JSON file
+func LoadUsageBudgets(path string) (UsageBudgets, error) {
+	var b UsageBudgets
+	if path == "" {
+		return b, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return b, fmt.Errorf("read usage budgets: %w", err)
+	}
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return b, fmt.Errorf("parse usage budgets %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// UsageRecord is the number of provider calls for one day, tenant, provider and domain
+type UsageRecord struct {
+	Day      string `json:"day"`
+	Tenant   string `json:"tenant"`
+	Provider string `json:"provider"`
+	Domain   string `json:"domain"`
+	Calls    int64  `json:"calls"`
+}
+
+// UsageFilter selects records for a usage report. Empty fields match everything.
+type UsageFilter struct {
+	From     string // First day (YYYY-MM-DD), inclusive
+	To       string // Last day (YYYY-MM-DD), inclusive
+	Tenant   string
+	Provider string
+	Domain   string
+}
+
+// UsageReport aggregates usage records for reconciliation
+type UsageReport struct {
+	From       string           `json:"from,omitempty"`
+	To         string           `json:"to,omitempty"`
+	Records    []UsageRecord    `json:"records"`
+	ByProvider map[string]int64 `json:"by_provider"`
+	ByTenant   map[string]int64 `json:"by_tenant"`
+	ByDomain   map[string]int64 `json:"by_domain"`
+	Total      int64            `json:"total"`
+}
+
+// UsageTracker counts provider calls and enforces budgets before dispatch.
+// Several processes may share the file (the server and cookiegen): each
+// write merges this process's new calls into the file under lockFile, and
+// Reserve reloads the file when another process changed it.
+type UsageTracker struct {
+	mu        sync.Mutex
+	file      *debouncedFile
+	budgets   UsageBudgets
+	records   map[string]*UsageRecord
+	totals    map[string]int64 // Calls per scope, name and day or month (see totalKey)
+	prunedDay string           // Day of the last prune; records are pruned once a day
+	now       func() time.Time
+
+	pending map[string]*UsageRecord // Calls counted since the last write, not yet in the file
+	modTime time.Time               // File state when records were last synced
+	size    int64
+}
+
+var (
+	defaultUsageTrackerMu sync.RWMutex
+	defaultUsageTracker   = NewUsageTracker("", UsageBudgets{})
+)
+
+// NewUsageTracker creates a tracker persisted to path (if not empty).
+// Writes are debounced by DefaultSaveDelay; call Flush before exiting.
+func NewUsageTracker(path string, budgets UsageBudgets) *UsageTracker {
+	ut := &UsageTracker{
+		budgets: budgets,
+		records: make(map[string]*UsageRecord),
+		totals:  make(map[string]int64),
+		now:     time.Now,
+		pending: make(map[string]*UsageRecord),
+	}
+	ut.file = newMergedFile(path, ut.save)
+	return ut
+}
+
+func defaultUsagePath() string {
+	return filepath.Join(DefaultProviderCacheDir(), "provider-usage.json")
+}
+
+// LoadUsageTracker loads persisted counters from path. A file that cannot be
+// read or parsed is an error, so it is not overwritten with empty counters.
+func LoadUsageTracker(path string, budgets UsageBudgets) (*UsageTracker, error) {
+	ut := NewUsageTracker(path, budgets)
+	raw, info, err := readUsageFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ut.rebuildLocked(raw, info)
+	return ut, nil
+}
+
+// readUsageFile reads the records stored at path; a missing file has none
+func readUsageFile(path string) ([]UsageRecord, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("read provider usage: %w", err)
+	}
+	defer f.Close()
+	// Stat the open file: the stamp must describe the content read
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read provider usage: %w", err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read provider usage: %w", err)
+	}
+	if len(b) == 0 {
+		return nil, info, nil
+	}
+	var raw []UsageRecord
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, nil, fmt.Errorf("parse provider usage %s: %w", path, err)
+	}
+	return raw, info, nil
+}
+
+// LoadUsageTrackerDefault loads counters stored next to the provider cache
+func LoadUsageTrackerDefault(budgets UsageBudgets) (*UsageTracker, error) {
+	return LoadUsageTracker(defaultUsagePath(), budgets)
+}
+
+// DefaultUsageTracker returns the tracker shared by all solvers
+func DefaultUsageTracker() *UsageTracker {
+	defaultUsageTrackerMu.RLock()
+	defer defaultUsageTrackerMu.RUnlock()
+	return defaultUsageTracker
+}
+
+// SetDefaultUsageTracker replaces the tracker shared by all solvers
+func SetDefaultUsageTracker(ut *UsageTracker) {
+	if ut == nil {
+		ut = NewUsageTracker("", UsageBudgets{})
+	}
+	defaultUsageTrackerMu.Lock()
+	defer defaultUsageTrackerMu.Unlock()
+	defaultUsageTracker = ut
+}
+
+// SetBudgets replaces the enforced budgets
+func (ut *UsageTracker) SetBudgets(b UsageBudgets) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	ut.budgets = b
+}
+
+// Reserve checks every applicable budget and, if none is exhausted, counts
+// one provider call. It returns a PhaseQuota error when a budget is exceeded.
+func (ut *UsageTracker) Reserve(tenant, provider, domain string) error {
+	if ut == nil {
+		return nil
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	ut.refreshLocked()
+
+	now := ut.now()
+	day := now.Format(usageDayLayout)
+	month := day[:7]
+
+	checks := []struct {
+		scope string
+		name  string
+		limit UsageLimit
+	}{
+		{"provider", provider, ut.budgets.Providers[provider]},
+		{"tenant", tenant, lookupLimit(ut.budgets.Tenants, tenant)},
+		{"domain", domain, lookupLimit(ut.budgets.Domains, domain)},
+	}
+	for _, c := range checks {
+		if c.limit.Daily == 0 && c.limit.Monthly == 0 {
+			continue
+		}
+		daily := ut.totals[totalKey(c.scope, c.name, day)]
+		monthly := ut.totals[totalKey(c.scope, c.name, month)]
+		if c.limit.Daily > 0 && daily >= c.limit.Daily {
+			return ut.quotaError(c.scope, provider, domain, fmt.Errorf("daily %s budget exceeded for %s: %d/%d calls", c.scope, c.name, daily, c.limit.Daily))
+		}
+		if c.limit.Monthly > 0 && monthly >= c.limit.Monthly {
+			return ut.quotaError(c.scope, provider, domain, fmt.Errorf("monthly %s budget exceeded for %s: %d/%d calls", c.scope, c.name, monthly, c.limit.Monthly))
+		}
+	}
+
+	k := usageKey(day, tenant, provider, domain)
+	r, ok := ut.records[k]
+	if !ok {
+		r = &UsageRecord{Day: day, Tenant: tenant, Provider: provider, Domain: domain}
+		ut.records[k] = r
+	}
+	r.Calls++
+	ut.addTotals(r, 1)
+	if p, ok := ut.pending[k]; ok {
+		p.Calls++
+	} else {
+		ut.pending[k] = &UsageRecord{Day: day, Tenant: tenant, Provider: provider, Domain: domain, Calls: 1}
+	}
+	ut.pruneLocked(now)
+	ut.file.schedule()
+	return nil
+}
+
+// Flush writes pending changes to disk now
+func (ut *UsageTracker) Flush() error {
+	if ut == nil {
+		return nil
+	}
+	return ut.file.flush()
+}
+
+// Report returns the records matching f with per-provider/tenant/domain totals
+func (ut *UsageTracker) Report(f UsageFilter) *UsageReport {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	rep := &UsageReport{
+		From:       f.From,
+		To:         f.To,
+		Records:    make([]UsageRecord, 0),
+		ByProvider: make(map[string]int64),
+		ByTenant:   make(map[string]int64),
+		ByDomain:   make(map[string]int64),
+	}
+	for _, r := range ut.records {
+		if f.From != "" && r.Day < f.From {
+			continue
+		}
+		if f.To != "" && r.Day > f.To {
+			continue
+		}
+		if (f.Tenant != "" && r.Tenant != f.Tenant) ||
+			(f.Provider != "" && r.Provider != f.Provider) ||
+			(f.Domain != "" && r.Domain != f.Domain) {
+			continue
+		}
+		rep.Records = append(rep.Records, *r)
+		rep.ByProvider[r.Provider] += r.Calls
+		rep.ByTenant[r.Tenant] += r.Calls
+		rep.ByDomain[r.Domain] += r.Calls
+		rep.Total += r.Calls
+	}
+	sort.Slice(rep.Records, func(i, j int) bool {
+		a, b := rep.Records[i], rep.Records[j]
+		return usageKey(a.Day, a.Tenant, a.Provider, a.Domain) < usageKey(b.Day, b.Tenant, b.Provider, b.Domain)
+	})
+	return rep
+}
+
+// quotaError names the exhausted budget in Step ("provider quota exceeded"),
+// so the solvers only fall back to another provider when that one has its own
+func (ut *UsageTracker) quotaError(scope, provider, domain string, err error) *SolverError {
+	return NewErrorWithProvider(PhaseQuota, scope+" quota exceeded", provider, err).WithDomain(domain)
+}
+
+// pruneLocked drops records past the retention, once a day
+func (ut *UsageTracker) pruneLocked(now time.Time) {
+	day := now.Format(usageDayLayout)
+	if day == ut.prunedDay {
+		return
+	}
+	ut.prunedDay = day
+	cutoff := now.AddDate(0, 0, -usageRetentionDays).Format(usageDayLayout)
+	for k, r := range ut.records {
+		if r.Day < cutoff {
+			ut.addTotals(r, -r.Calls)
+			delete(ut.records, k)
+		}
+	}
+}
+
+// addTotals adds n calls of r to the per-scope daily and monthly totals
+func (ut *UsageTracker) addTotals(r *UsageRecord, n int64) {
+	for _, sc := range [][2]string{{"provider", r.Provider}, {"tenant", r.Tenant}, {"domain", r.Domain}} {
+		for _, period := range []string{r.Day, r.Day[:min(len(r.Day), 7)]} {
+			k := totalKey(sc[0], sc[1], period)
+			if ut.totals[k] += n; ut.totals[k] == 0 {
+				delete(ut.totals, k)
+			}
+		}
+	}
+}
+
+// save merges the calls counted since the last write into the file. The
+// file is re-read under lockFile so calls counted by other processes are
+// kept; pending calls stay pending if the write fails.
+func (ut *UsageTracker) save(path string) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	raw, _, err := readUsageFile(path)
+	if err != nil {
+		return err
+	}
+	merged := make(map[string]*UsageRecord, len(raw)+len(ut.pending))
+	for i := range raw {
+		r := raw[i]
+		merged[usageKey(r.Day, r.Tenant, r.Provider, r.Domain)] = &r
+	}
+	for k, p := range ut.pending {
+		if r, ok := merged[k]; ok {
+			r.Calls += p.Calls
+		} else {
+			r := *p
+			merged[k] = &r
+		}
+	}
+	cutoff := ut.now().AddDate(0, 0, -usageRetentionDays).Format(usageDayLayout)
+	list := make([]UsageRecord, 0, len(merged))
+	for _, r := range merged {
+		if r.Day >= cutoff {
+			list = append(list, *r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		return usageKey(a.Day, a.Tenant, a.Provider, a.Domain) < usageKey(b.Day, b.Tenant, b.Provider, b.Domain)
+	})
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, b); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	ut.pending = make(map[string]*UsageRecord)
+	ut.rebuildLocked(list, info)
+	return nil
+}
+
+// refreshLocked reloads the file when another process wrote it since the
+// last sync. Changes are detected by mtime and size: a same-size write within
+// the mtime granularity is only seen at the next one, or at the next save.
+func (ut *UsageTracker) refreshLocked() {
+	if ut.file.path == "" {
+		return
+	}
+	info, err := os.Stat(ut.file.path)
+	if err != nil || (info.ModTime().Equal(ut.modTime) && info.Size() == ut.size) {
+		return
+	}
+	raw, info, err := readUsageFile(ut.file.path)
+	if err != nil || info == nil {
+		return
+	}
+	ut.rebuildLocked(raw, info)
+}
+
+// rebuildLocked replaces the records with the file's plus the pending calls
+func (ut *UsageTracker) rebuildLocked(raw []UsageRecord, info os.FileInfo) {
+	ut.records = make(map[string]*UsageRecord, len(raw))
+	ut.totals = make(map[string]int64)
+	for i := range raw {
+		r := raw[i]
+		ut.records[usageKey(r.Day, r.Tenant, r.Provider, r.Domain)] = &r
+		ut.addTotals(&r, r.Calls)
+	}
+	for k, p := range ut.pending {
+		r, ok := ut.records[k]
+		if !ok {
+			r = &UsageRecord{Day: p.Day, Tenant: p.Tenant, Provider: p.Provider, Domain: p.Domain}
+			ut.records[k] = r
+		}
+		r.Calls += p.Calls
+		ut.addTotals(r, p.Calls)
+	}
+	if info != nil {
+		ut.modTime, ut.size = info.ModTime(), info.Size()
+	}
+}
+
+func lookupLimit(m map[string]UsageLimit, name string) UsageLimit {
+	if l, ok := m[name]; ok {
+		return l
+	}
+	return m["*"]
+}
+
+func usageKey(day, tenant, provider, domain string) string {
+	return fmt.Sprintf("%s|%s|%s|%s", day, tenant, provider, domain)
+}
+
+// totalKey indexes totals; period is a day (YYYY-MM-DD) or a month (YYYY-MM)
+func totalKey(scope, name, period string) string {
+	return scope + "|" + name + "|" + period
+}
@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedClock returns a now func reading *t, so tests can move the day
+func fixedClock(t *time.Time) func() time.Time {
+	return func() time.Time { return *t }
+}
+
+func reserveN(t *testing.T, ut *UsageTracker, n int, tenant, provider, domain string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := ut.Reserve(tenant, provider, domain); err != nil {
+			t.Fatalf("Reserve #%d: %v", i+1, err)
+		}
+	}
+}
+
+func TestUsageBudgets(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		budgets UsageBudgets
+		tenant  string
+		domain  string
+		allowed int
+		want    string
+	}{
+		{"provider daily", UsageBudgets{Providers: map[string]UsageLimit{"hyper": {Daily: 2}}}, "acme", "a.com", 2, "daily provider budget exceeded for hyper"},
+		{"provider monthly", UsageBudgets{Providers: map[string]UsageLimit{"hyper": {Monthly: 3}}}, "acme", "a.com", 3, "monthly provider budget exceeded"},
+		{"tenant", UsageBudgets{Tenants: map[string]UsageLimit{"acme": {Daily: 1}}}, "acme", "a.com", 1, "daily tenant budget exceeded for acme"},
+		{"tenant wildcard", UsageBudgets{Tenants: map[string]UsageLimit{"*": {Daily: 1}, "vip": {Daily: 5}}}, "acme", "a.com", 1, "tenant budget exceeded for acme"},
+		{"empty tenant is default", UsageBudgets{Tenants: map[string]UsageLimit{DefaultTenant: {Daily: 1}}}, "", "a.com", 1, "for " + DefaultTenant},
+		{"domain", UsageBudgets{Domains: map[string]UsageLimit{"a.com": {Daily: 2}}}, "acme", "a.com", 2, "daily domain budget exceeded for a.com"},
+	} {
+		ut := NewUsageTracker("", c.budgets)
+		reserveN(t, ut, c.allowed, c.tenant, "hyper", c.domain)
+		err := ut.Reserve(c.tenant, "hyper", c.domain)
+		var se *SolverError
+		if !errors.As(err, &se) || se.Phase != PhaseQuota || se.Provider != "hyper" || !strings.Contains(se.RawError, c.want) {
+			t.Errorf("%s: err = %v; want a QUOTA error mentioning %q", c.name, err, c.want)
+			continue
+		}
+		// The rejected call is not counted
+		if total := ut.Report(UsageFilter{}).Total; total != int64(c.allowed) {
+			t.Errorf("%s: Total = %d; want %d", c.name, total, c.allowed)
+		}
+	}
+
+	// Other providers, tenants and domains have their own budgets
+	ut := NewUsageTracker("", UsageBudgets{Providers: map[string]UsageLimit{"hyper": {Daily: 1}}})
+	reserveN(t, ut, 1, "acme", "hyper", "a.com")
+	reserveN(t, ut, 3, "acme", "jevi", "a.com")
+}
+
+func TestUsageRollover(t *testing.T) {
+	now := time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC)
+	ut := NewUsageTracker("", UsageBudgets{Providers: map[string]UsageLimit{"hyper": {Daily: 2, Monthly: 3}}})
+	ut.now = fixedClock(&now)
+
+	reserveN(t, ut, 2, "acme", "hyper", "a.com")
+	if err := ut.Reserve("acme", "hyper", "a.com"); err == nil || !strings.Contains(err.Error(), "daily") {
+		t.Fatalf("err = %v; want the daily budget exceeded", err)
+	}
+
+	// New day, same month: the daily budget resets, the monthly one does not
+	now = now.AddDate(0, 0, 1)
+	reserveN(t, ut, 1, "acme", "hyper", "a.com")
+	if err := ut.Reserve("acme", "hyper", "a.com"); err == nil || !strings.Contains(err.Error(), "monthly") {
+		t.Fatalf("err = %v; want the monthly budget exceeded", err)
+	}
+
+	// New month: both reset
+	now = time.Date(2026, 2, 1, 0, 0, 1, 0, time.UTC)
+	reserveN(t, ut, 2, "acme", "hyper", "a.com")
+
+	rep := ut.Report(UsageFilter{From: "2026-01-01", To: "2026-01-31"})
+	if rep.Total != 3 || rep.ByProvider["hyper"] != 3 || rep.ByTenant["acme"] != 3 || rep.ByDomain["a.com"] != 3 {
+		t.Errorf("January report = %+v; want 3 calls", rep)
+	}
+	if rep := ut.Report(UsageFilter{From: "2026-02-01"}); rep.Total != 2 || len(rep.Records) != 1 {
+		t.Errorf("February report = %+v; want 2 calls", rep)
+	}
+}
+
+func TestUsagePruneRetention(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ut := NewUsageTracker("", UsageBudgets{})
+	ut.now = fixedClock(&now)
+	reserveN(t, ut, 1, "acme", "hyper", "a.com")
+	now = now.AddDate(0, 0, usageRetentionDays+1)
+	reserveN(t, ut, 1, "acme", "hyper", "a.com")
+	if rep := ut.Report(UsageFilter{}); rep.Total != 1 || rep.Records[0].Day != now.Format(usageDayLayout) {
+		t.Errorf("report = %+v; want only the recent day", rep)
+	}
+}
+
+// TestUsageTrackersShareFile checks that two processes writing the same
+// ledger keep each other's calls, and that budgets see the other's calls
+func TestUsageTrackersShareFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-usage.json")
+	budgets := UsageBudgets{Providers: map[string]UsageLimit{"hyper": {Daily: 5}}}
+	server, err := LoadUsageTracker(path, budgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := LoadUsageTracker(path, budgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reserveN(t, server, 2, "acme", "hyper", "a.com")
+	reserveN(t, cli, 1, "acme", "hyper", "a.com")
+	reserveN(t, cli, 1, "other", "jevi", "b.com")
+	if err := server.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reserveN(t, server, 1, "acme", "hyper", "a.com")
+	if err := server.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadUsageTracker(path, budgets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := reloaded.Report(UsageFilter{})
+	if rep.Total != 5 || rep.ByProvider["hyper"] != 4 || rep.ByProvider["jevi"] != 1 {
+		t.Fatalf("merged report = %+v; want 4 hyper and 1 jevi calls", rep)
+	}
+
+	// cli sees the server's calls: 4 of 5 hyper calls are used
+	reserveN(t, cli, 1, "acme", "hyper", "a.com")
+	if err := cli.Reserve("acme", "hyper", "a.com"); err == nil {
+		t.Fatal("budget enforced against a stale count")
+	}
+}